@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -23,6 +24,8 @@ import (
 	"github.com/clipboard-ai/agent/internal/ipc"
 	"github.com/clipboard-ai/agent/internal/notify"
 	"github.com/clipboard-ai/agent/internal/rules"
+	"github.com/clipboard-ai/agent/internal/sandbox"
+	"github.com/clipboard-ai/agent/internal/scheduler"
 )
 
 // version is stamped at build time via
@@ -50,6 +53,12 @@ func (s *runtimeState) swap(cfg *config.Config, rulesEngine *rules.Engine) {
 }
 
 func main() {
+	// The sandbox helper runs between the daemon and an action; it must not
+	// parse flags or load config.
+	if len(os.Args) > 1 && os.Args[1] == sandbox.HelperCommand {
+		os.Exit(sandbox.RunHelper(os.Args[2:], os.Stderr))
+	}
+
 	versionFlag := flag.Bool("version", false, "Print version and exit")
 	flag.Parse()
 
@@ -58,6 +67,10 @@ func main() {
 		os.Exit(0)
 	}
 
+	if args := flag.Args(); len(args) > 0 {
+		os.Exit(runSubcommand(args, os.Stdout, os.Stderr))
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -85,7 +98,7 @@ func main() {
 	defer cancel()
 
 	// Create rules engine
-	rulesEngine, err := rules.NewEngine(cfg.Actions)
+	rulesEngine, err := rules.NewEngineFromConfig(cfg)
 	if err != nil {
 		logger.Error("failed to create rules engine", "error", err)
 		os.Exit(1)
@@ -97,12 +110,10 @@ func main() {
 	// (their deferred temp-file cleanup must run, and subprocesses must drain).
 	var actionWG sync.WaitGroup
 
-	// Bound concurrent action subprocesses across all clipboard changes. nil
-	// (max_concurrent_actions = 0) means unlimited.
-	var actionSem chan struct{}
-	if cfg.Settings.MaxConcurrentActions > 0 {
-		actionSem = make(chan struct{}, cfg.Settings.MaxConcurrentActions)
-	}
+	// Bound concurrent actions across clipboard triggers and the API. Actions
+	// beyond max_concurrent_actions wait in a bounded queue, API requests
+	// ahead of triggered ones.
+	actionScheduler := scheduler.New(scheduler.OptionsFrom(cfg.Settings))
 
 	// Create clipboard handler
 	handler := func(content clipboard.Content) {
@@ -114,18 +125,27 @@ func main() {
 		if content.Type == clipboard.ContentTypeImage {
 			logFields = append(logFields, "image_bytes", len(content.Image))
 		}
+		if content.Recopy {
+			logFields = append(logFields, "recopy", true)
+		}
 		logger.Info("clipboard changed", logFields...)
 		now := time.Now()
 
-		if controller.ShouldSkipClipboard(content.Signature, now) {
+		// settings.ignore drops the event before dedupe and every action.
+		if rule, ok := rulesEngine.Ignored(content); ok {
+			controller.RecordIgnored()
+			logger.Debug("ignored clipboard content", "rule", rule)
+			return
+		}
+
+		matches, deduped := clipboardMatches(controller, rulesEngine, content, now)
+		if deduped && len(matches) == 0 {
 			logger.Debug("skipped duplicate clipboard content",
+				"recopy", content.Recopy,
 				"dedupe_window_ms", cfg.Settings.ClipboardDedupeWindow,
 			)
 			return
 		}
-
-		// Evaluate rules
-		matches := rulesEngine.Evaluate(content)
 		for _, match := range matches {
 			guardHit := false
 			// Scan the RTF payload too: a styled paste can carry a secret that
@@ -165,23 +185,49 @@ func main() {
 				continue
 			}
 
-			logger.Info("action triggered", "action", match.ActionName)
+			triggeredFields := []any{"action", match.ActionName}
+			if match.Route != nil {
+				model, endpoint := match.Route.Overrides(match.Config)
+				triggeredFields = append(triggeredFields,
+					"route", match.Route.Index,
+					"model", model,
+					"endpoint", endpoint,
+				)
+			}
+			logger.Info("action triggered", triggeredFields...)
+
+			// A superseding action's run is cancelled, queued or running, by
+			// the next clipboard event that triggers the action.
+			runCtx, runDone := ctx, func() {}
+			if match.Config.Supersede {
+				runCtx, runDone = controller.Supersede(ctx, match.ActionName, executor.ErrSuperseded)
+			}
 
 			actionWG.Add(1)
-			go func(actionName string, actionCfg config.ActionConfig, content clipboard.Content, sensitiveGuardHit bool) {
+			go func(runCtx context.Context, runDone func(), match rules.Match, content clipboard.Content, sensitiveGuardHit bool) {
 				defer actionWG.Done()
+				defer runDone()
 
-				// Acquire a concurrency slot (or bail if shutting down).
-				release, ok := acquireActionSlot(ctx, actionSem)
-				if !ok {
-					return
-				}
-				defer release()
-
+				actionName, actionCfg := match.ActionName, match.Config
+				model, endpoint := match.Route.Overrides(actionCfg)
 				opts := executor.Options{
 					Trigger:          actionCfg.Trigger,
-					ModelOverride:    actionCfg.Model,
-					EndpointOverride: actionCfg.Endpoint,
+					ModelOverride:    model,
+					EndpointOverride: endpoint,
+					Args:             match.Args(),
+					Match:            match.Captures,
+					Prompt:           executor.PromptFor(cfg, actionName),
+					Command:          executor.CommandFor(cfg, actionName, match.Captures),
+					Transform:        executor.TransformFor(cfg, actionName),
+					Webhook:          executor.WebhookFor(cfg, actionName),
+					Pipeline:         executor.PipelineFor(cfg, actionName, match.Captures),
+					Cache:            executor.CacheFor(cfg, actionName),
+					EnvInput:         cfg.Settings.ActionInput == "env",
+					Limits:           actionCfg.Limits,
+					Sandbox:          cfg.Settings.Sandbox,
+					HistorySettings:  cfg.Settings,
+					NoNetwork:        actionCfg.NoNetwork,
+					Env:              executor.EnvFor(cfg, actionName),
 				}
 				if sensitiveGuardHit {
 					opts.SensitiveGuardHit = true
@@ -190,9 +236,15 @@ func main() {
 					opts.Timeout = time.Duration(actionCfg.TimeoutMs) * time.Millisecond
 				}
 				opts.InputType = string(content.Type)
+				opts.InputSource = executor.InputSourceClipboard
+				opts.InputTimestamp = content.Timestamp
 				if content.RTF != "" {
 					opts.InputRTF = content.RTF
 				}
+				superseded := func(elapsed time.Duration) {
+					logger.Info("action superseded", "action", actionName, "elapsed_ms", elapsed.Milliseconds())
+					executor.RecordSuperseded(actionName, content.Text, opts, cfg.Settings, elapsed)
+				}
 
 				if content.Type == clipboard.ContentTypeImage && len(content.Image) > 0 {
 					path, err := executor.WriteTempImage(content.Image)
@@ -205,46 +257,57 @@ func main() {
 					opts.InputImageMime = content.ImageMime
 				}
 
-				attempts := actionCfg.RetryCount + 1
-				backoff := time.Duration(actionCfg.RetryBackoffMs) * time.Millisecond
-				var result executor.Result
-
-				for attempt := 1; attempt <= attempts; attempt++ {
-					result = executor.ExecuteWithOptions(ctx, actionName, content.Text, opts)
-					if result.Error == nil {
-						break
-					}
-
-					if attempt == attempts {
-						break
+				// Wait for a run slot within the action's and its endpoint's
+				// limits before each attempt; the queue may drop this run for
+				// newer work, and shutdown abandons it.
+				queuedAt := time.Now()
+				var queueWait time.Duration
+				limits := executor.LimitsFor(cfg, actionName, opts)
+				acquire := func() (func(), error) {
+					release, err := actionScheduler.Acquire(runCtx, scheduler.PriorityTriggered, actionName, limits...)
+					if queueWait == 0 {
+						queueWait = time.Since(queuedAt)
 					}
+					return release, err
+				}
 
+				// Retry transient failures (timeouts, unreachable providers,
+				// rate limits, server errors) with backoff, giving the slot
+				// up while waiting.
+				policy := executor.RetryPolicyFor(actionCfg)
+				result, attempts := executor.ExecuteWithRetry(runCtx, actionName, content.Text, opts, policy, acquire, func(attempt int, result executor.Result, delay time.Duration) {
 					logger.Warn("action attempt failed",
 						"action", actionName,
 						"attempt", attempt,
-						"attempts_total", attempts,
+						"attempts_total", policy.Attempts,
+						"code", result.Code,
 						"error", result.Error,
-						"retry_backoff", backoff.String(),
+						"retry_backoff", delay.String(),
 					)
-
-					if backoff <= 0 {
-						continue
-					}
-
-					select {
-					case <-ctx.Done():
-						return
-					case <-time.After(backoff):
+				})
+				if attempts == 0 {
+					if errors.Is(context.Cause(runCtx), executor.ErrSuperseded) {
+						superseded(time.Since(queuedAt))
+					} else if ctx.Err() == nil {
+						logger.Warn("action not run", "action", actionName, "reason", result.Error)
 					}
+					return
+				}
+				if result.Code == executor.CodeSuperseded {
+					superseded(time.Since(queuedAt))
+					return
 				}
 
 				// Re-snapshot so a long-running action honors notification
 				// settings as of completion, not as of when it was triggered.
 				notifyCfg, _ := state.snapshot()
+				for _, warning := range result.Warnings {
+					logger.Warn("action warning", "action", actionName, "warning", warning)
+				}
 				if result.Error != nil {
-					logger.Error("action failed", "action", actionName, "error", result.Error)
+					logger.Error("action failed", "action", actionName, "code", result.Code, "error", result.Error)
 					if notifyCfg.Settings.Notifications {
-						if strings.Contains(result.Output, "safe mode") {
+						if result.Code == executor.CodeSafeModeBlocked {
 							notify.SendWithSubtitle("clipboard-ai", "Safe mode", actionName+" blocked — cloud provider not allowed")
 						} else {
 							notify.SendWithSubtitle("clipboard-ai", actionName+" failed", result.Error.Error())
@@ -254,12 +317,18 @@ func main() {
 				}
 				logger.Info("action completed",
 					"action", actionName,
+					"cached", result.Cached,
+					"queue_wait_ms", queueWait.Milliseconds(),
 					"elapsed_ms", result.Elapsed.Milliseconds(),
+					"provider_ms", result.Latency.Provider.Milliseconds(),
+					"model", result.Model,
+					"prompt_tokens", result.Usage.PromptTokens,
+					"completion_tokens", result.Usage.CompletionTokens,
 				)
 				if notifyCfg.Settings.Notifications {
 					notify.SendWithSubtitle("clipboard-ai", actionName, truncateRunes(result.Output, 200))
 				}
-			}(match.ActionName, match.Config, content, guardHit)
+			}(runCtx, runDone, match, content, guardHit)
 		}
 	}
 
@@ -269,10 +338,13 @@ func main() {
 	// Create IPC server
 	socketPath := config.GetSocketPath()
 	server := ipc.NewServer(socketPath, monitor, cfg, version)
+	server.SetIgnoredEventsSource(controller.IgnoredEvents)
+	server.SetRulesEngine(rulesEngine)
+	server.SetScheduler(actionScheduler)
 	configPath := config.ConfigPath()
 
 	reloadConfig := func(reason string) {
-		previousCfg, _ := state.snapshot()
+		previousCfg, previousRulesEngine := state.snapshot()
 		nextCfg, err := config.ReloadFromPath(configPath, previousCfg)
 		if err != nil {
 			logger.Error("config reload rejected", "reason", reason, "error", err)
@@ -282,7 +354,7 @@ func main() {
 			return
 		}
 
-		nextRulesEngine, err := rules.NewEngine(nextCfg.Actions)
+		nextRulesEngine, err := rules.NewEngineFromConfig(nextCfg)
 		if err != nil {
 			logger.Error("config reload rejected", "reason", reason, "error", err)
 			if previousCfg.Settings.Notifications {
@@ -290,11 +362,14 @@ func main() {
 			}
 			return
 		}
+		nextRulesEngine.InheritState(previousRulesEngine)
 
 		logRestartRequiredSettings(logger, previousCfg, nextCfg)
 		levelVar.Set(parseLogLevel(nextCfg.Settings.LogLevel))
 		state.swap(nextCfg, nextRulesEngine)
+		actionScheduler.SetOptions(scheduler.OptionsFrom(nextCfg.Settings))
 		server.SetConfig(nextCfg)
+		server.SetRulesEngine(nextRulesEngine)
 
 		// Drop cooldown state for actions removed by this reload.
 		activeActions := make(map[string]struct{}, len(nextCfg.Actions))
@@ -380,6 +455,18 @@ func main() {
 	}
 }
 
+// clipboardMatches evaluates the rules for a clipboard event. A re-copy of
+// unchanged content, or a duplicate inside the dedupe window, is offered only
+// to actions waiting on a repeat: gesture, which it may complete; deduped
+// reports that it was held back from the rest.
+func clipboardMatches(controller *automation.Controller, rulesEngine *rules.Engine, content clipboard.Content, now time.Time) (matches []rules.Match, deduped bool) {
+	duplicate := controller.ShouldSkipClipboard(content.Signature, now)
+	if content.Recopy || duplicate {
+		return rulesEngine.EvaluateRepeats(content), true
+	}
+	return rulesEngine.Evaluate(content), false
+}
+
 // truncateRunes shortens s to at most maxRunes runes (not bytes), so a
 // multi-byte character is never cut mid-sequence in a notification.
 func truncateRunes(s string, maxRunes int) string {
@@ -390,21 +477,6 @@ func truncateRunes(s string, maxRunes int) string {
 	return string(runes[:maxRunes]) + "..."
 }
 
-// acquireActionSlot reserves a slot in the concurrency semaphore, returning a
-// release function. A nil semaphore means unlimited. ok is false if ctx is
-// cancelled before a slot frees up.
-func acquireActionSlot(ctx context.Context, sem chan struct{}) (release func(), ok bool) {
-	if sem == nil {
-		return func() {}, true
-	}
-	select {
-	case sem <- struct{}{}:
-		return func() { <-sem }, true
-	case <-ctx.Done():
-		return func() {}, false
-	}
-}
-
 // waitForActions blocks until all in-flight action goroutines finish (so their
 // deferred temp-file cleanup runs), bounded by a timeout so a hung action can't
 // wedge shutdown forever.
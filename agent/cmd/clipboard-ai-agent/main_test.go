@@ -2,12 +2,15 @@ package main
 
 import (
 	"bytes"
-	"context"
 	"log/slog"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 	"unicode/utf8"
 
+	"github.com/clipboard-ai/agent/internal/automation"
+	"github.com/clipboard-ai/agent/internal/clipboard"
 	"github.com/clipboard-ai/agent/internal/config"
 	"github.com/clipboard-ai/agent/internal/rules"
 )
@@ -67,41 +70,6 @@ func TestLogRestartRequiredSettings(t *testing.T) {
 	}
 }
 
-func TestAcquireActionSlot_Unlimited(t *testing.T) {
-	release, ok := acquireActionSlot(context.Background(), nil)
-	if !ok {
-		t.Fatal("nil semaphore should always grant a slot")
-	}
-	release() // must be a no-op, not panic
-}
-
-func TestAcquireActionSlot_BoundsConcurrency(t *testing.T) {
-	sem := make(chan struct{}, 2)
-
-	r1, ok1 := acquireActionSlot(context.Background(), sem)
-	r2, ok2 := acquireActionSlot(context.Background(), sem)
-	if !ok1 || !ok2 {
-		t.Fatal("first two acquisitions should succeed")
-	}
-
-	// Third acquisition must block until a slot frees; with a cancelled context
-	// it returns ok=false instead of blocking forever.
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel()
-	if _, ok := acquireActionSlot(ctx, sem); ok {
-		t.Fatal("third acquisition should fail when full and ctx is cancelled")
-	}
-
-	// Release one slot; a new acquisition now succeeds.
-	r1()
-	r3, ok3 := acquireActionSlot(context.Background(), sem)
-	if !ok3 {
-		t.Fatal("acquisition should succeed after a slot is released")
-	}
-	r2()
-	r3()
-}
-
 func TestTruncateRunes(t *testing.T) {
 	if got := truncateRunes("hello", 200); got != "hello" {
 		t.Fatalf("short string should be unchanged, got %q", got)
@@ -116,3 +84,47 @@ func TestTruncateRunes(t *testing.T) {
 		t.Fatalf("expected 200 runes + ellipsis, got %d runes", len(r))
 	}
 }
+
+func TestClipboardMatches_RecopyCompletesRepeat(t *testing.T) {
+	cfg := config.Default()
+	cfg.Actions = map[string]config.ActionConfig{
+		"summarize": {Enabled: true, Trigger: "length > 5"},
+		"double":    {Enabled: true, Trigger: "repeat:2 within:1500ms"},
+	}
+	engine, err := rules.NewEngineFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create rules engine: %v", err)
+	}
+	controller := automation.NewController(time.Second)
+	start := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name        string
+		text        string
+		recopy      bool
+		at          time.Duration
+		want        []string
+		wantDeduped bool
+	}{
+		{name: "first copy", text: "some text", want: []string{"summarize"}},
+		{name: "copied again", text: "some text", recopy: true, at: 300 * time.Millisecond, want: []string{"double"}, wantDeduped: true},
+		{name: "copied again later", text: "some text", recopy: true, at: 5 * time.Second, wantDeduped: true},
+		{name: "other content", text: "other text", at: 5100 * time.Millisecond, want: []string{"summarize"}},
+		{name: "back inside the window", text: "some text", at: 5400 * time.Millisecond, want: []string{"double"}, wantDeduped: true},
+	}
+	for _, tt := range tests {
+		now := start.Add(tt.at)
+		engine.SetClock(func() time.Time { return now })
+		content := clipboard.Content{Text: tt.text, Signature: tt.text, Type: clipboard.ContentTypeText, Recopy: tt.recopy}
+
+		matches, deduped := clipboardMatches(controller, engine, content, now)
+		var got []string
+		for _, match := range matches {
+			got = append(got, match.ActionName)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") || deduped != tt.wantDeduped {
+			t.Fatalf("%s: expected %v (deduped %v), got %v (deduped %v)", tt.name, tt.want, tt.wantDeduped, got, deduped)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/clipboard-ai/agent/internal/automation"
+	"github.com/clipboard-ai/agent/internal/clipboard"
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/rules"
+)
+
+// Exit codes for subcommands such as `clipboard-ai-agent rules test`.
+const (
+	exitPassed = 0
+	exitFailed = 1
+	exitUsage  = 2
+)
+
+// ruleCaseFile is a rules fixture file:
+//
+//	now = "2026-03-04T10:00:00Z"   # optional fixed clock for every case
+//
+//	[[case]]
+//	name = "long article"
+//	text = "..."
+//	expect = ["summarize"]
+type ruleCaseFile struct {
+	Now   string     `toml:"now"`
+	Cases []ruleCase `toml:"case"`
+}
+
+// ruleCase is one fixture clipboard event and the actions it must trigger.
+// Cases run in order against one engine and the config's dedupe window, so
+// repeat:/after: sequences can be exercised across consecutive cases. A case
+// with the same content as the one before is a re-copy, as the agent sees it.
+type ruleCase struct {
+	Name   string   `toml:"name"`
+	Text   string   `toml:"text"`
+	RTF    string   `toml:"rtf"`
+	Image  string   `toml:"image"` // image file path, relative to the cases file
+	Type   string   `toml:"type"`  // content type; detected from text when empty
+	Now    string   `toml:"now"`   // optional fixed clock for this case
+	Expect []string `toml:"expect"`
+}
+
+// runSubcommand dispatches non-daemon subcommands and returns the exit code.
+func runSubcommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) >= 2 && args[0] == "rules" && args[1] == "test" {
+		return runRulesTest(args[2:], stdout, stderr)
+	}
+	fmt.Fprintf(stderr, "unknown command %q\nusage: clipboard-ai-agent rules test [--config <path>] <cases.toml>\n", strings.Join(args, " "))
+	return exitUsage
+}
+
+// runRulesTest evaluates the configured triggers against fixture clipboard
+// contents and reports, per case, which expected actions did not match (-)
+// and which unexpected actions did (+).
+func runRulesTest(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("rules test", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", config.ConfigPath(), "Config file whose triggers are tested")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: clipboard-ai-agent rules test [--config <path>] <cases.toml>")
+		return exitUsage
+	}
+	casesPath := flags.Arg(0)
+
+	cfg, err := config.LoadFromPath(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load config %s: %v\n", *configPath, err)
+		return exitUsage
+	}
+	engine, err := rules.NewEngineFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to create rules engine: %v\n", err)
+		return exitUsage
+	}
+
+	var file ruleCaseFile
+	if _, err := toml.DecodeFile(casesPath, &file); err != nil {
+		fmt.Fprintf(stderr, "failed to read cases %s: %v\n", casesPath, err)
+		return exitUsage
+	}
+	if len(file.Cases) == 0 {
+		fmt.Fprintf(stderr, "no [[case]] entries in %s\n", casesPath)
+		return exitUsage
+	}
+
+	controller := automation.NewController(time.Duration(cfg.Settings.ClipboardDedupeWindow) * time.Millisecond)
+	previous := ""
+	failed := 0
+	for i, tc := range file.Cases {
+		name := tc.Name
+		if name == "" {
+			name = fmt.Sprintf("case %d", i+1)
+		}
+
+		content, err := tc.content(filepath.Dir(casesPath))
+		if err != nil {
+			fmt.Fprintf(stdout, "FAIL %s\n  error: %v\n", name, err)
+			failed++
+			continue
+		}
+		now, err := caseClock(tc.Now, file.Now)
+		if err != nil {
+			fmt.Fprintf(stdout, "FAIL %s\n  error: %v\n", name, err)
+			failed++
+			continue
+		}
+		engine.SetClock(now)
+		content.Recopy = i > 0 && content.Signature == previous
+		previous = content.Signature
+
+		// Ignored content reaches no action, and duplicates only repeat:
+		// actions, as in the agent.
+		var got []string
+		if _, ignored := engine.Ignored(content); !ignored {
+			matches, _ := clipboardMatches(controller, engine, content, now())
+			for _, match := range matches {
+				got = append(got, match.ActionName)
+			}
+		}
+		missing, unexpected := diffActions(tc.Expect, got)
+		if len(missing) == 0 && len(unexpected) == 0 {
+			fmt.Fprintf(stdout, "PASS %s\n", name)
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(stdout, "FAIL %s\n", name)
+		for _, action := range missing {
+			fmt.Fprintf(stdout, "  - %s (expected, not matched)\n", action)
+		}
+		for _, action := range unexpected {
+			fmt.Fprintf(stdout, "  + %s (matched, not expected)\n", action)
+		}
+	}
+
+	fmt.Fprintf(stdout, "\n%d cases, %d passed, %d failed\n", len(file.Cases), len(file.Cases)-failed, failed)
+	if failed > 0 {
+		return exitFailed
+	}
+	return exitPassed
+}
+
+// content builds the clipboard content a case describes.
+func (tc ruleCase) content(baseDir string) (clipboard.Content, error) {
+	content := clipboard.Content{
+		Text:      tc.Text,
+		RTF:       tc.RTF,
+		Timestamp: time.Now(),
+		Signature: tc.Text,
+	}
+
+	switch {
+	case tc.Image != "":
+		path := tc.Image
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return content, fmt.Errorf("failed to read image: %w", err)
+		}
+		content.Image = data
+		content.ImageMime = "image/png"
+		content.Type = clipboard.ContentTypeImage
+		content.Signature = path
+	case tc.RTF != "":
+		content.Type = clipboard.ContentTypeRTF
+		content.Signature = tc.RTF
+	default:
+		content.Type = clipboard.DetectContentType(tc.Text)
+	}
+
+	if tc.Type != "" {
+		content.Type = clipboard.ContentType(tc.Type)
+	}
+	return content, nil
+}
+
+// caseClock returns a fixed clock from the case's or file's now, or the real
+// clock when neither is set.
+func caseClock(caseNow string, fileNow string) (func() time.Time, error) {
+	value := caseNow
+	if value == "" {
+		value = fileNow
+	}
+	if value == "" {
+		return time.Now, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid now %q: expected RFC 3339", value)
+	}
+	return func() time.Time { return parsed }, nil
+}
+
+// diffActions returns the expected actions that are missing from got and the
+// actions in got that weren't expected, each sorted.
+func diffActions(expected []string, got []string) (missing []string, unexpected []string) {
+	want := make(map[string]bool, len(expected))
+	for _, name := range expected {
+		want[name] = true
+	}
+	have := make(map[string]bool, len(got))
+	for _, name := range got {
+		have[name] = true
+		if !want[name] {
+			unexpected = append(unexpected, name)
+		}
+	}
+	for name := range want {
+		if !have[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+	return missing, unexpected
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRulesTestFiles(t *testing.T, configBody string, casesBody string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.toml")
+	casesPath := filepath.Join(dir, "cases.toml")
+	if err := os.WriteFile(configPath, []byte(configBody), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := os.WriteFile(casesPath, []byte(casesBody), 0600); err != nil {
+		t.Fatalf("failed to write cases: %v", err)
+	}
+	return configPath, casesPath
+}
+
+const rulesTestConfig = `
+[triggers]
+is_url = "regex:^https?://"
+
+[actions.summarize]
+enabled = true
+trigger = "length > 20"
+
+[actions.explain]
+enabled = true
+trigger = "mime:code"
+
+[actions.summarize_url]
+enabled = true
+trigger = "@is_url"
+
+[actions.caption]
+enabled = true
+trigger = "mime:image AND weekday:mon-fri tz:UTC"
+
+[actions.double]
+enabled = true
+trigger = "repeat:2 within:2s"
+`
+
+func TestRunRulesTest_AllPass(t *testing.T) {
+	configPath, casesPath := writeRulesTestFiles(t, rulesTestConfig, `
+now = "2026-03-04T10:00:00Z"
+
+[[case]]
+name = "long text"
+text = "this is a long enough paragraph"
+expect = ["summarize"]
+
+[[case]]
+name = "url"
+text = "https://example.com"
+expect = ["summarize_url"]
+
+[[case]]
+name = "code"
+text = "func main() {}"
+expect = ["explain"]
+
+[[case]]
+name = "screenshot on a weekday"
+image = "shot.png"
+expect = ["caption"]
+
+[[case]]
+name = "url copied again inside the dedupe window completes repeat only"
+text = "https://example.com"
+expect = ["double"]
+
+[[case]]
+name = "same content copied twice"
+text = "func main() {}"
+now = "2026-03-04T10:00:05Z"
+expect = ["explain"]
+
+[[case]]
+name = "second copy completes repeat"
+text = "func main() {}"
+now = "2026-03-04T10:00:06Z"
+expect = ["double"]
+
+[[case]]
+name = "screenshot on a weekend"
+image = "shot.png"
+now = "2026-03-07T10:00:00Z"
+expect = []
+`)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(casesPath), "shot.png"), []byte{0x89, 'P', 'N', 'G'}, 0600); err != nil {
+		t.Fatalf("failed to write image fixture: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runSubcommand([]string{"rules", "test", "--config", configPath, casesPath}, &stdout, &stderr)
+	if code != exitPassed {
+		t.Fatalf("expected exit %d, got %d\nstdout:\n%s\nstderr:\n%s", exitPassed, code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "8 cases, 8 passed, 0 failed") {
+		t.Fatalf("expected summary line, got:\n%s", stdout.String())
+	}
+}
+
+func TestRunRulesTest_ReportsDiff(t *testing.T) {
+	configPath, casesPath := writeRulesTestFiles(t, rulesTestConfig, `
+[[case]]
+name = "wrong expectation"
+text = "func main() { return }"
+expect = ["summarize"]
+`)
+
+	var stdout, stderr bytes.Buffer
+	code := runRulesTest([]string{"--config", configPath, casesPath}, &stdout, &stderr)
+	if code != exitFailed {
+		t.Fatalf("expected exit %d, got %d", exitFailed, code)
+	}
+	output := stdout.String()
+	for _, want := range []string{
+		"FAIL wrong expectation",
+		"  + explain (matched, not expected)",
+		"1 cases, 0 passed, 1 failed",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in report, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "- summarize") {
+		t.Fatalf("summarize matched and must not be reported missing:\n%s", output)
+	}
+}
+
+func TestRunRulesTest_MissingExpectedAction(t *testing.T) {
+	configPath, casesPath := writeRulesTestFiles(t, rulesTestConfig, `
+[[case]]
+text = "short"
+expect = ["summarize"]
+`)
+
+	var stdout, stderr bytes.Buffer
+	if code := runRulesTest([]string{"--config", configPath, casesPath}, &stdout, &stderr); code != exitFailed {
+		t.Fatalf("expected exit %d, got %d", exitFailed, code)
+	}
+	if !strings.Contains(stdout.String(), "FAIL case 1\n  - summarize (expected, not matched)") {
+		t.Fatalf("expected missing action in report, got:\n%s", stdout.String())
+	}
+}
+
+func TestRunRulesTest_UsageErrors(t *testing.T) {
+	configPath, casesPath := writeRulesTestFiles(t, rulesTestConfig, "now = \"2026-03-04T10:00:00Z\"\n")
+	invalidConfig, _ := writeRulesTestFiles(t, "[settings]\npoll_interval = 0\n", "")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "no cases file", args: []string{"rules", "test", "--config", configPath}, want: "usage:"},
+		{name: "no cases", args: []string{"rules", "test", "--config", configPath, casesPath}, want: "no [[case]] entries"},
+		{name: "invalid config", args: []string{"rules", "test", "--config", invalidConfig, casesPath}, want: "failed to load config"},
+		{name: "unknown command", args: []string{"rules", "lint"}, want: "unknown command"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := runSubcommand(tt.args, &stdout, &stderr); code != exitUsage {
+			t.Fatalf("%s: expected exit %d, got %d", tt.name, exitUsage, code)
+		}
+		if !strings.Contains(stderr.String(), tt.want) {
+			t.Fatalf("%s: expected %q on stderr, got %q", tt.name, tt.want, stderr.String())
+		}
+	}
+}
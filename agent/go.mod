@@ -5,7 +5,9 @@ go 1.21
 require (
 	github.com/BurntSushi/toml v1.3.2
 	github.com/fsnotify/fsnotify v1.9.0
+	go.starlark.net v0.0.0-20231121155337-90ade8b19d09
 	golang.design/x/clipboard v0.7.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require golang.org/x/sys v0.13.0 // indirect
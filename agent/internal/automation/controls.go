@@ -1,19 +1,29 @@
 package automation
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // maxRecentSignatures bounds the dedupe LRU so memory stays constant.
 const maxRecentSignatures = 128
 
-// Controller applies clipboard dedupe and per-action cooldown policies.
+// Controller applies clipboard dedupe, per-action cooldown and supersede
+// policies.
 type Controller struct {
 	dedupeWindow     time.Duration
 	recentSignatures map[string]time.Time
 	actionLastRunAt  map[string]time.Time
+	latestRuns       map[string]*latestRun
 	mu               sync.Mutex
+	ignoredEvents    atomic.Int64
+}
+
+// latestRun is the newest unfinished run of a superseding action.
+type latestRun struct {
+	cancel context.CancelCauseFunc
 }
 
 // NewController creates a controller with the given dedupe window.
@@ -22,6 +32,7 @@ func NewController(dedupeWindow time.Duration) *Controller {
 		dedupeWindow:     dedupeWindow,
 		recentSignatures: make(map[string]time.Time),
 		actionLastRunAt:  make(map[string]time.Time),
+		latestRuns:       make(map[string]*latestRun),
 	}
 }
 
@@ -97,3 +108,39 @@ func (c *Controller) AllowAction(action string, cooldown time.Duration, now time
 	c.actionLastRunAt[action] = now
 	return true
 }
+
+// Supersede starts a run of action that replaces the action's previous run:
+// if that run hasn't finished, its context is cancelled with cause. The new
+// run's context is cancelled the same way by the next run. Call done when the
+// run finishes.
+func (c *Controller) Supersede(ctx context.Context, action string, cause error) (runCtx context.Context, done func()) {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	run := &latestRun{cancel: cancel}
+
+	c.mu.Lock()
+	if previous, ok := c.latestRuns[action]; ok {
+		previous.cancel(cause)
+	}
+	c.latestRuns[action] = run
+	c.mu.Unlock()
+
+	return runCtx, func() {
+		c.mu.Lock()
+		if c.latestRuns[action] == run {
+			delete(c.latestRuns, action)
+		}
+		c.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// RecordIgnored counts a clipboard event dropped by a settings.ignore rule.
+func (c *Controller) RecordIgnored() {
+	c.ignoredEvents.Add(1)
+}
+
+// IgnoredEvents returns the number of clipboard events dropped by
+// settings.ignore rules since startup.
+func (c *Controller) IgnoredEvents() int64 {
+	return c.ignoredEvents.Load()
+}
@@ -1,6 +1,8 @@
 package automation
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -111,3 +113,46 @@ func TestRetainActions_PrunesRemovedActions(t *testing.T) {
 		t.Fatal("retained action should still be on cooldown")
 	}
 }
+
+func TestRecordIgnored(t *testing.T) {
+	c := NewController(0)
+	if c.IgnoredEvents() != 0 {
+		t.Fatalf("expected 0 ignored events, got %d", c.IgnoredEvents())
+	}
+	c.RecordIgnored()
+	c.RecordIgnored()
+	if c.IgnoredEvents() != 2 {
+		t.Fatalf("expected 2 ignored events, got %d", c.IgnoredEvents())
+	}
+}
+
+func TestSupersede(t *testing.T) {
+	c := NewController(0)
+	errStale := errors.New("stale")
+
+	first, firstDone := c.Supersede(context.Background(), "summarize", errStale)
+	other, otherDone := c.Supersede(context.Background(), "explain", errStale)
+	second, secondDone := c.Supersede(context.Background(), "summarize", errStale)
+
+	if !errors.Is(context.Cause(first), errStale) {
+		t.Fatalf("expected the older run to be cancelled with the cause, got %v", context.Cause(first))
+	}
+	if second.Err() != nil || other.Err() != nil {
+		t.Fatal("expected the newest run and other actions' runs to keep going")
+	}
+
+	// A finished run neither cancels nor forgets the run that replaced it.
+	firstDone()
+	third, thirdDone := c.Supersede(context.Background(), "summarize", errStale)
+	if !errors.Is(context.Cause(second), errStale) {
+		t.Fatalf("expected the second run to be superseded, got %v", context.Cause(second))
+	}
+
+	// Once the latest run finishes, a new one has nothing to cancel.
+	secondDone()
+	thirdDone()
+	if errors.Is(context.Cause(third), errStale) {
+		t.Fatal("a run that finished normally must not be reported as superseded")
+	}
+	otherDone()
+}
@@ -0,0 +1,163 @@
+// Package cache is the agent's on-disk result cache: action outputs stored
+// under a digest of everything that determines them, expired after a TTL and
+// evicted least-recently-used once the cache outgrows its size cap.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// entryExt is the file extension of cache entries; other files in the cache
+// directory (e.g. an interrupted write) are ignored.
+const entryExt = ".json"
+
+// Entry is a cached action result.
+type Entry struct {
+	CreatedAt time.Time `json:"created_at"`
+	Output    string    `json:"output"`
+	Provider  string    `json:"provider,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	Warnings  []string  `json:"warnings,omitempty"`
+}
+
+// Store is a cache directory with its expiry and size limits. Entries are
+// files named by key; a file's modification time is when it was last used.
+type Store struct {
+	Dir      string
+	TTL      time.Duration
+	MaxBytes int64
+}
+
+// mu serializes cache access within the agent, the cache's only writer.
+var mu sync.Mutex
+
+// Key returns the hex SHA-256 of parts. Each part is length-prefixed, so
+// ("ab", "c") and ("a", "bc") get different keys.
+func Key(parts ...string) string {
+	h := sha256.New()
+	var size [8]byte
+	for _, part := range parts {
+		binary.BigEndian.PutUint64(size[:], uint64(len(part)))
+		h.Write(size[:])
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s Store) path(key string) string {
+	return filepath.Join(s.Dir, key+entryExt)
+}
+
+// Get returns the entry stored under key unless it is missing, corrupt or
+// older than the TTL, and marks it as recently used.
+func (s Store) Get(key string) (Entry, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := s.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil || time.Since(entry.CreatedAt) > s.TTL {
+		os.Remove(path)
+		return Entry{}, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return entry, true
+}
+
+// Put stores entry under key, then evicts entries unused for longer than the
+// TTL and, while the cache is over MaxBytes, the least recently used. An
+// entry larger than MaxBytes on its own is not stored.
+func (s Store) Put(key string, entry Entry) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > s.MaxBytes {
+		return nil
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	// Write and rename, so a reader never sees a partial entry.
+	tmp, err := os.CreateTemp(s.Dir, ".put-*")
+	if err != nil {
+		return err
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return s.evict()
+}
+
+// evict removes entries unused for longer than the TTL, then the least
+// recently used ones until the cache fits in MaxBytes.
+func (s Store) evict() error {
+	dirEntries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return err
+	}
+	type file struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var files []file
+	var total int64
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), entryExt) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(s.Dir, dirEntry.Name())
+		// Unused for a whole TTL means created even longer ago: expired.
+		if time.Since(info.ModTime()) > s.TTL {
+			os.Remove(path)
+			continue
+		}
+		files = append(files, file{path: path, size: info.Size(), used: info.ModTime()})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].used.Before(files[j].used) })
+	for _, f := range files {
+		if total <= s.MaxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		total -= f.size
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) Store {
+	t.Helper()
+	return Store{Dir: filepath.Join(t.TempDir(), "cache"), TTL: time.Hour, MaxBytes: 1 << 20}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		same bool
+	}{
+		{name: "same parts", a: []string{"summarize", "text"}, b: []string{"summarize", "text"}, same: true},
+		{name: "different part", a: []string{"summarize", "text"}, b: []string{"explain", "text"}},
+		{name: "shifted boundary", a: []string{"ab", "c"}, b: []string{"a", "bc"}},
+		{name: "empty part", a: []string{"a", ""}, b: []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Key(tt.a...) == Key(tt.b...); got != tt.same {
+				t.Fatalf("Key(%q) == Key(%q) is %v, want %v", tt.a, tt.b, got, tt.same)
+			}
+		})
+	}
+	if key := Key("x"); len(key) != 64 || strings.Trim(key, "0123456789abcdef") != "" {
+		t.Fatalf("expected a hex SHA-256, got %q", key)
+	}
+}
+
+func TestStore_PutGet(t *testing.T) {
+	store := newTestStore(t)
+	key := Key("summarize", "article")
+
+	if _, ok := store.Get(key); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+	if err := store.Put(key, Entry{Output: "summary", Model: "mistral", Warnings: []string{"truncated"}}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	entry, ok := store.Get(key)
+	if !ok || entry.Output != "summary" || entry.Model != "mistral" || len(entry.Warnings) != 1 || entry.CreatedAt.IsZero() {
+		t.Fatalf("unexpected entry %+v (hit %v)", entry, ok)
+	}
+
+	info, err := os.Stat(store.path(key))
+	if err != nil {
+		t.Fatalf("expected an entry file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("expected entry mode 0600, got %o", perm)
+	}
+}
+
+func TestStore_GetExpired(t *testing.T) {
+	store := newTestStore(t)
+	key := Key("old")
+	if err := store.Put(key, Entry{Output: "stale", CreatedAt: time.Now().Add(-2 * time.Hour)}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := store.Get(key); ok {
+		t.Fatal("expected an expired entry to miss")
+	}
+	if _, err := os.Stat(store.path(key)); !os.IsNotExist(err) {
+		t.Fatalf("expected the expired entry to be removed, got %v", err)
+	}
+}
+
+func TestStore_GetCorrupt(t *testing.T) {
+	store := newTestStore(t)
+	key := Key("corrupt")
+	if err := os.MkdirAll(store.Dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.path(key), []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Get(key); ok {
+		t.Fatal("expected a corrupt entry to miss")
+	}
+}
+
+func TestStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	store := newTestStore(t)
+	keys := []string{Key("a"), Key("b"), Key("c")}
+	for _, key := range keys {
+		if err := store.Put(key, Entry{Output: strings.Repeat("x", 100)}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	// Order last use explicitly: b is the oldest, then c, then a.
+	now := time.Now()
+	for i, age := range []time.Duration{time.Minute, 3 * time.Minute, 2 * time.Minute} {
+		used := now.Add(-age)
+		if err := os.Chtimes(store.path(keys[i]), used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(store.path(keys[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Room for three entries: adding a fourth evicts exactly one, b.
+	store.MaxBytes = 3*info.Size() + info.Size()/2
+	if err := store.Put(Key("d"), Entry{Output: strings.Repeat("x", 100)}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	for _, tt := range []struct {
+		key  string
+		want bool
+	}{{keys[0], true}, {keys[1], false}, {keys[2], true}, {Key("d"), true}} {
+		if _, err := os.Stat(store.path(tt.key)); (err == nil) != tt.want {
+			t.Fatalf("entry %s: present = %v, want %v", tt.key[:8], err == nil, tt.want)
+		}
+	}
+}
+
+func TestStore_EvictsUnusedPastTTL(t *testing.T) {
+	store := newTestStore(t)
+	old := Key("old")
+	if err := store.Put(old, Entry{Output: "old"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	used := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(store.path(old), used, used); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(Key("new"), Entry{Output: "new"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := os.Stat(store.path(old)); !os.IsNotExist(err) {
+		t.Fatalf("expected the stale entry to be evicted, got %v", err)
+	}
+}
+
+func TestStore_PutSkipsOversizedEntry(t *testing.T) {
+	store := newTestStore(t)
+	store.MaxBytes = 64
+	key := Key("big")
+	if err := store.Put(key, Entry{Output: strings.Repeat("x", 100)}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := store.Get(key); ok {
+		t.Fatal("expected an entry over MaxBytes not to be stored")
+	}
+}
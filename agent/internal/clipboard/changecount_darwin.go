@@ -0,0 +1,20 @@
+//go:build darwin && cgo
+
+package clipboard
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework AppKit
+#import <AppKit/AppKit.h>
+
+static long pasteboard_change_count(void) {
+	return (long)[[NSPasteboard generalPasteboard] changeCount];
+}
+*/
+import "C"
+
+// pasteboardChangeCount returns NSPasteboard's change count, which increases
+// with every copy even when the content is unchanged.
+func pasteboardChangeCount() int64 {
+	return int64(C.pasteboard_change_count())
+}
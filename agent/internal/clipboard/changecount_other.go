@@ -0,0 +1,9 @@
+//go:build !darwin || !cgo
+
+package clipboard
+
+// pasteboardChangeCount returns 0: without a pasteboard change count, a
+// re-copy of unchanged content can't be told from no copy at all.
+func pasteboardChangeCount() int64 {
+	return 0
+}
@@ -23,6 +23,9 @@ type Content struct {
 	Timestamp time.Time
 	Type      ContentType
 	Signature string
+	// Recopy is true when the same content was copied again: the clipboard
+	// changed hands without its content changing.
+	Recopy bool
 }
 
 // ContentType indicates the type of clipboard content
@@ -46,18 +49,20 @@ type Handler func(content Content)
 
 // Monitor watches the clipboard for changes
 type Monitor struct {
-	pollInterval  time.Duration
-	handler       Handler
-	lastSignature string
-	mu            sync.RWMutex
-	current       Content
+	pollInterval    time.Duration
+	handler         Handler
+	lastSignature   string
+	lastChangeCount int64
+	mu              sync.RWMutex
+	current         Content
 
 	// Read/clock seams. Defaulted to the real clipboard in NewMonitor so the
 	// poll/dedupe logic can be exercised with fakes and no GUI/cgo dependency.
-	readText  func() []byte
-	readImage func() []byte
-	readRTF   func() string
-	now       func() time.Time
+	readText    func() []byte
+	readImage   func() []byte
+	readRTF     func() string
+	changeCount func() int64 // 0 when the platform has no change count
+	now         func() time.Time
 }
 
 // NewMonitor creates a new clipboard monitor
@@ -72,6 +77,7 @@ func NewMonitor(pollIntervalMs int, handler Handler) *Monitor {
 		readText:     func() []byte { return clipboard.Read(clipboard.FmtText) },
 		readImage:    func() []byte { return clipboard.Read(clipboard.FmtImage) },
 		readRTF:      readRTF,
+		changeCount:  pasteboardChangeCount,
 		now:          time.Now,
 	}
 }
@@ -96,16 +102,39 @@ func (m *Monitor) Start(ctx context.Context) error {
 	}
 }
 
-// check reads the clipboard and fires handler if changed
+// check reads the clipboard and fires handler if it changed, or if the same
+// content was copied again (see Content.Recopy).
 func (m *Monitor) check() {
-	if content, ok := m.checkImage(); ok {
-		m.update(content)
+	content, ok := m.read()
+	if !ok {
 		return
 	}
 
+	// Read the change count after the content: a copy in between bumps the
+	// count early, and its content still differs on the next poll.
+	count := m.changeCount()
+	copied := count != m.lastChangeCount
+	m.lastChangeCount = count
+
+	if content.Signature == m.lastSignature {
+		if count == 0 || !copied {
+			return
+		}
+		content.Recopy = true
+	}
+	m.update(content)
+}
+
+// read returns the clipboard content, preferring an image, or false when the
+// clipboard holds neither an image nor text.
+func (m *Monitor) read() (Content, bool) {
+	if content, ok := m.readImageContent(); ok {
+		return content, true
+	}
+
 	data := m.readText()
 	if data == nil {
-		return
+		return Content{}, false
 	}
 
 	text := string(data)
@@ -118,38 +147,27 @@ func (m *Monitor) check() {
 		contentType = ContentTypeRTF
 	}
 
-	if signature == m.lastSignature {
-		return
-	}
-
-	content := Content{
+	return Content{
 		Text:      text,
 		RTF:       rtf,
 		Timestamp: m.now(),
 		Type:      contentType,
 		Signature: signature,
-	}
-
-	m.update(content)
+	}, true
 }
 
-func (m *Monitor) checkImage() (Content, bool) {
+func (m *Monitor) readImageContent() (Content, bool) {
 	data := m.readImage()
 	if len(data) == 0 {
 		return Content{}, false
 	}
 
-	signature := hashBytes(data)
-	if signature == m.lastSignature {
-		return Content{}, false
-	}
-
 	return Content{
 		Image:     data,
 		ImageMime: "image/png",
 		Timestamp: m.now(),
 		Type:      ContentTypeImage,
-		Signature: signature,
+		Signature: hashBytes(data),
 	}, true
 }
 
@@ -193,6 +211,12 @@ func (m *Monitor) Current() Content {
 	return m.current
 }
 
+// DetectContentType classifies text the way the monitor does for a clipboard
+// change (url, code, text, or unknown when empty).
+func DetectContentType(text string) ContentType {
+	return detectContentType(text)
+}
+
 // detectContentType attempts to classify the content
 func detectContentType(text string) ContentType {
 	if len(text) == 0 {
@@ -12,6 +12,8 @@ type fakeClipboard struct {
 	text  []byte
 	image []byte
 	rtf   string
+	// count is the pasteboard change count; 0 models a platform without one.
+	count int64
 }
 
 func newTestMonitor(handler Handler, fake *fakeClipboard) *Monitor {
@@ -19,6 +21,7 @@ func newTestMonitor(handler Handler, fake *fakeClipboard) *Monitor {
 	m.readText = func() []byte { return fake.text }
 	m.readImage = func() []byte { return fake.image }
 	m.readRTF = func() string { return fake.rtf }
+	m.changeCount = func() int64 { return fake.count }
 	m.now = func() time.Time { return time.Unix(0, 0) }
 	return m
 }
@@ -120,6 +123,55 @@ func TestCheck_ImagePrecedenceAndDedupe(t *testing.T) {
 	}
 }
 
+func TestCheck_ReportsRecopyOfUnchangedContent(t *testing.T) {
+	tests := []struct {
+		name  string
+		fake  *fakeClipboard
+		count int64 // change count after the re-copy
+	}{
+		{name: "text", fake: &fakeClipboard{text: []byte("same content"), count: 1}, count: 2},
+		{name: "image", fake: &fakeClipboard{image: []byte{0x89, 0x50, 0x4e, 0x47}, count: 1}, count: 2},
+		{name: "no change count", fake: &fakeClipboard{text: []byte("same content")}, count: 0},
+	}
+	for _, tt := range tests {
+		var got []Content
+		m := newTestMonitor(func(c Content) { got = append(got, c) }, tt.fake)
+
+		m.check()
+		m.check() // nothing copied since the last poll
+		tt.fake.count = tt.count
+		m.check() // the same content copied again
+
+		want := 2
+		if tt.count == 0 {
+			want = 1
+		}
+		if len(got) != want {
+			t.Fatalf("%s: expected %d fires, got %d", tt.name, want, len(got))
+		}
+		if got[0].Recopy {
+			t.Errorf("%s: first copy reported as a re-copy", tt.name)
+		}
+		if want == 2 && (!got[1].Recopy || got[1].Signature != got[0].Signature) {
+			t.Errorf("%s: expected a re-copy of the same content, got %+v", tt.name, got[1])
+		}
+	}
+}
+
+func TestCheck_ImageWithTextDoesNotAlternate(t *testing.T) {
+	var got []Content
+	fake := &fakeClipboard{text: []byte("alt text"), image: []byte{0x89, 0x50, 0x4e, 0x47}, count: 1}
+	m := newTestMonitor(func(c Content) { got = append(got, c) }, fake)
+
+	m.check()
+	m.check()
+	m.check()
+
+	if len(got) != 1 || got[0].Type != ContentTypeImage {
+		t.Fatalf("expected one image fire, got %+v", got)
+	}
+}
+
 func TestStart_PollsUntilContextCancelled(t *testing.T) {
 	// Start() requires clipboard.Init(); skip the real init by calling check()
 	// through a short-lived ticker substitute. We assert the loop honors ctx.
@@ -3,11 +3,16 @@ package config
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/BurntSushi/toml"
+
+	"github.com/clipboard-ai/agent/internal/script"
 )
 
 // Config represents the application configuration
@@ -15,47 +20,152 @@ type Config struct {
 	Provider ProviderConfig          `toml:"provider"`
 	Actions  map[string]ActionConfig `toml:"actions"`
 	Settings SettingsConfig          `toml:"settings"`
+	Triggers map[string]string       `toml:"triggers"` // named trigger macros, referenced as @name
+
+	// Scripts holds each action's trigger_script as compiled by validation,
+	// so the rules engine doesn't compile it again.
+	Scripts map[string]*script.Program `toml:"-" json:"-"`
 }
 
+var triggerMacroNameRe = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
 // ProviderConfig configures the LLM provider
 type ProviderConfig struct {
-	Type     string `toml:"type"`     // ollama, openai
-	Endpoint string `toml:"endpoint"` // API endpoint
-	Model    string `toml:"model"`    // model name
-	APIKey   string `toml:"api_key"`  // API key (optional for local)
+	Type           string         `toml:"type"`            // ollama, openai
+	Endpoint       string         `toml:"endpoint"`        // API endpoint
+	Model          string         `toml:"model"`           // model name
+	APIKey         string         `toml:"api_key"`         // API key (optional for local)
+	EndpointLimits map[string]int `toml:"endpoint_limits"` // max concurrent actions per endpoint URL
+}
+
+// EndpointLimit returns the concurrency limit configured for endpoint, or 0
+// for none. A trailing slash doesn't distinguish endpoints.
+func (p ProviderConfig) EndpointLimit(endpoint string) int {
+	endpoint = strings.TrimRight(endpoint, "/")
+	for key, limit := range p.EndpointLimits {
+		if strings.TrimRight(key, "/") == endpoint {
+			return limit
+		}
+	}
+	return 0
 }
 
+// Action kinds (ActionConfig.Kind).
+const (
+	ActionKindCbai     = "cbai"     // run through the cbai CLI (the default)
+	ActionKindCommand  = "command"  // run a local program with the clipboard on stdin
+	ActionKindWebhook  = "webhook"  // send the clipboard to an HTTP endpoint
+	ActionKindPipeline = "pipeline" // run other actions in sequence, each on the previous output
+)
+
+// Pipeline step failure policies (ActionConfig.OnStepError).
+const (
+	OnStepErrorAbort = "abort" // fail the pipeline (the default)
+	OnStepErrorSkip  = "skip"  // pass the step's input on to the next step
+)
+
+// Action queue overflow policies (SettingsConfig.ActionQueueOverflow): what
+// happens to an action when every slot is busy and the queue is full.
+const (
+	QueueOverflowReject     = "reject"      // refuse the new action
+	QueueOverflowDropOldest = "drop-oldest" // drop the longest-waiting action (the default)
+	QueueOverflowCoalesce   = "coalesce"    // replace a queued run of the same action, else refuse
+)
+
 // ActionConfig configures an individual action
 type ActionConfig struct {
-	Enabled        bool   `toml:"enabled"`
-	Trigger        string `toml:"trigger"`          // trigger expression
-	Prompt         string `toml:"prompt"`           // custom action: prompt template (no JS plugin needed)
-	Model          string `toml:"model"`            // optional model override
-	Endpoint       string `toml:"endpoint"`         // optional endpoint override
-	MaxTokens      int    `toml:"max_tokens"`       // optional max completion tokens override
-	TimeoutMs      int    `toml:"timeout_ms"`       // action execution timeout override
-	RetryCount     int    `toml:"retry_count"`      // retries after initial attempt
-	RetryBackoffMs int    `toml:"retry_backoff_ms"` // delay between retries
-	CooldownMs     int    `toml:"cooldown_ms"`      // minimum delay between invocations
+	Enabled                bool              `toml:"enabled"`
+	Kind                   string            `toml:"kind"`                     // cbai (default), command, webhook or pipeline
+	Command                []string          `toml:"command"`                  // kind = "command": program and arguments; {{match.<group>}} expands regex captures
+	Webhook                WebhookConfig     `toml:"webhook"`                  // kind = "webhook": the request to send
+	Pipeline               []string          `toml:"pipeline"`                 // kind = "pipeline": action names run in order, each on the previous output
+	OnStepError            string            `toml:"on_step_error"`            // kind = "pipeline": abort (default) or skip a failed step
+	Trigger                string            `toml:"trigger"`                  // trigger expression
+	TriggerScript          string            `toml:"trigger_script"`           // optional Starlark match(content) predicate, ANDed with trigger
+	Prompt                 string            `toml:"prompt"`                   // custom action: prompt template (no JS plugin needed)
+	Args                   []string          `toml:"args"`                     // action args; {{match.<group>}} expands regex captures
+	Model                  string            `toml:"model"`                    // optional model override
+	Endpoint               string            `toml:"endpoint"`                 // optional endpoint override
+	MaxTokens              int               `toml:"max_tokens"`               // optional max completion tokens override
+	TimeoutMs              int               `toml:"timeout_ms"`               // action execution timeout override
+	RetryCount             int               `toml:"retry_count"`              // retries after initial attempt
+	RetryBackoffMs         int               `toml:"retry_backoff_ms"`         // delay before the first retry
+	RetryBackoffMultiplier float64           `toml:"retry_backoff_multiplier"` // growth of the delay per retry, 0 or 1 = fixed
+	RetryBackoffMaxMs      int               `toml:"retry_backoff_max_ms"`     // cap on the delay, 0 = uncapped
+	RetryJitter            float64           `toml:"retry_jitter"`             // random ± fraction of each delay, 0 to 1
+	CooldownMs             int               `toml:"cooldown_ms"`              // minimum delay between invocations
+	MaxConcurrent          int               `toml:"max_concurrent"`           // cap on simultaneous runs of this action, 0 = only the global cap
+	Supersede              bool              `toml:"supersede"`                // cancel a queued or running triggered run when newer clipboard content triggers the action
+	Routes                 []RouteConfig     `toml:"routes"`                   // content-based model/endpoint overrides, first match wins
+	Limits                 LimitsConfig      `toml:"limits"`                   // resource limits for the action's cbai subprocess
+	NoNetwork              bool              `toml:"no_network"`               // run the action's cbai subprocess without network access (Linux)
+	Env                    map[string]string `toml:"env"`                      // extra environment variables for the action's cbai subprocess
+	NoCache                bool              `toml:"no_cache"`                 // always run the action, never serve it from the result cache
+}
+
+// WebhookConfig describes the HTTP request a webhook action sends. The
+// action's timeout_ms bounds the request and retry_count/retry_backoff_ms
+// apply to failures as for any action.
+type WebhookConfig struct {
+	URL             string            `toml:"url"`              // http or https endpoint
+	Method          string            `toml:"method"`           // POST (default), PUT, PATCH or GET (no body)
+	Headers         map[string]string `toml:"headers"`          // extra request headers
+	Body            string            `toml:"body"`             // body template; empty sends a JSON event
+	Secret          string            `toml:"secret"`           // HMAC-SHA256 key for signing the body
+	SignatureHeader string            `toml:"signature_header"` // header carrying the signature, default X-Clipboard-AI-Signature
+}
+
+// EnvConfig adjusts which of the agent's environment variables reach action
+// subprocesses, on top of a built-in allowlist. Entries are variable names; a
+// trailing * matches a prefix (e.g. "AWS_*").
+type EnvConfig struct {
+	Allow []string `toml:"allow"` // also pass these
+	Deny  []string `toml:"deny"`  // never pass these, even if allowed
+}
+
+// LimitsConfig caps the resources of an action's subprocess via rlimits. Zero
+// leaves a resource unlimited.
+type LimitsConfig struct {
+	CPUSeconds int `toml:"cpu_seconds"` // RLIMIT_CPU
+	MemoryMB   int `toml:"memory_mb"`   // RLIMIT_AS, in MiB
+	OpenFiles  int `toml:"open_files"`  // RLIMIT_NOFILE
+}
+
+// RouteConfig overrides an action's model and/or endpoint for inputs matching
+// the trigger expression When.
+type RouteConfig struct {
+	When     string `toml:"when"`     // trigger expression
+	Model    string `toml:"model"`    // model override when When matches
+	Endpoint string `toml:"endpoint"` // endpoint override when When matches
 }
 
 // SettingsConfig contains general settings
 type SettingsConfig struct {
-	PollInterval          int    `toml:"poll_interval"`              // ms between clipboard checks
-	SafeMode              bool   `toml:"safe_mode"`                  // require confirmation for cloud
-	Notifications         bool   `toml:"notifications"`              // show macOS notifications
-	LogLevel              string `toml:"log_level"`                  // debug, info, warn, error
-	ClipboardDedupeWindow int    `toml:"clipboard_dedupe_window_ms"` // suppress duplicate clipboard events for this duration
-	HTTPEnabled           bool   `toml:"http_enabled"`               // enable local HTTP server
-	HTTPAddress           string `toml:"http_addr"`                  // local HTTP address
-	HTTPAuthToken         string `toml:"http_auth_token"`            // auth token for HTTP API
-	HTTPAllowRemote       bool   `toml:"http_allow_remote"`          // allow binding a non-loopback http_addr
-	HistoryEnabled        bool   `toml:"history_enabled"`            // write action history
-	HistoryMaxEntries     int    `toml:"history_max_entries"`        // maximum retained history records
-	HistoryTruncateChars  int    `toml:"history_truncate_chars"`     // max input/output chars per record, 0 disables truncation
-	SensitiveGuard        string `toml:"sensitive_guard"`            // block, warn, off
-	MaxConcurrentActions  int    `toml:"max_concurrent_actions"`     // cap on simultaneously running actions, 0 = unlimited
-	MaxTokens             int    `toml:"max_tokens"`                 // default max completion tokens per action
+	PollInterval          int       `toml:"poll_interval"`              // ms between clipboard checks
+	SafeMode              bool      `toml:"safe_mode"`                  // require confirmation for cloud
+	Notifications         bool      `toml:"notifications"`              // show macOS notifications
+	LogLevel              string    `toml:"log_level"`                  // debug, info, warn, error
+	ClipboardDedupeWindow int       `toml:"clipboard_dedupe_window_ms"` // suppress duplicate clipboard events for this duration
+	HTTPEnabled           bool      `toml:"http_enabled"`               // enable local HTTP server
+	HTTPAddress           string    `toml:"http_addr"`                  // local HTTP address
+	HTTPAuthToken         string    `toml:"http_auth_token"`            // auth token for HTTP API
+	HTTPAllowRemote       bool      `toml:"http_allow_remote"`          // allow binding a non-loopback http_addr
+	HistoryEnabled        bool      `toml:"history_enabled"`            // write action history
+	HistoryMaxEntries     int       `toml:"history_max_entries"`        // maximum retained history records
+	HistoryTruncateChars  int       `toml:"history_truncate_chars"`     // max input/output chars per record, 0 disables truncation
+	SensitiveGuard        string    `toml:"sensitive_guard"`            // block, warn, off
+	MaxConcurrentActions  int       `toml:"max_concurrent_actions"`     // cap on simultaneously running actions
+	ActionQueueSize       int       `toml:"action_queue_size"`          // actions that may wait for a free slot, 0 = none
+	ActionQueueOverflow   string    `toml:"action_queue_overflow"`      // reject, drop-oldest or coalesce when the queue is full
+	MaxTokens             int       `toml:"max_tokens"`                 // default max completion tokens per action
+	Ignore                []string  `toml:"ignore"`                     // trigger expressions whose matches are never acted on
+	NativePrompts         bool      `toml:"native_prompts"`             // run prompt-template actions in-process instead of via cbai
+	ActionInput           string    `toml:"action_input"`               // how cbai receives input: stdin (JSON envelope) or env (legacy)
+	Sandbox               bool      `toml:"sandbox"`                    // confine cbai's filesystem access with Landlock (Linux)
+	CacheEnabled          bool      `toml:"cache_enabled"`              // reuse results of identical LLM action runs
+	CacheTTLSeconds       int       `toml:"cache_ttl_seconds"`          // how long a cached result stays valid
+	CacheMaxSizeMB        int       `toml:"cache_max_size_mb"`          // cache size cap; least recently used results are evicted past it
+	Env                   EnvConfig `toml:"env"`                        // environment passed to action subprocesses
 }
 
 // Default returns a config with sensible defaults
@@ -85,7 +195,14 @@ func Default() *Config {
 			HistoryTruncateChars:  2000,
 			SensitiveGuard:        "warn",
 			MaxConcurrentActions:  4,
+			ActionQueueSize:       16,
+			ActionQueueOverflow:   QueueOverflowDropOldest,
 			MaxTokens:             1024,
+			NativePrompts:         true,
+			ActionInput:           "stdin",
+			CacheEnabled:          true,
+			CacheTTLSeconds:       3600,
+			CacheMaxSizeMB:        50,
 		},
 	}
 }
@@ -125,6 +242,11 @@ func ReloadFromPath(configPath string, previous *Config) (*Config, error) {
 }
 
 func (c *Config) validate() error {
+	for endpoint, limit := range c.Provider.EndpointLimits {
+		if limit <= 0 {
+			return fmt.Errorf("invalid provider.endpoint_limits %q = %d: must be greater than 0", endpoint, limit)
+		}
+	}
 	if c.Settings.PollInterval <= 0 {
 		return fmt.Errorf("invalid settings.poll_interval %d: must be greater than 0", c.Settings.PollInterval)
 	}
@@ -159,8 +281,29 @@ func (c *Config) validate() error {
 	if c.Settings.HistoryTruncateChars < 0 {
 		return fmt.Errorf("invalid settings.history_truncate_chars %d: must be greater than or equal to 0", c.Settings.HistoryTruncateChars)
 	}
-	if c.Settings.MaxConcurrentActions < 0 {
-		return fmt.Errorf("invalid settings.max_concurrent_actions %d: must be greater than or equal to 0", c.Settings.MaxConcurrentActions)
+	// An unlimited cap would let a burst of /action requests spawn
+	// unbounded LLM-calling runs.
+	if c.Settings.MaxConcurrentActions <= 0 {
+		return fmt.Errorf("invalid settings.max_concurrent_actions %d: must be greater than 0", c.Settings.MaxConcurrentActions)
+	}
+	if c.Settings.ActionQueueSize < 0 {
+		return fmt.Errorf("invalid settings.action_queue_size %d: must be greater than or equal to 0", c.Settings.ActionQueueSize)
+	}
+	switch overflow := strings.ToLower(strings.TrimSpace(c.Settings.ActionQueueOverflow)); overflow {
+	case "":
+		c.Settings.ActionQueueOverflow = QueueOverflowDropOldest
+	case QueueOverflowReject, QueueOverflowDropOldest, QueueOverflowCoalesce:
+		c.Settings.ActionQueueOverflow = overflow
+	default:
+		return fmt.Errorf("invalid settings.action_queue_overflow %q: must be %s, %s or %s", c.Settings.ActionQueueOverflow, QueueOverflowReject, QueueOverflowDropOldest, QueueOverflowCoalesce)
+	}
+	if c.Settings.CacheEnabled {
+		if c.Settings.CacheTTLSeconds <= 0 {
+			return fmt.Errorf("invalid settings.cache_ttl_seconds %d: must be greater than 0 when cache_enabled is true", c.Settings.CacheTTLSeconds)
+		}
+		if c.Settings.CacheMaxSizeMB <= 0 {
+			return fmt.Errorf("invalid settings.cache_max_size_mb %d: must be greater than 0 when cache_enabled is true", c.Settings.CacheMaxSizeMB)
+		}
 	}
 	if c.Settings.MaxTokens < 0 {
 		return fmt.Errorf("invalid settings.max_tokens %d: must be greater than or equal to 0", c.Settings.MaxTokens)
@@ -175,8 +318,115 @@ func (c *Config) validate() error {
 	default:
 		return fmt.Errorf("invalid settings.sensitive_guard %q: must be block, warn, or off", c.Settings.SensitiveGuard)
 	}
+	switch strings.ToLower(strings.TrimSpace(c.Settings.ActionInput)) {
+	case "", "stdin", "env":
+		if strings.TrimSpace(c.Settings.ActionInput) == "" {
+			c.Settings.ActionInput = "stdin"
+		} else {
+			c.Settings.ActionInput = strings.ToLower(strings.TrimSpace(c.Settings.ActionInput))
+		}
+	default:
+		return fmt.Errorf("invalid settings.action_input %q: must be stdin or env", c.Settings.ActionInput)
+	}
+
+	for _, list := range []struct {
+		field    string
+		patterns []string
+	}{
+		{"allow", c.Settings.Env.Allow},
+		{"deny", c.Settings.Env.Deny},
+	} {
+		for i, pattern := range list.patterns {
+			if !validEnvName(strings.TrimSuffix(pattern, "*")) {
+				return fmt.Errorf("invalid settings.env.%s[%d] %q: must be a variable name, optionally ending in *", list.field, i, pattern)
+			}
+		}
+	}
+
+	macroNames := make([]string, 0, len(c.Triggers))
+	for name := range c.Triggers {
+		macroNames = append(macroNames, name)
+	}
+	sort.Strings(macroNames)
+	for _, name := range macroNames {
+		if !triggerMacroNameRe.MatchString(name) {
+			return fmt.Errorf("invalid triggers.%s: macro names may only contain letters, digits, and underscores", name)
+		}
+		if _, err := ExpandTrigger("@"+name, c.Triggers); err != nil {
+			return fmt.Errorf("invalid triggers.%s: %w", name, err)
+		}
+	}
+
+	for i, rule := range c.Settings.Ignore {
+		expanded, err := ExpandTrigger(rule, c.Triggers)
+		if err != nil {
+			return fmt.Errorf("invalid settings.ignore[%d]: %w", i, err)
+		}
+		if strings.TrimSpace(expanded) == "" {
+			return fmt.Errorf("invalid settings.ignore[%d]: must be a non-empty trigger expression", i)
+		}
+	}
 
+	c.Scripts = make(map[string]*script.Program)
 	for name, action := range c.Actions {
+		if _, err := ExpandTrigger(action.Trigger, c.Triggers); err != nil {
+			return fmt.Errorf("invalid actions.%s.trigger: %w", name, err)
+		}
+		if strings.TrimSpace(action.TriggerScript) != "" {
+			program, err := script.Compile(name, action.TriggerScript)
+			if err != nil {
+				return fmt.Errorf("invalid actions.%s.trigger_script: %w", name, err)
+			}
+			c.Scripts[name] = program
+		}
+		switch action.Kind {
+		case "", ActionKindCbai:
+		case ActionKindCommand:
+			if len(action.Command) == 0 || strings.TrimSpace(action.Command[0]) == "" {
+				return fmt.Errorf("invalid actions.%s.command: must name a program when kind is %q", name, ActionKindCommand)
+			}
+			if strings.TrimSpace(action.Prompt) != "" {
+				return fmt.Errorf("invalid actions.%s.prompt: not used by kind %q", name, ActionKindCommand)
+			}
+		case ActionKindWebhook:
+			if err := validateWebhook(name, action.Webhook); err != nil {
+				return err
+			}
+			if strings.TrimSpace(action.Prompt) != "" {
+				return fmt.Errorf("invalid actions.%s.prompt: not used by kind %q", name, ActionKindWebhook)
+			}
+		case ActionKindPipeline:
+			if len(action.Pipeline) == 0 {
+				return fmt.Errorf("invalid actions.%s.pipeline: must list at least one step when kind is %q", name, ActionKindPipeline)
+			}
+			for i, step := range action.Pipeline {
+				if strings.TrimSpace(step) == "" {
+					return fmt.Errorf("invalid actions.%s.pipeline[%d]: must name an action", name, i)
+				}
+				if c.Actions[step].Kind == ActionKindPipeline {
+					return fmt.Errorf("invalid actions.%s.pipeline[%d] %q: pipelines can't be nested", name, i, step)
+				}
+			}
+			if strings.TrimSpace(action.Prompt) != "" {
+				return fmt.Errorf("invalid actions.%s.prompt: not used by kind %q", name, ActionKindPipeline)
+			}
+		default:
+			return fmt.Errorf("invalid actions.%s.kind %q: must be %s, %s, %s or %s", name, action.Kind, ActionKindCbai, ActionKindCommand, ActionKindWebhook, ActionKindPipeline)
+		}
+		if len(action.Command) > 0 && action.Kind != ActionKindCommand {
+			return fmt.Errorf("invalid actions.%s.command: requires kind = %q", name, ActionKindCommand)
+		}
+		if action.Webhook.URL != "" && action.Kind != ActionKindWebhook {
+			return fmt.Errorf("invalid actions.%s.webhook: requires kind = %q", name, ActionKindWebhook)
+		}
+		if len(action.Pipeline) > 0 && action.Kind != ActionKindPipeline {
+			return fmt.Errorf("invalid actions.%s.pipeline: requires kind = %q", name, ActionKindPipeline)
+		}
+		switch action.OnStepError {
+		case "", OnStepErrorAbort, OnStepErrorSkip:
+		default:
+			return fmt.Errorf("invalid actions.%s.on_step_error %q: must be %s or %s", name, action.OnStepError, OnStepErrorAbort, OnStepErrorSkip)
+		}
 		if action.TimeoutMs < 0 {
 			return fmt.Errorf("invalid actions.%s.timeout_ms %d: must be greater than or equal to 0", name, action.TimeoutMs)
 		}
@@ -190,17 +440,104 @@ func (c *Config) validate() error {
 				action.RetryBackoffMs,
 			)
 		}
+		if action.RetryBackoffMultiplier != 0 && action.RetryBackoffMultiplier < 1 {
+			return fmt.Errorf("invalid actions.%s.retry_backoff_multiplier %g: must be 0 or at least 1", name, action.RetryBackoffMultiplier)
+		}
+		if action.RetryBackoffMaxMs < 0 {
+			return fmt.Errorf("invalid actions.%s.retry_backoff_max_ms %d: must be greater than or equal to 0", name, action.RetryBackoffMaxMs)
+		}
+		if action.RetryJitter < 0 || action.RetryJitter > 1 {
+			return fmt.Errorf("invalid actions.%s.retry_jitter %g: must be between 0 and 1", name, action.RetryJitter)
+		}
 		if action.CooldownMs < 0 {
 			return fmt.Errorf("invalid actions.%s.cooldown_ms %d: must be greater than or equal to 0", name, action.CooldownMs)
 		}
+		if action.MaxConcurrent < 0 {
+			return fmt.Errorf("invalid actions.%s.max_concurrent %d: must be greater than or equal to 0", name, action.MaxConcurrent)
+		}
 		if action.MaxTokens < 0 {
 			return fmt.Errorf("invalid actions.%s.max_tokens %d: must be greater than or equal to 0", name, action.MaxTokens)
 		}
+		for _, limit := range []struct {
+			field string
+			value int
+		}{
+			{"cpu_seconds", action.Limits.CPUSeconds},
+			{"memory_mb", action.Limits.MemoryMB},
+			{"open_files", action.Limits.OpenFiles},
+		} {
+			if limit.value < 0 {
+				return fmt.Errorf("invalid actions.%s.limits.%s %d: must be greater than or equal to 0", name, limit.field, limit.value)
+			}
+		}
+		for key := range action.Env {
+			if !validEnvName(key) {
+				return fmt.Errorf("invalid actions.%s.env key %q: must be a variable name", name, key)
+			}
+		}
+		for i, route := range action.Routes {
+			expanded, err := ExpandTrigger(route.When, c.Triggers)
+			if err != nil {
+				return fmt.Errorf("invalid actions.%s.routes[%d].when: %w", name, i, err)
+			}
+			if strings.TrimSpace(expanded) == "" {
+				return fmt.Errorf("invalid actions.%s.routes[%d].when: must be a non-empty trigger expression", name, i)
+			}
+			if strings.TrimSpace(route.Model) == "" && strings.TrimSpace(route.Endpoint) == "" {
+				return fmt.Errorf("invalid actions.%s.routes[%d]: must set model or endpoint", name, i)
+			}
+		}
 	}
 
 	return nil
 }
 
+// validEnvName reports whether name can be an environment variable name: non-
+// empty, without '=' or NUL.
+func validEnvName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "=\x00")
+}
+
+// webhookMethods are the HTTP methods a webhook action may use.
+var webhookMethods = map[string]bool{"GET": true, "POST": true, "PUT": true, "PATCH": true}
+
+func validateWebhook(name string, webhook WebhookConfig) error {
+	target, err := url.Parse(webhook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("invalid actions.%s.webhook.url %q: must be an http or https URL", name, webhook.URL)
+	}
+	method := strings.ToUpper(webhook.Method)
+	if method != "" && !webhookMethods[method] {
+		return fmt.Errorf("invalid actions.%s.webhook.method %q: must be GET, POST, PUT or PATCH", name, webhook.Method)
+	}
+	if method == "GET" && webhook.Body != "" {
+		return fmt.Errorf("invalid actions.%s.webhook.body: not sent with method GET", name)
+	}
+	for header := range webhook.Headers {
+		if !validHeaderName(header) {
+			return fmt.Errorf("invalid actions.%s.webhook.headers key %q: not a valid header name", name, header)
+		}
+	}
+	if webhook.SignatureHeader != "" && !validHeaderName(webhook.SignatureHeader) {
+		return fmt.Errorf("invalid actions.%s.webhook.signature_header %q: not a valid header name", name, webhook.SignatureHeader)
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is an HTTP header field name: one or
+// more token characters (RFC 9110).
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
 // isLoopbackHost reports whether an http_addr host binds only the loopback
 // interface. An empty host (e.g. ":9159") binds all interfaces and is not
 // considered loopback.
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -389,6 +390,581 @@ retry_count = -1
 	}
 }
 
+func TestLoad_ActionLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  string
+		want    LimitsConfig
+		wantErr string
+	}{
+		{
+			name:   "all limits",
+			limits: "cpu_seconds = 30\nmemory_mb = 2048\nopen_files = 256",
+			want:   LimitsConfig{CPUSeconds: 30, MemoryMB: 2048, OpenFiles: 256},
+		},
+		{
+			name:    "negative limit",
+			limits:  "memory_mb = -1",
+			wantErr: "actions.summarize.limits.memory_mb",
+		},
+	}
+
+	for _, tt := range tests {
+		tmpHome := t.TempDir()
+		t.Setenv("HOME", tmpHome)
+
+		configDir := filepath.Join(tmpHome, ".clipboard-ai")
+		if err := os.MkdirAll(configDir, 0700); err != nil {
+			t.Fatalf("failed to create config dir: %v", err)
+		}
+		content := "[actions.summarize]\nenabled = true\ntrigger = \"length > 200\"\n\n[actions.summarize.limits]\n" + tt.limits + "\n"
+		if err := os.WriteFile(filepath.Join(configDir, "config.toml"), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err := Load()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if got := cfg.Actions["summarize"].Limits; got != tt.want {
+			t.Errorf("%s: expected limits %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestLoad_Sandbox(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(configFile, []byte(`
+[settings]
+sandbox = true
+
+[actions.summarize]
+enabled = true
+trigger = "length > 200"
+no_network = true
+`), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Settings.Sandbox {
+		t.Error("expected settings.sandbox to be enabled")
+	}
+	if !cfg.Actions["summarize"].NoNetwork {
+		t.Error("expected actions.summarize.no_network to be set")
+	}
+	if Default().Settings.Sandbox {
+		t.Error("expected the sandbox to be opt-in")
+	}
+}
+
+func TestLoad_Cache(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "defaults", content: ""},
+		{name: "custom limits", content: "[settings]\ncache_ttl_seconds = 60\ncache_max_size_mb = 5\n[actions.summarize]\nno_cache = true"},
+		{name: "disabled ignores limits", content: "[settings]\ncache_enabled = false\ncache_ttl_seconds = 0"},
+		{name: "zero ttl", content: "[settings]\ncache_ttl_seconds = 0", wantErr: "settings.cache_ttl_seconds"},
+		{name: "negative size", content: "[settings]\ncache_max_size_mb = -1", wantErr: "settings.cache_max_size_mb"},
+	}
+	for _, tt := range tests {
+		configFile := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configFile, []byte(tt.content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+		_, err := LoadFromPath(configFile)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+
+	defaults := Default().Settings
+	if !defaults.CacheEnabled || defaults.CacheTTLSeconds != 3600 || defaults.CacheMaxSizeMB != 50 {
+		t.Errorf("unexpected cache defaults %+v", defaults)
+	}
+}
+
+func TestLoad_ActionQueue(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{name: "defaults", content: "", want: QueueOverflowDropOldest},
+		{name: "coalesce", content: "[settings]\naction_queue_size = 4\naction_queue_overflow = \"Coalesce\"", want: QueueOverflowCoalesce},
+		{name: "no queue", content: "[settings]\naction_queue_size = 0\naction_queue_overflow = \"reject\"", want: QueueOverflowReject},
+		{name: "negative size", content: "[settings]\naction_queue_size = -1", wantErr: "settings.action_queue_size"},
+		{name: "unknown policy", content: "[settings]\naction_queue_overflow = \"lifo\"", wantErr: "settings.action_queue_overflow"},
+	}
+	for _, tt := range tests {
+		configFile := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configFile, []byte(tt.content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+		cfg, err := LoadFromPath(configFile)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if cfg.Settings.ActionQueueOverflow != tt.want {
+			t.Errorf("%s: expected overflow %q, got %q", tt.name, tt.want, cfg.Settings.ActionQueueOverflow)
+		}
+	}
+}
+
+func TestLoad_ConcurrencyLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "limits", content: "[provider.endpoint_limits]\n\"http://localhost:11434/v1\" = 1\n[actions.caption]\nmax_concurrent = 1"},
+		{name: "zero endpoint limit", content: "[provider.endpoint_limits]\n\"http://localhost:11434/v1\" = 0", wantErr: "provider.endpoint_limits"},
+		{name: "negative action limit", content: "[actions.caption]\nmax_concurrent = -1", wantErr: "actions.caption.max_concurrent"},
+		{name: "unlimited actions", content: "[settings]\nmax_concurrent_actions = 0", wantErr: "settings.max_concurrent_actions"},
+	}
+	for _, tt := range tests {
+		configFile := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configFile, []byte(tt.content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+		_, err := LoadFromPath(configFile)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestLoad_RetryBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "exponential", content: "[actions.caption]\nretry_count = 3\nretry_backoff_ms = 200\nretry_backoff_multiplier = 2\nretry_backoff_max_ms = 5000\nretry_jitter = 0.2"},
+		{name: "shrinking multiplier", content: "[actions.caption]\nretry_backoff_multiplier = 0.5", wantErr: "actions.caption.retry_backoff_multiplier"},
+		{name: "negative cap", content: "[actions.caption]\nretry_backoff_max_ms = -1", wantErr: "actions.caption.retry_backoff_max_ms"},
+		{name: "jitter over 1", content: "[actions.caption]\nretry_jitter = 1.5", wantErr: "actions.caption.retry_jitter"},
+	}
+	for _, tt := range tests {
+		configFile := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configFile, []byte(tt.content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+		_, err := LoadFromPath(configFile)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestProviderConfig_EndpointLimit(t *testing.T) {
+	provider := ProviderConfig{EndpointLimits: map[string]int{"http://localhost:11434/v1/": 1}}
+	if got := provider.EndpointLimit("http://localhost:11434/v1"); got != 1 {
+		t.Fatalf("expected the limit regardless of a trailing slash, got %d", got)
+	}
+	if got := provider.EndpointLimit("https://api.openai.com/v1"); got != 0 {
+		t.Fatalf("expected no limit for another endpoint, got %d", got)
+	}
+}
+
+func TestLoad_Env(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "allow, deny and action entries",
+			content: `
+[settings.env]
+allow = ["AWS_*", "GITHUB_TOKEN"]
+deny = ["AWS_SECRET_*"]
+
+[actions.summarize]
+enabled = true
+trigger = "length > 200"
+env = { DEBUG = "1" }
+`,
+		},
+		{
+			name:    "invalid pattern",
+			content: "[settings.env]\ndeny = [\"\"]\n",
+			wantErr: "settings.env.deny[0]",
+		},
+		{
+			name:    "invalid action entry",
+			content: "[actions.summarize]\nenabled = true\ntrigger = \"length > 200\"\nenv = { \"A=B\" = \"1\" }\n",
+			wantErr: "actions.summarize.env",
+		},
+	}
+
+	for _, tt := range tests {
+		configFile := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configFile, []byte(tt.content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err := LoadFromPath(configFile)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(cfg.Settings.Env, EnvConfig{Allow: []string{"AWS_*", "GITHUB_TOKEN"}, Deny: []string{"AWS_SECRET_*"}}) {
+			t.Errorf("%s: unexpected settings.env %+v", tt.name, cfg.Settings.Env)
+		}
+		if got := cfg.Actions["summarize"].Env; !reflect.DeepEqual(got, map[string]string{"DEBUG": "1"}) {
+			t.Errorf("%s: unexpected action env %v", tt.name, got)
+		}
+	}
+}
+
+func TestLoad_CommandActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		wantErr string
+	}{
+		{name: "command action", action: "kind = \"command\"\ncommand = [\"jq\", \".\"]"},
+		{name: "explicit cbai kind", action: "kind = \"cbai\""},
+		{name: "unknown kind", action: "kind = \"shell\"", wantErr: "actions.fmt.kind"},
+		{name: "command without program", action: "kind = \"command\"\ncommand = []", wantErr: "actions.fmt.command"},
+		{name: "command without kind", action: "command = [\"jq\", \".\"]", wantErr: "requires kind"},
+		{name: "command with prompt", action: "kind = \"command\"\ncommand = [\"jq\"]\nprompt = \"x\"", wantErr: "actions.fmt.prompt"},
+	}
+
+	for _, tt := range tests {
+		configFile := filepath.Join(t.TempDir(), "config.toml")
+		content := "[actions.fmt]\nenabled = true\ntrigger = \"length > 0\"\n" + tt.action + "\n"
+		if err := os.WriteFile(configFile, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err := LoadFromPath(configFile)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got := cfg.Actions["fmt"]; got.Kind == ActionKindCommand && !reflect.DeepEqual(got.Command, []string{"jq", "."}) {
+			t.Errorf("%s: unexpected command %v", tt.name, got.Command)
+		}
+	}
+}
+
+func TestLoad_WebhookActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		wantErr string
+	}{
+		{name: "webhook action", action: "kind = \"webhook\"\n[actions.hook.webhook]\nurl = \"http://localhost:8123/api\"\nheaders = { Authorization = \"Bearer x\" }"},
+		{name: "get without body", action: "kind = \"webhook\"\nwebhook.url = \"https://hub.local/x\"\nwebhook.method = \"get\""},
+		{name: "missing url", action: "kind = \"webhook\"", wantErr: "actions.hook.webhook.url"},
+		{name: "non-http url", action: "kind = \"webhook\"\nwebhook.url = \"file:///etc/passwd\"", wantErr: "actions.hook.webhook.url"},
+		{name: "unknown method", action: "kind = \"webhook\"\nwebhook.url = \"http://localhost\"\nwebhook.method = \"TRACE\"", wantErr: "actions.hook.webhook.method"},
+		{name: "get with body", action: "kind = \"webhook\"\nwebhook.url = \"http://localhost\"\nwebhook.method = \"GET\"\nwebhook.body = \"{{input}}\"", wantErr: "actions.hook.webhook.body"},
+		{name: "bad header name", action: "kind = \"webhook\"\nwebhook.url = \"http://localhost\"\nwebhook.headers = { \"X Bad\" = \"1\" }", wantErr: "actions.hook.webhook.headers"},
+		{name: "bad signature header", action: "kind = \"webhook\"\nwebhook.url = \"http://localhost\"\nwebhook.signature_header = \"X-Sig:\"", wantErr: "actions.hook.webhook.signature_header"},
+		{name: "webhook without kind", action: "webhook.url = \"http://localhost\"", wantErr: "requires kind"},
+		{name: "webhook with prompt", action: "kind = \"webhook\"\nprompt = \"x\"\nwebhook.url = \"http://localhost\"", wantErr: "actions.hook.prompt"},
+	}
+
+	for _, tt := range tests {
+		configFile := filepath.Join(t.TempDir(), "config.toml")
+		content := "[actions.hook]\nenabled = true\ntrigger = \"length > 0\"\n" + tt.action + "\n"
+		if err := os.WriteFile(configFile, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err := LoadFromPath(configFile)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got := cfg.Actions["hook"]; got.Kind != ActionKindWebhook || got.Webhook.URL == "" {
+			t.Errorf("%s: unexpected action %+v", tt.name, got)
+		}
+	}
+}
+
+func TestLoad_PipelineActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		wantErr string
+	}{
+		{name: "pipeline action", action: "kind = \"pipeline\"\npipeline = [\"ocr\", \"summarize\"]\non_step_error = \"skip\""},
+		{name: "step is a configured action", action: "kind = \"pipeline\"\npipeline = [\"fmt\"]\n[actions.fmt]\nkind = \"command\"\ncommand = [\"jq\"]"},
+		{name: "no steps", action: "kind = \"pipeline\"\npipeline = []", wantErr: "actions.flow.pipeline"},
+		{name: "blank step", action: "kind = \"pipeline\"\npipeline = [\"ocr\", \" \"]", wantErr: "actions.flow.pipeline[1]"},
+		{name: "self reference", action: "kind = \"pipeline\"\npipeline = [\"flow\"]", wantErr: "can't be nested"},
+		{name: "nested pipeline", action: "kind = \"pipeline\"\npipeline = [\"inner\"]\n[actions.inner]\nkind = \"pipeline\"\npipeline = [\"ocr\"]", wantErr: "can't be nested"},
+		{name: "pipeline without kind", action: "pipeline = [\"ocr\"]", wantErr: "requires kind"},
+		{name: "pipeline with prompt", action: "kind = \"pipeline\"\npipeline = [\"ocr\"]\nprompt = \"x\"", wantErr: "actions.flow.prompt"},
+		{name: "unknown failure policy", action: "kind = \"pipeline\"\npipeline = [\"ocr\"]\non_step_error = \"retry\"", wantErr: "actions.flow.on_step_error"},
+	}
+
+	for _, tt := range tests {
+		configFile := filepath.Join(t.TempDir(), "config.toml")
+		content := "[actions.flow]\nenabled = true\ntrigger = \"length > 0\"\n" + tt.action + "\n"
+		if err := os.WriteFile(configFile, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err := LoadFromPath(configFile)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got := cfg.Actions["flow"]; got.Kind != ActionKindPipeline || len(got.Pipeline) == 0 {
+			t.Errorf("%s: unexpected action %+v", tt.name, got)
+		}
+	}
+}
+
+func TestLoad_TriggerMacros(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(configFile, []byte(`
+[triggers]
+is_url = "regex:^https?://"
+
+[actions.summarize_url]
+enabled = true
+trigger = "@is_url AND length < 500"
+`), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configFile)
+	if err != nil {
+		t.Fatalf("expected valid macro config, got %v", err)
+	}
+	if cfg.Triggers["is_url"] != "regex:^https?://" {
+		t.Fatalf("expected is_url macro to be loaded, got %q", cfg.Triggers["is_url"])
+	}
+}
+
+func TestLoad_InvalidTriggerMacros(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "unknown reference",
+			content: `
+[actions.summarize]
+enabled = true
+trigger = "@missing AND length > 10"
+`,
+			want: "actions.summarize.trigger: unknown trigger macro @missing",
+		},
+		{
+			name: "cycle",
+			content: `
+[triggers]
+a = "@b"
+b = "@a"
+`,
+			want: "triggers.a: trigger macro cycle: @a -> @b -> @a",
+		},
+		{
+			name: "invalid name",
+			content: `
+[triggers]
+"is-url" = "regex:^https?://"
+`,
+			want: "triggers.is-url",
+		},
+		{
+			name: "ignore rule with unknown macro",
+			content: `
+[settings]
+ignore = ["length > 10000", "@missing"]
+`,
+			want: "settings.ignore[1]: unknown trigger macro @missing",
+		},
+		{
+			name: "empty ignore rule",
+			content: `
+[settings]
+ignore = [" "]
+`,
+			want: "settings.ignore[0]",
+		},
+	}
+
+	for _, tt := range tests {
+		configFile := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configFile, []byte(tt.content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		_, err := LoadFromPath(configFile)
+		if err == nil {
+			t.Fatalf("%s: expected validation error", tt.name)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestLoad_Routes(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte(`
+[actions.explain]
+enabled = true
+trigger = "mime:code"
+routes = [
+  { when = "length > 8000", model = "llama3.1:70b" },
+  { when = "lang:go", model = "qwen2.5-coder", endpoint = "http://localhost:11435/v1" },
+]
+`), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configFile)
+	if err != nil {
+		t.Fatalf("expected valid routes config, got %v", err)
+	}
+	routes := cfg.Actions["explain"].Routes
+	if len(routes) != 2 || routes[1].When != "lang:go" || routes[1].Endpoint != "http://localhost:11435/v1" {
+		t.Fatalf("unexpected routes: %+v", routes)
+	}
+}
+
+func TestLoad_InvalidRoutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes string
+		want   string
+	}{
+		{name: "empty when", routes: `{ when = "", model = "m" }`, want: "actions.explain.routes[0].when"},
+		{name: "unknown macro", routes: `{ when = "@missing", model = "m" }`, want: "actions.explain.routes[0].when: unknown trigger macro @missing"},
+		{name: "no override", routes: `{ when = "lang:go" }`, want: "actions.explain.routes[0]: must set model or endpoint"},
+	}
+
+	for _, tt := range tests {
+		configFile := filepath.Join(t.TempDir(), "config.toml")
+		content := "[actions.explain]\nenabled = true\ntrigger = \"mime:code\"\nroutes = [" + tt.routes + "]\n"
+		if err := os.WriteFile(configFile, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		_, err := LoadFromPath(configFile)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestLoad_InvalidTriggerScript(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte(`
+[actions.jira]
+enabled = true
+trigger_script = """
+def matches(content):
+    return True
+"""
+`), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	_, err := LoadFromPath(configFile)
+	if err == nil {
+		t.Fatal("expected error for a script without match()")
+	}
+	if !strings.Contains(err.Error(), "actions.jira.trigger_script") {
+		t.Fatalf("expected trigger_script error, got %v", err)
+	}
+}
+
+func TestLoad_CompilesTriggerScripts(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte(`
+[actions.jira]
+enabled = true
+trigger_script = """
+def match(content):
+    return True
+"""
+`), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Scripts["jira"] == nil || len(cfg.Scripts) != 1 {
+		t.Fatalf("expected jira's compiled script, got %v", cfg.Scripts)
+	}
+}
+
 func TestLoad_InvalidHistoryRetentionSettings(t *testing.T) {
 	tmpHome := t.TempDir()
 	t.Setenv("HOME", tmpHome)
@@ -443,6 +1019,46 @@ sensitive_guard = "maybe"
 	}
 }
 
+func TestLoad_ActionInput(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: "stdin"},
+		{value: " ENV ", want: "env"},
+		{value: "argv", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tmpHome := t.TempDir()
+		t.Setenv("HOME", tmpHome)
+
+		configDir := filepath.Join(tmpHome, ".clipboard-ai")
+		if err := os.MkdirAll(configDir, 0700); err != nil {
+			t.Fatalf("failed to create config dir: %v", err)
+		}
+		content := "[settings]\naction_input = \"" + tt.value + "\"\n"
+		if err := os.WriteFile(filepath.Join(configDir, "config.toml"), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err := Load()
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "settings.action_input") {
+				t.Errorf("action_input %q: expected action_input error, got %v", tt.value, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("action_input %q: unexpected error: %v", tt.value, err)
+		}
+		if cfg.Settings.ActionInput != tt.want {
+			t.Errorf("action_input %q: expected %q, got %q", tt.value, tt.want, cfg.Settings.ActionInput)
+		}
+	}
+}
+
 func TestReloadFromPath_KeepsPreviousConfigOnValidationError(t *testing.T) {
 	tmpDir := t.TempDir()
 	configFile := filepath.Join(tmpDir, "config.toml")
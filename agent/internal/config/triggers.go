@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExpandTrigger replaces @name references in a trigger expression with the
+// parenthesised body of the matching [triggers] macro, recursively. A
+// reference is only recognised where a condition starts (at the beginning of
+// the expression or after "(", AND, OR, NOT), and quoted regex:/contains:
+// operands are copied verbatim, so an operand like regex:\w+@\w+ is untouched.
+func ExpandTrigger(trigger string, macros map[string]string) (string, error) {
+	return expandTrigger(trigger, macros, nil)
+}
+
+func expandTrigger(trigger string, macros map[string]string, stack []string) (string, error) {
+	var b strings.Builder
+	atConditionStart := true
+
+	for i := 0; i < len(trigger); {
+		ch := trigger[i]
+
+		switch {
+		case isTriggerSpace(ch):
+			b.WriteByte(ch)
+			i++
+			continue
+		case ch == '(':
+			b.WriteByte(ch)
+			i++
+			atConditionStart = true
+			continue
+		case atConditionStart && ch == '@':
+			end := i + 1
+			for end < len(trigger) && isTriggerWordChar(trigger[end]) {
+				end++
+			}
+			name := trigger[i+1 : end]
+			if name == "" {
+				return "", fmt.Errorf("empty trigger macro reference at offset %d", i)
+			}
+			expanded, err := expandMacro(name, macros, stack)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString("(" + expanded + ")")
+			i = end
+			atConditionStart = false
+			continue
+		}
+
+		if atConditionStart {
+			if n := quotedOperandLength(trigger[i:]); n > 0 {
+				b.WriteString(trigger[i : i+n])
+				i += n
+				atConditionStart = false
+				continue
+			}
+		}
+
+		if kw := triggerKeywordAt(trigger, i); kw != "" {
+			b.WriteString(trigger[i : i+len(kw)])
+			i += len(kw)
+			atConditionStart = true
+			continue
+		}
+
+		b.WriteByte(ch)
+		i++
+		atConditionStart = false
+	}
+
+	return b.String(), nil
+}
+
+func expandMacro(name string, macros map[string]string, stack []string) (string, error) {
+	for i, seen := range stack {
+		if seen == name {
+			cycle := append(append([]string{}, stack[i:]...), name)
+			return "", fmt.Errorf("trigger macro cycle: @%s", strings.Join(cycle, " -> @"))
+		}
+	}
+	body, ok := macros[name]
+	if !ok {
+		return "", fmt.Errorf("unknown trigger macro @%s", name)
+	}
+	if strings.TrimSpace(body) == "" {
+		return "", fmt.Errorf("trigger macro @%s is empty", name)
+	}
+	return expandTrigger(strings.TrimSpace(body), macros, append(stack, name))
+}
+
+// quotedOperandLength returns the length of a quoted regex:/contains:
+// condition at the start of s, or 0 if s does not start with one.
+func quotedOperandLength(s string) int {
+	for _, prefix := range []string{"regex:", "contains:"} {
+		if !strings.HasPrefix(s, prefix) || len(s) <= len(prefix) {
+			continue
+		}
+		quote := s[len(prefix)]
+		if quote != '"' && quote != '\'' {
+			return 0
+		}
+		closing := strings.IndexByte(s[len(prefix)+1:], quote)
+		if closing < 0 {
+			return len(s)
+		}
+		return len(prefix) + 1 + closing + 1
+	}
+	return 0
+}
+
+// triggerKeywordAt returns the AND/OR/NOT keyword at s[i:], if it stands alone
+// as a word.
+func triggerKeywordAt(s string, i int) string {
+	if i > 0 && isTriggerWordChar(s[i-1]) {
+		return ""
+	}
+	for _, kw := range []string{"AND", "OR", "NOT"} {
+		end := i + len(kw)
+		if end > len(s) || !strings.EqualFold(s[i:end], kw) {
+			continue
+		}
+		if end < len(s) && isTriggerWordChar(s[end]) {
+			continue
+		}
+		return s[i:end]
+	}
+	return ""
+}
+
+func isTriggerSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+func isTriggerWordChar(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') ||
+		(ch >= 'A' && ch <= 'Z') ||
+		(ch >= '0' && ch <= '9') ||
+		ch == '_'
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandTrigger(t *testing.T) {
+	macros := map[string]string{
+		"is_url":     `regex:"^https?://\S+$"`,
+		"not_secret": "NOT contains:BEGIN",
+		"short_url":  "@is_url AND length < 100",
+	}
+
+	tests := []struct {
+		name    string
+		trigger string
+		want    string
+	}{
+		{name: "no macros", trigger: "length > 200", want: "length > 200"},
+		{name: "single reference", trigger: "@is_url AND length < 500", want: `(regex:"^https?://\S+$") AND length < 500`},
+		{name: "after NOT", trigger: "NOT @not_secret", want: "NOT (NOT contains:BEGIN)"},
+		{name: "inside group", trigger: "(@is_url OR mime:code)", want: `((regex:"^https?://\S+$") OR mime:code)`},
+		{name: "nested", trigger: "@short_url", want: `((regex:"^https?://\S+$") AND length < 100)`},
+		{name: "unquoted regex operand untouched", trigger: `regex:^\w+@\w+\.com$`, want: `regex:^\w+@\w+\.com$`},
+		{name: "quoted contains operand untouched", trigger: `contains:"@is_url" AND @not_secret`, want: `contains:"@is_url" AND (NOT contains:BEGIN)`},
+	}
+
+	for _, tt := range tests {
+		got, err := ExpandTrigger(tt.trigger, macros)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: ExpandTrigger(%q) = %q, want %q", tt.name, tt.trigger, got, tt.want)
+		}
+	}
+}
+
+func TestExpandTrigger_Errors(t *testing.T) {
+	macros := map[string]string{
+		"a":     "@b AND length > 1",
+		"b":     "NOT @a",
+		"self":  "@self",
+		"empty": "  ",
+	}
+
+	tests := []struct {
+		trigger string
+		want    string
+	}{
+		{trigger: "@missing", want: "unknown trigger macro @missing"},
+		{trigger: "@a", want: "trigger macro cycle: @a -> @b -> @a"},
+		{trigger: "length > 1 OR @self", want: "trigger macro cycle: @self -> @self"},
+		{trigger: "@empty", want: "trigger macro @empty is empty"},
+		{trigger: "@ AND length > 1", want: "empty trigger macro reference"},
+	}
+
+	for _, tt := range tests {
+		_, err := ExpandTrigger(tt.trigger, macros)
+		if err == nil {
+			t.Fatalf("ExpandTrigger(%q): expected error", tt.trigger)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("ExpandTrigger(%q): expected %q, got %v", tt.trigger, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package executor
+
+// builtinActionNames is the set of action ids and aliases the CLI registry
+// ships (see cli/src/lib/builtin-actions.ts). Kept here so the agent can reject
+// requests for unknown actions before spawning a subprocess, and knows which
+// names the CLI resolves ahead of a config-defined prompt.
+var builtinActionNames = map[string]struct{}{
+	"summary": {}, "summarize": {}, "sum": {},
+	"explain":       {},
+	"translate":     {},
+	"improve":       {},
+	"extract":       {},
+	"tldr":          {},
+	"classify":      {},
+	"summarize_url": {}, "summarize-url": {},
+	"caption": {}, "describe": {}, "describe-image": {},
+	"ocr": {}, "extract-text": {},
+}
+
+// IsBuiltinAction reports whether name is a builtin CLI action id or alias.
+func IsBuiltinAction(name string) bool {
+	_, ok := builtinActionNames[name]
+	return ok
+}
@@ -0,0 +1,22 @@
+package executor
+
+import "testing"
+
+func TestIsBuiltinAction(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "summarize", want: true},
+		{name: "sum", want: true},
+		{name: "describe-image", want: true},
+		{name: "tweet", want: false},
+		{name: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsBuiltinAction(tt.name); got != tt.want {
+			t.Errorf("IsBuiltinAction(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/cache"
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+// cacheKeyVersion is bumped when the cache key's inputs change, orphaning
+// entries stored under the old scheme until they're evicted.
+const cacheKeyVersion = "1"
+
+// resultCacheDir is where action results are cached.
+var resultCacheDir = filepath.Join(config.GetDataDir(), "cache")
+
+// CacheConfig describes how a run's result is cached. The key covers the
+// input, action, args, provider, endpoint, model and prompt version.
+type CacheConfig struct {
+	Store cache.Store
+	// Provider, Endpoint and Model are the defaults the run uses when
+	// Options doesn't override them.
+	Provider string
+	Endpoint string
+	Model    string
+	// PromptVersion identifies the action's prompt: its template or plugin
+	// file and its max_tokens.
+	PromptVersion string
+	// SensitiveGuard is the guard mode; input that trips the guard is never
+	// cached.
+	SensitiveGuard string
+	// Settings hold the history settings a cache hit is recorded under.
+	Settings config.SettingsConfig
+}
+
+// CacheFor returns the cache config for action, or nil when its result must
+// not be cached: the cache is disabled, the action sets no_cache, or it isn't
+// an LLM action (commands, webhooks and pipelines may have side effects, and
+// transforms are cheaper than the cache).
+func CacheFor(cfg *config.Config, action string) *CacheConfig {
+	if cfg == nil || !cfg.Settings.CacheEnabled {
+		return nil
+	}
+	actionCfg := cfg.Actions[action]
+	if actionCfg.NoCache || (actionCfg.Kind != "" && actionCfg.Kind != config.ActionKindCbai) || TransformFor(cfg, action) != nil {
+		return nil
+	}
+	return &CacheConfig{
+		Store: cache.Store{
+			Dir:      resultCacheDir,
+			TTL:      time.Duration(cfg.Settings.CacheTTLSeconds) * time.Second,
+			MaxBytes: int64(cfg.Settings.CacheMaxSizeMB) << 20,
+		},
+		Provider:       cfg.Provider.Type,
+		Endpoint:       cfg.Provider.Endpoint,
+		Model:          cfg.Provider.Model,
+		PromptVersion:  promptVersion(cfg, action),
+		SensitiveGuard: cfg.Settings.SensitiveGuard,
+		Settings:       cfg.Settings,
+	}
+}
+
+// promptVersion digests what the agent can see of action's prompt: its
+// template, its plugin file, and its max_tokens. A builtin's prompt ships with
+// cbai; the TTL bounds how long a cbai upgrade goes unnoticed.
+func promptVersion(cfg *config.Config, action string) string {
+	actionCfg := cfg.Actions[action]
+	maxTokens := actionCfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = cfg.Settings.MaxTokens
+	}
+	parts := []string{actionCfg.Prompt, strconv.Itoa(maxTokens)}
+	for _, ext := range []string{".js", ".mjs", ".cjs"} {
+		if data, err := os.ReadFile(filepath.Join(pluginDir, action+ext)); err == nil {
+			parts = append(parts, ext, string(data))
+		}
+	}
+	return cache.Key(parts...)
+}
+
+// runCached serves the run from opts.Cache when an identical one succeeded
+// within the TTL, and otherwise runs it and caches a successful result. Input
+// the sensitive guard flags is neither looked up nor stored. A hit is recorded
+// in history as cached; a miss records itself as its run does.
+func runCached(ctx context.Context, action string, text string, opts Options) Result {
+	cacheCfg := opts.Cache
+	opts.Cache = nil
+	if guardHit, _ := scanGuard(cacheCfg.SensitiveGuard, text, opts); guardHit {
+		return runExecuteWithOptions(ctx, action, text, opts)
+	}
+	key, err := cacheKey(action, text, opts, cacheCfg)
+	if err != nil {
+		slog.Warn("result cache unavailable for run", "action", action, "error", err)
+		return runExecuteWithOptions(ctx, action, text, opts)
+	}
+
+	start := time.Now()
+	if entry, ok := cacheCfg.Store.Get(key); ok {
+		if opts.OnToken != nil {
+			opts.OnToken(entry.Output)
+		}
+		result := Result{
+			Action:   action,
+			Output:   entry.Output,
+			Elapsed:  time.Since(start),
+			Provider: entry.Provider,
+			Model:    entry.Model,
+			Warnings: entry.Warnings,
+			Cached:   true,
+		}
+		recordLocalRun(action, text, opts, entry.Provider, entry.Model, result, cacheCfg.Settings)
+		return result
+	}
+
+	result := runExecuteWithOptions(ctx, action, text, opts)
+	if result.Error == nil && result.Output != "" {
+		err := cacheCfg.Store.Put(key, cache.Entry{
+			Output:   result.Output,
+			Provider: result.Provider,
+			Model:    result.Model,
+			Warnings: result.Warnings,
+		})
+		if err != nil {
+			slog.Warn("failed to cache result", "action", action, "error", err)
+		}
+	}
+	return result
+}
+
+// cacheKey returns the cache key of a run: a digest of its input (including
+// an image's bytes), action, args, provider, endpoint, model and prompt
+// version.
+func cacheKey(action string, text string, opts Options, cacheCfg *CacheConfig) (string, error) {
+	content := sha256.New()
+	content.Write([]byte(cache.Key(opts.InputType, text, opts.InputRTF)))
+	if opts.InputImagePath != "" {
+		image, err := os.ReadFile(opts.InputImagePath)
+		if err != nil {
+			return "", err
+		}
+		content.Write(image)
+	}
+	endpoint := cacheCfg.Endpoint
+	if opts.EndpointOverride != "" {
+		endpoint = opts.EndpointOverride
+	}
+	model := cacheCfg.Model
+	if opts.ModelOverride != "" {
+		model = opts.ModelOverride
+	}
+	return cache.Key(
+		cacheKeyVersion,
+		hex.EncodeToString(content.Sum(nil)),
+		action,
+		cache.Key(opts.Args...),
+		cacheCfg.Provider,
+		endpoint,
+		model,
+		cacheCfg.PromptVersion,
+	), nil
+}
@@ -0,0 +1,53 @@
+package executor
+
+import "fmt"
+
+// Capture limits for an action subprocess. Output beyond them is counted and
+// dropped, so a runaway plugin can't exhaust the agent's memory.
+const (
+	maxStdoutBytes = 8 << 20
+	maxStderrBytes = 256 << 10
+)
+
+// cappedBuffer is an io.Writer that keeps the first limit bytes written and
+// counts the rest. Writes never fail, so the child isn't killed by EPIPE for
+// being chatty.
+type cappedBuffer struct {
+	limit   int
+	data    []byte
+	dropped int64
+}
+
+func newCappedBuffer(limit int) *cappedBuffer {
+	return &cappedBuffer{limit: limit}
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	keep := min(len(p), b.limit-len(b.data))
+	b.data = append(b.data, p[:keep]...)
+	b.dropped += int64(len(p) - keep)
+	return len(p), nil
+}
+
+// Bytes returns the kept bytes, without a truncation marker.
+func (b *cappedBuffer) Bytes() []byte {
+	return b.data
+}
+
+// Len returns the number of kept bytes.
+func (b *cappedBuffer) Len() int {
+	return len(b.data)
+}
+
+// Truncated reports whether any output was dropped.
+func (b *cappedBuffer) Truncated() bool {
+	return b.dropped > 0
+}
+
+// String returns the kept output, followed by a marker when any was dropped.
+func (b *cappedBuffer) String() string {
+	if b.dropped == 0 {
+		return string(b.data)
+	}
+	return fmt.Sprintf("%s\n…[truncated %d bytes]", b.data, b.dropped)
+}
@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCappedBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{name: "under the limit", writes: []string{"ab", "cd"}, want: "abcd"},
+		{name: "exactly the limit", writes: []string{"abcdef"}, want: "abcdef"},
+		{name: "over the limit", writes: []string{"abcd", "efgh"}, want: "abcdef\n…[truncated 2 bytes]"},
+		{name: "writes after the limit", writes: []string{"abcdefgh", "ij"}, want: "abcdef\n…[truncated 4 bytes]"},
+	}
+
+	for _, tt := range tests {
+		buf := newCappedBuffer(6)
+		for _, w := range tt.writes {
+			if n, err := buf.Write([]byte(w)); n != len(w) || err != nil {
+				t.Fatalf("%s: Write(%q) = %d, %v; want a full write", tt.name, w, n, err)
+			}
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunExecuteWithOptions_SeparatesAndCapsStderr(t *testing.T) {
+	useFakeCbai(t, `printf 'result'
+head -c 1048576 /dev/zero | tr '\0' 'x' >&2
+`)
+
+	result := runExecuteWithOptions(context.Background(), "summary", "input", Options{})
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	if result.Output != "result" {
+		t.Fatalf("expected stdout only in output, got %d bytes", len(result.Output))
+	}
+	if !strings.HasPrefix(result.Diagnostics, strings.Repeat("x", maxStderrBytes)) ||
+		!strings.HasSuffix(result.Diagnostics, "…[truncated 786432 bytes]") {
+		t.Fatalf("expected stderr capped at %d bytes with a marker, got %d bytes", maxStderrBytes, len(result.Diagnostics))
+	}
+}
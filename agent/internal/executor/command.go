@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/history"
+	"github.com/clipboard-ai/agent/internal/rules"
+)
+
+// exitTempFail is sysexits.h's EX_TEMPFAIL: a command exiting with it reports
+// a temporary failure worth retrying.
+const exitTempFail = 75
+
+// CommandConfig describes a run of a command action: a local program that
+// reads the clipboard text on stdin and whose stdout is the result, with no
+// cbai or provider involved.
+type CommandConfig struct {
+	// Argv is the program and its arguments, with match placeholders
+	// already expanded.
+	Argv []string
+	// Settings supplies history retention.
+	Settings config.SettingsConfig
+}
+
+// CommandFor returns the command config for action, or nil when it isn't a
+// command action. captures fill {{match.<group>}} placeholders in its
+// command; the action's args are appended by the executor.
+func CommandFor(cfg *config.Config, action string, captures map[string]string) *CommandConfig {
+	if cfg == nil {
+		return nil
+	}
+	actionCfg, ok := cfg.Actions[action]
+	if !ok || actionCfg.Kind != config.ActionKindCommand || len(actionCfg.Command) == 0 {
+		return nil
+	}
+	// The program itself is kept verbatim: dropping an empty one would
+	// promote the first argument to the program.
+	argv := append([]string{actionCfg.Command[0]}, rules.RenderArgs(actionCfg.Command[1:], captures)...)
+	return &CommandConfig{Argv: argv, Settings: cfg.Settings}
+}
+
+// runCommand runs opts.Command with the same timeout, limits, sandbox and
+// environment policy as cbai. The program gets the clipboard text on stdin
+// and its context in CBAI_* variables.
+func runCommand(ctx context.Context, action string, text string, opts Options) Result {
+	start := time.Now()
+
+	argv := append(append([]string{}, opts.Command.Argv...), opts.Args...)
+	proc := process{
+		name:     argv[0],
+		args:     argv[1:],
+		env:      append(opts.Env.environ(os.Environ()), "CBAI_ACTION="+action),
+		stdin:    strings.NewReader(text),
+		programs: argv[:1],
+	}
+	if opts.Trigger != "" {
+		proc.env = append(proc.env, "CBAI_TRIGGER="+opts.Trigger)
+	}
+	if opts.InputType != "" {
+		proc.env = append(proc.env, "CBAI_INPUT_TYPE="+opts.InputType)
+	}
+	if opts.InputImagePath != "" {
+		proc.env = append(proc.env, "CBAI_INPUT_IMAGE_PATH="+opts.InputImagePath, "CBAI_INPUT_IMAGE_MIME="+opts.InputImageMime)
+	}
+	if opts.SensitiveGuardHit {
+		proc.env = append(proc.env, "CBAI_SENSITIVE_GUARD_HIT=true")
+	}
+	proc.env = append(proc.env, matchEnv(opts.Match)...)
+
+	run := runProcess(ctx, action, proc, opts)
+	result := run.newResult(action, start)
+	result.Output = run.stdout.String()
+	result.Latency.Process = result.Elapsed
+	var exitErr *exec.ExitError
+	if errors.As(run.err, &exitErr) && exitErr.ExitCode() == exitTempFail {
+		result.Code = CodeTemporaryFailure
+	}
+	run.applyTimeout(&result)
+	applySuperseded(ctx, &result)
+	recordLocalRun(action, text, opts, config.ActionKindCommand, opts.Command.Argv[0], result, opts.Command.Settings)
+	return result
+}
+
+// recordLocalRun appends a run that involved no provider of its own (a
+// command, transform, webhook, pipeline or result cache hit) to history in the
+// CLI's record format, with kind in place of the provider and program in place
+// of the model. A write failure is logged, never surfaced as an action error.
+func recordLocalRun(action string, text string, opts Options, kind string, program string, result Result, settings config.SettingsConfig) {
+	if result.Code == CodeSuperseded || opts.NoHistory {
+		return // recorded by RecordSuperseded, or by the pipeline
+	}
+	trigger := opts.Trigger
+	if trigger == "" {
+		trigger = "daemon"
+	}
+	record := history.Record{
+		Action:    action,
+		Args:      opts.Args,
+		Source:    "daemon",
+		Trigger:   trigger,
+		Provider:  kind,
+		Model:     program,
+		LatencyMs: int(result.Elapsed.Milliseconds()),
+		Status:    "success",
+		Input:     text,
+		Output:    result.Output,
+		Steps:     historySteps(result.Steps),
+		Cached:    result.Cached,
+	}
+	if result.Error != nil {
+		record.Status = "error"
+		record.Error = result.Error.Error()
+	}
+	if opts.SensitiveGuardHit {
+		record.Input = "[sensitive content omitted]"
+		record.Output = ""
+	}
+	if err := history.Append(historyPath(), record, settings); err != nil {
+		slog.Warn("failed to write history", "action", action, "error", err)
+	}
+}
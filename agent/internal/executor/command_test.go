@@ -0,0 +1,125 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/history"
+)
+
+func TestCommandFor(t *testing.T) {
+	cfg := config.Default()
+	cfg.Actions["fmt"] = config.ActionConfig{
+		Kind:    config.ActionKindCommand,
+		Command: []string{"sqlformat", "--keywords", "{{match.case}}", "--lang={{match.lang}}", "-"},
+	}
+	cfg.Actions["tweet"] = config.ActionConfig{Prompt: "Rewrite as a tweet: {{input}}"}
+
+	tests := []struct {
+		name     string
+		action   string
+		captures map[string]string
+		want     []string
+	}{
+		{name: "expands captures", action: "fmt", captures: map[string]string{"case": "upper", "lang": "sql"},
+			want: []string{"sqlformat", "--keywords", "upper", "--lang=sql", "-"}},
+		{name: "keeps empty arguments", action: "fmt", captures: map[string]string{"lang": "sql"},
+			want: []string{"sqlformat", "--keywords", "", "--lang=sql", "-"}},
+		{name: "prompt action", action: "tweet", want: nil},
+		{name: "builtin action", action: "summarize", want: nil},
+		{name: "unknown action", action: "missing", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := CommandFor(cfg, tt.action, tt.captures)
+			if tt.want == nil {
+				if command != nil {
+					t.Fatalf("expected no command, got %+v", command)
+				}
+				return
+			}
+			if command == nil || !reflect.DeepEqual(command.Argv, tt.want) {
+				t.Fatalf("expected argv %v, got %+v", tt.want, command)
+			}
+		})
+	}
+}
+
+// commandOptions returns Options that run argv as a command action, with
+// history enabled.
+func commandOptions(argv ...string) Options {
+	settings := config.Default().Settings
+	settings.HistoryEnabled = true
+	return Options{Command: &CommandConfig{Argv: argv, Settings: settings}}
+}
+
+func TestRunExecuteWithOptions_RunsCommand(t *testing.T) {
+	historyFile := usePromptFixtures(t)
+	// No cbai on PATH: a command action must not need it.
+	t.Setenv("PATH", "/usr/bin:/bin")
+	t.Setenv("GITHUB_TOKEN", "leaked-token")
+
+	opts := commandOptions("/bin/sh", "-c",
+		`tr a-z A-Z; echo "$1 $CBAI_MATCH_LANG $CBAI_INPUT_TYPE ${GITHUB_TOKEN:-unset}"`, "sh")
+	opts.Args = []string{"arg"}
+	opts.Match = map[string]string{"lang": "sql"}
+	opts.InputType = "text"
+	opts.Trigger = "regex:select"
+	result := runExecuteWithOptions(context.Background(), "shout", "select 1\n", opts)
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v (stderr %q)", result.Error, result.Diagnostics)
+	}
+	if result.Output != "SELECT 1\narg sql text unset\n" {
+		t.Fatalf("unexpected output %q", result.Output)
+	}
+
+	data, err := os.ReadFile(historyFile)
+	if err != nil {
+		t.Fatalf("expected a history record: %v", err)
+	}
+	var record history.Record
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(data))), &record); err != nil {
+		t.Fatalf("corrupt history record: %v", err)
+	}
+	if record.Action != "shout" || record.Trigger != "regex:select" || record.Status != "success" ||
+		record.Provider != config.ActionKindCommand || record.Input != "select 1\n" {
+		t.Fatalf("unexpected history record: %+v", record)
+	}
+}
+
+func TestRunExecuteWithOptions_CommandFailure(t *testing.T) {
+	historyFile := usePromptFixtures(t)
+
+	opts := commandOptions("/bin/sh", "-c", "echo partial; echo 'parse error' >&2; exit 4")
+	result := runExecuteWithOptions(context.Background(), "jq", "{", opts)
+	if result.Error == nil || result.Error.Error() != "exit status 4" {
+		t.Fatalf("expected exit status 4, got %v", result.Error)
+	}
+	if result.Output != "partial\n" || result.Diagnostics != "parse error\n" {
+		t.Fatalf("unexpected output %q / diagnostics %q", result.Output, result.Diagnostics)
+	}
+	data, _ := os.ReadFile(historyFile)
+	if !strings.Contains(string(data), `"status":"error"`) {
+		t.Fatalf("expected an error history record, got %s", data)
+	}
+}
+
+func TestRunExecuteWithOptions_CommandTimeout(t *testing.T) {
+	usePromptFixtures(t)
+
+	opts := commandOptions("/bin/sh", "-c", "sleep 5")
+	opts.Timeout = 100 * time.Millisecond
+	result := runExecuteWithOptions(context.Background(), "slow", "", opts)
+	if result.Code != CodeTimeout {
+		t.Fatalf("expected a timeout, got code %q error %v", result.Code, result.Error)
+	}
+	if result.Elapsed > 2*time.Second {
+		t.Fatalf("timeout didn't stop the command: took %s", result.Elapsed)
+	}
+}
@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+// defaultEnvAllow is what a cbai subprocess inherits from the agent's
+// environment when nothing else is configured: enough to find its runtime,
+// read its config, format output and verify TLS. A trailing * matches a
+// prefix.
+var defaultEnvAllow = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TMPDIR", "TZ",
+	"LANG", "LANGUAGE", "LC_*",
+	"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME",
+	"NO_COLOR", "CBAI_*",
+	"SSL_CERT_FILE", "SSL_CERT_DIR", "NODE_EXTRA_CA_CERTS",
+}
+
+// providerEnvAllow adds the variables the provider SDK reads for each
+// provider type.
+var providerEnvAllow = map[string][]string{
+	"openai":    {"OPENAI_API_KEY", "OPENAI_BASE_URL", "OPENAI_ORG_ID", "OPENAI_PROJECT_ID"},
+	"anthropic": {"ANTHROPIC_API_KEY"},
+}
+
+// EnvPolicy decides the environment of a cbai subprocess. Variables from the
+// agent's environment pass when they match defaultEnvAllow or Allow and don't
+// match Deny; Set is added on top, regardless of Deny.
+type EnvPolicy struct {
+	Allow []string
+	Deny  []string
+	Set   map[string]string
+}
+
+// EnvFor returns the environment policy for action: the provider's
+// variables and settings.env.allow on top of the defaults, settings.env.deny,
+// and the action's own env entries.
+func EnvFor(cfg *config.Config, action string) EnvPolicy {
+	if cfg == nil {
+		return EnvPolicy{}
+	}
+	var policy EnvPolicy
+	policy.Allow = append(policy.Allow, providerEnvAllow[strings.ToLower(cfg.Provider.Type)]...)
+	policy.Allow = append(policy.Allow, cfg.Settings.Env.Allow...)
+	policy.Deny = cfg.Settings.Env.Deny
+	policy.Set = cfg.Actions[action].Env
+	return policy
+}
+
+// environ filters environ (KEY=value entries, as from os.Environ) through
+// policy and appends its Set entries in name order.
+func (policy EnvPolicy) environ(environ []string) []string {
+	var env []string
+	for _, entry := range environ {
+		name, _, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if matchesEnv(name, policy.Deny) {
+			continue
+		}
+		if matchesEnv(name, defaultEnvAllow) || matchesEnv(name, policy.Allow) {
+			env = append(env, entry)
+		}
+	}
+	names := make([]string, 0, len(policy.Set))
+	for name := range policy.Set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+policy.Set[name])
+	}
+	return env
+}
+
+// matchesEnv reports whether name equals one of patterns or starts with a
+// pattern's prefix before a trailing *.
+func matchesEnv(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+func TestEnvPolicyEnviron(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin",
+		"HOME=/home/me",
+		"LC_ALL=C",
+		"CBAI_CONFIG_FILE=/tmp/config.toml",
+		"AWS_SECRET_ACCESS_KEY=secret",
+		"AWS_PROFILE=work",
+		"GITHUB_TOKEN=ghp_x",
+		"OPENAI_API_KEY=sk-x",
+	}
+	tests := []struct {
+		name   string
+		policy EnvPolicy
+		want   []string
+	}{
+		{
+			name:   "defaults only",
+			policy: EnvPolicy{},
+			want:   []string{"PATH=/usr/bin", "HOME=/home/me", "LC_ALL=C", "CBAI_CONFIG_FILE=/tmp/config.toml"},
+		},
+		{
+			name:   "allow by name and prefix",
+			policy: EnvPolicy{Allow: []string{"GITHUB_TOKEN", "AWS_*"}},
+			want: []string{"PATH=/usr/bin", "HOME=/home/me", "LC_ALL=C", "CBAI_CONFIG_FILE=/tmp/config.toml",
+				"AWS_SECRET_ACCESS_KEY=secret", "AWS_PROFILE=work", "GITHUB_TOKEN=ghp_x"},
+		},
+		{
+			name:   "deny wins over allow and defaults",
+			policy: EnvPolicy{Allow: []string{"AWS_*"}, Deny: []string{"AWS_SECRET_*", "CBAI_*"}},
+			want:   []string{"PATH=/usr/bin", "HOME=/home/me", "LC_ALL=C", "AWS_PROFILE=work"},
+		},
+		{
+			name:   "explicit entries are added even when denied",
+			policy: EnvPolicy{Deny: []string{"LANG"}, Set: map[string]string{"LANG": "de_DE.UTF-8", "DEBUG": "1"}},
+			want:   []string{"PATH=/usr/bin", "HOME=/home/me", "LC_ALL=C", "CBAI_CONFIG_FILE=/tmp/config.toml", "DEBUG=1", "LANG=de_DE.UTF-8"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.environ(environ); !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEnvFor(t *testing.T) {
+	cfg := config.Default()
+	cfg.Provider.Type = "openai"
+	cfg.Settings.Env = config.EnvConfig{Allow: []string{"AWS_*"}, Deny: []string{"AWS_SECRET_*"}}
+	cfg.Actions["summarize"] = config.ActionConfig{Enabled: true, Env: map[string]string{"DEBUG": "1"}}
+
+	policy := EnvFor(cfg, "summarize")
+	if !slices.Contains(policy.Allow, "OPENAI_API_KEY") || !slices.Contains(policy.Allow, "AWS_*") {
+		t.Fatalf("expected provider and configured variables to be allowed, got %v", policy.Allow)
+	}
+	if !slices.Equal(policy.Deny, []string{"AWS_SECRET_*"}) || policy.Set["DEBUG"] != "1" {
+		t.Fatalf("unexpected policy: %+v", policy)
+	}
+
+	cfg.Provider.Type = "ollama"
+	if policy := EnvFor(cfg, "explain"); slices.Contains(policy.Allow, "OPENAI_API_KEY") || policy.Set != nil {
+		t.Fatalf("expected no provider keys or entries for a local provider, got %+v", policy)
+	}
+}
+
+func TestRunExecuteWithOptions_DoesNotInheritUnrelatedEnv(t *testing.T) {
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "leaked-secret")
+	t.Setenv("GITHUB_TOKEN", "leaked-token")
+	t.Setenv("LANG", "en_US.UTF-8")
+	useFakeCbai(t, "env\n")
+
+	result := runExecuteWithOptions(context.Background(), "summary", "input", Options{
+		Env: EnvPolicy{Set: map[string]string{"ACTION_FLAG": "on"}},
+	})
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	env := strings.Split(strings.TrimSpace(result.Output), "\n")
+	for _, leaked := range []string{"AWS_SECRET_ACCESS_KEY=leaked-secret", "GITHUB_TOKEN=leaked-token"} {
+		if slices.Contains(env, leaked) {
+			t.Fatalf("cbai inherited %s", leaked)
+		}
+	}
+	for _, want := range []string{"LANG=en_US.UTF-8", "ACTION_FLAG=on", "CBAI_DAEMON_MODE=true"} {
+		if !slices.Contains(env, want) {
+			t.Fatalf("expected %s in cbai's environment, got %v", want, env)
+		}
+	}
+}
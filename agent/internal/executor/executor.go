@@ -3,25 +3,109 @@ package executor
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"strings"
 	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/sandbox"
 )
 
 const defaultTimeout = 30 * time.Second
 
+// inputEnvelopeVersion is the version of the stdin input envelope; cbai
+// rejects envelopes newer than it understands.
+const inputEnvelopeVersion = 1
+
+// inputEnvelope is the action input written to cbai's stdin as one JSON
+// document when CBAI_INPUT_STDIN=1 (see cli/src/lib/input.ts): the content and
+// where, when and why it was captured. Unlike environment variables it has no
+// size limit and isn't visible to other processes.
+type inputEnvelope struct {
+	Version   int    `json:"version"`
+	Text      string `json:"text"`
+	RTF       string `json:"rtf,omitempty"`
+	Type      string `json:"type,omitempty"`
+	ImagePath string `json:"image_path,omitempty"`
+	ImageMime string `json:"image_mime,omitempty"`
+	Source    string `json:"source,omitempty"`    // InputSource*
+	Trigger   string `json:"trigger,omitempty"`   // the trigger that matched
+	Timestamp string `json:"timestamp,omitempty"` // when the content was copied, RFC 3339
+}
+
+// Input sources (Options.InputSource): where an action's input came from.
+const (
+	InputSourceClipboard = "clipboard" // the clipboard, as copied
+	InputSourceRequest   = "request"   // the body of an /action request
+	InputSourcePipeline  = "pipeline"  // the previous step's output
+)
+
 // Options controls action execution behavior.
 type Options struct {
-	Timeout           time.Duration
-	Trigger           string
-	InputType         string
-	InputRTF          string
-	InputImagePath    string
-	InputImageMime    string
+	Timeout        time.Duration
+	Trigger        string
+	InputType      string
+	InputRTF       string
+	InputImagePath string
+	InputImageMime string
+	// InputSource is an InputSource* constant and InputTimestamp when the
+	// input was copied; both are passed to cbai in the stdin envelope.
+	InputSource       string
+	InputTimestamp    time.Time
 	SensitiveGuardHit bool
 	ModelOverride     string
 	EndpointOverride  string
 	Args              []string
+	// Match holds named regex captures from the trigger; each is exported to
+	// the action as CBAI_MATCH_<NAME>.
+	Match map[string]string
+	// Prompt, when set, runs a config-defined prompt template in-process
+	// against the provider instead of spawning cbai (see PromptFor).
+	Prompt *PromptConfig
+	// Command, when set, runs a command action's program instead of cbai (see
+	// CommandFor).
+	Command *CommandConfig
+	// Transform, when set, runs a built-in transform in-process instead of
+	// cbai (see TransformFor).
+	Transform *TransformConfig
+	// Webhook, when set, sends the clipboard event to an HTTP endpoint
+	// instead of running cbai (see WebhookFor).
+	Webhook *WebhookConfig
+	// Pipeline, when set, runs other actions in sequence instead of cbai
+	// (see PipelineFor).
+	Pipeline *PipelineConfig
+	// Cache, when set, serves the result of an identical earlier run and
+	// caches this one's (see CacheFor).
+	Cache *CacheConfig
+	// OnToken receives streamed completion tokens of an in-process prompt run.
+	OnToken func(token string)
+	// EnvInput passes the input in the legacy CBAI_INPUT_* environment
+	// variables instead of the stdin envelope, for CLIs that predate it
+	// (settings.action_input = "env").
+	EnvInput bool
+	// Limits are rlimits applied to the cbai subprocess (Unix only).
+	Limits config.LimitsConfig
+	// Sandbox confines the cbai subprocess's filesystem access with Landlock
+	// (Linux only, settings.sandbox).
+	Sandbox bool
+	// HistorySettings are the history settings a sandboxed cbai run's
+	// records are collected under (see runHistory).
+	HistorySettings config.SettingsConfig
+	// NoHistory keeps the run out of history: a pipeline step, which its
+	// pipeline's record reports.
+	NoHistory bool
+	// NoNetwork runs the cbai subprocess without network access (Linux only,
+	// actions.<name>.no_network).
+	NoNetwork bool
+	// Env decides which of the agent's environment variables the cbai
+	// subprocess sees (see EnvFor); the zero value passes only the defaults.
+	Env EnvPolicy
 }
 
 // ExecuteFunc allows tests to override the executor behavior.
@@ -38,6 +122,24 @@ type Result struct {
 	Output  string
 	Error   error
 	Elapsed time.Duration
+	// Code classifies Error; empty when the failure wasn't classified.
+	Code     ErrorCode
+	Provider string
+	Model    string
+	Usage    Usage
+	Latency  Latency
+	// Warnings are non-fatal problems reported by the action, such as output
+	// truncated at max_tokens.
+	Warnings []string
+	// Diagnostics is the action's stderr, kept apart from Output.
+	Diagnostics string
+	// Sandbox reports the protections applied to the subprocess; nil when
+	// none were requested.
+	Sandbox *sandbox.Status
+	// Steps reports each step of a pipeline run, in order.
+	Steps []StepResult
+	// Cached reports that Output was served from the result cache.
+	Cached bool
 }
 
 // Execute spawns `cbai <action>` and captures its output
@@ -45,7 +147,9 @@ func Execute(ctx context.Context, action string, text string) Result {
 	return ExecuteWithOptions(ctx, action, text, Options{})
 }
 
-// ExecuteWithOptions spawns `cbai run <action>` with optional execution controls.
+// ExecuteWithOptions spawns `cbai run <action>` with optional execution
+// controls, or runs opts.Prompt, opts.Command, opts.Transform, opts.Webhook or
+// opts.Pipeline when set.
 func ExecuteWithOptions(ctx context.Context, action string, text string, opts Options) Result {
 	return executeWithOptionsFn(ctx, action, text, opts)
 }
@@ -68,68 +172,209 @@ func ResetExecuteFunc() {
 }
 
 func runExecuteWithOptions(ctx context.Context, action string, text string, opts Options) Result {
-	start := time.Now()
-
-	timeout := opts.Timeout
-	if timeout <= 0 {
-		timeout = defaultTimeout
+	if opts.Cache != nil {
+		return runCached(ctx, action, text, opts)
+	}
+	if opts.Prompt != nil {
+		return runPrompt(ctx, action, text, opts)
+	}
+	if opts.Command != nil {
+		return runCommand(ctx, action, text, opts)
+	}
+	if opts.Transform != nil {
+		return runTransform(ctx, action, text, opts)
+	}
+	if opts.Webhook != nil {
+		return runWebhook(ctx, action, text, opts)
+	}
+	if opts.Pipeline != nil {
+		return runPipeline(ctx, action, text, opts)
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
+	start := time.Now()
 
 	// Separate user-controlled args with "--" so clipboard-derived values can't
 	// be parsed as cbai global flags (e.g. an injected --force bypassing the
 	// sensitive-data guard). cbai routes post-"--" tokens into the action args.
-	cmdArgs := append([]string{"run", action, "--"}, opts.Args...)
-	cmd := exec.CommandContext(ctx, "cbai", cmdArgs...)
-	cmd.Env = append(os.Environ(), "CBAI_DAEMON_MODE=true")
-	if opts.Trigger != "" {
-		cmd.Env = append(cmd.Env, "CBAI_TRIGGER="+opts.Trigger)
-	}
-	if opts.InputType != "" {
-		cmd.Env = append(cmd.Env, "CBAI_INPUT_TYPE="+opts.InputType)
-	}
-	if text != "" {
-		cmd.Env = append(cmd.Env, "CBAI_INPUT_TEXT="+text)
-	}
-	if opts.InputRTF != "" {
-		cmd.Env = append(cmd.Env, "CBAI_INPUT_RTF="+opts.InputRTF)
+	proc := process{
+		name:     "cbai",
+		args:     append([]string{"run", action, "--"}, opts.Args...),
+		env:      append(opts.Env.environ(os.Environ()), "CBAI_DAEMON_MODE=true", "CBAI_RESULT_FORMAT=json"),
+		programs: []string{"cbai", "node", "bun"},
 	}
-	if opts.InputImagePath != "" {
-		cmd.Env = append(cmd.Env, "CBAI_INPUT_IMAGE_PATH="+opts.InputImagePath)
+	if opts.Trigger != "" {
+		proc.env = append(proc.env, "CBAI_TRIGGER="+opts.Trigger)
 	}
-	if opts.InputImageMime != "" {
-		cmd.Env = append(cmd.Env, "CBAI_INPUT_IMAGE_MIME="+opts.InputImageMime)
+	if opts.EnvInput {
+		proc.env = append(proc.env, inputEnv(text, opts)...)
+	} else {
+		input := inputEnvelope{
+			Version:   inputEnvelopeVersion,
+			Text:      text,
+			RTF:       opts.InputRTF,
+			Type:      opts.InputType,
+			ImagePath: opts.InputImagePath,
+			ImageMime: opts.InputImageMime,
+			Source:    opts.InputSource,
+			Trigger:   opts.Trigger,
+		}
+		if !opts.InputTimestamp.IsZero() {
+			input.Timestamp = opts.InputTimestamp.UTC().Format(time.RFC3339Nano)
+		}
+		envelope, err := json.Marshal(input)
+		if err != nil {
+			return Result{Action: action, Error: err, Elapsed: time.Since(start)}
+		}
+		proc.env = append(proc.env, "CBAI_INPUT_STDIN=1")
+		proc.stdin = bytes.NewReader(envelope)
 	}
 	if opts.SensitiveGuardHit {
-		cmd.Env = append(cmd.Env, "CBAI_SENSITIVE_GUARD_HIT=true")
+		proc.env = append(proc.env, "CBAI_SENSITIVE_GUARD_HIT=true")
 	}
 	if opts.ModelOverride != "" {
-		cmd.Env = append(cmd.Env, "CBAI_MODEL_OVERRIDE="+opts.ModelOverride)
+		proc.env = append(proc.env, "CBAI_MODEL_OVERRIDE="+opts.ModelOverride)
 	}
 	if opts.EndpointOverride != "" {
-		cmd.Env = append(cmd.Env, "CBAI_ENDPOINT_OVERRIDE="+opts.EndpointOverride)
+		proc.env = append(proc.env, "CBAI_ENDPOINT_OVERRIDE="+opts.EndpointOverride)
+	}
+	proc.env = append(proc.env, matchEnv(opts.Match)...)
+	if opts.Sandbox || opts.NoHistory {
+		historyFile, cleanup := runHistory(action, opts)
+		defer cleanup()
+		if historyFile != "" {
+			proc.env = append(proc.env, "CBAI_HISTORY_FILE="+historyFile)
+		}
+	}
+
+	run := runProcess(ctx, action, proc, opts)
+	result := run.newResult(action, start)
+	// A truncated stdout has lost its trailing envelope, if it had one.
+	if envelope, ok := parseResultEnvelope(run.stdout.Bytes()); ok && !run.stdout.Truncated() {
+		applyEnvelope(&result, envelope)
+	} else {
+		result.Output = run.stdout.String()
+		if run.err != nil {
+			result.Code = legacyErrorCode(result.Diagnostics)
+		}
+	}
+	run.applyTimeout(&result)
+	applySuperseded(ctx, &result)
+	return result
+}
+
+// process is one action subprocess to run.
+type process struct {
+	name  string
+	args  []string
+	env   []string
+	stdin io.Reader
+	// programs are looked up on PATH and made readable under the sandbox.
+	programs []string
+}
+
+// processRun is what runProcess observed of a subprocess.
+type processRun struct {
+	stdout  *cappedBuffer
+	stderr  *cappedBuffer
+	err     error
+	sandbox *sandbox.Status
+	// timeout is the deadline the run hit, or zero.
+	timeout time.Duration
+}
+
+// runProcess runs proc under opts' timeout, resource limits and sandbox, in
+// its own process group, capturing capped stdout and stderr.
+func runProcess(ctx context.Context, action string, proc process, opts Options) processRun {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
 	}
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	name, args := limitedCommand(opts.Limits, proc.name, proc.args)
+	cmd := exec.CommandContext(ctx, name, args...)
+	startInProcessGroup(cmd)
+	run := processRun{
+		stdout: newCappedBuffer(maxStdoutBytes),
+		stderr: newCappedBuffer(maxStderrBytes),
+	}
+	if opts.Sandbox || opts.NoNetwork {
+		status := sandbox.Apply(cmd, sandboxPolicy(opts, proc.programs...))
+		run.sandbox = &status
+	}
+	cmd.Env = proc.env
+	cmd.Stdin = proc.stdin
+	cmd.Stdout = run.stdout
+	cmd.Stderr = run.stderr
 
-	elapsed := time.Since(start)
+	run.err = cmd.Run()
+	// The leader exiting cleanly while a background grandchild held the
+	// output pipes isn't an action failure; the grandchild is ended below.
+	if errors.Is(run.err, exec.ErrWaitDelay) {
+		run.err = nil
+	}
+	endProcessGroup(cmd)
 
-	output := stdout.String()
-	if output == "" && stderr.String() != "" {
-		output = stderr.String()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		run.timeout = timeout
+	}
+	if run.stderr.Len() > 0 {
+		slog.Debug("action stderr", "action", action, "stderr", run.stderr.String())
 	}
+	return run
+}
 
+// newResult returns the run's Result before its stdout is interpreted.
+func (run processRun) newResult(action string, start time.Time) Result {
 	return Result{
-		Action:  action,
-		Output:  output,
-		Error:   err,
-		Elapsed: elapsed,
+		Action:      action,
+		Error:       run.err,
+		Elapsed:     time.Since(start),
+		Diagnostics: run.stderr.String(),
+		Sandbox:     run.sandbox,
+	}
+}
+
+// applyTimeout reports a run that hit its deadline as a timeout, overriding
+// whatever the action said about its failure.
+func (run processRun) applyTimeout(result *Result) {
+	if run.timeout > 0 {
+		result.Code = CodeTimeout
+		result.Error = fmt.Errorf("action timed out after %s", run.timeout)
+	}
+}
+
+// matchEnv exports trigger regex captures as CBAI_MATCH_<NAME> variables.
+func matchEnv(captures map[string]string) []string {
+	var env []string
+	for name, value := range captures {
+		env = append(env, "CBAI_MATCH_"+strings.ToUpper(name)+"="+value)
+	}
+	return env
+}
+
+// inputEnv returns the legacy CBAI_INPUT_* environment variables for the
+// input.
+func inputEnv(text string, opts Options) []string {
+	var env []string
+	if opts.InputType != "" {
+		env = append(env, "CBAI_INPUT_TYPE="+opts.InputType)
+	}
+	if text != "" {
+		env = append(env, "CBAI_INPUT_TEXT="+text)
+	}
+	if opts.InputRTF != "" {
+		env = append(env, "CBAI_INPUT_RTF="+opts.InputRTF)
+	}
+	if opts.InputImagePath != "" {
+		env = append(env, "CBAI_INPUT_IMAGE_PATH="+opts.InputImagePath)
+	}
+	if opts.InputImageMime != "" {
+		env = append(env, "CBAI_INPUT_IMAGE_MIME="+opts.InputImageMime)
 	}
+	return env
 }
 
 // WriteTempImage writes image bytes to a temp file and returns its path.
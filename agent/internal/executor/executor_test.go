@@ -2,8 +2,10 @@ package executor
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -191,3 +193,112 @@ printf '%s' "$*"
 		t.Fatalf("expected args separated by --, got %q", result.Output)
 	}
 }
+
+func TestRunExecuteWithOptions_ExportsMatchCaptures(t *testing.T) {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "cbai")
+	script := `#!/bin/sh
+printf '%s|%s' "$CBAI_MATCH_LANG" "$*"
+`
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake cbai: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	result := runExecuteWithOptions(context.Background(), "translate", "input", Options{
+		Args:  []string{"Spanish"},
+		Match: map[string]string{"lang": "Spanish"},
+	})
+
+	if result.Error != nil {
+		t.Fatalf("expected fake cbai to succeed, got %v", result.Error)
+	}
+	expected := "Spanish|run translate -- Spanish"
+	if result.Output != expected {
+		t.Fatalf("expected output %q, got %q", expected, result.Output)
+	}
+}
+
+func TestRunExecuteWithOptions_PassesInputOverStdin(t *testing.T) {
+	dir := t.TempDir()
+	stdinPath := filepath.Join(dir, "stdin.json")
+	scriptPath := filepath.Join(dir, "cbai")
+	script := `#!/bin/sh
+cat > "$CBAI_TEST_STDIN"
+printf '%s|%s' "$CBAI_INPUT_STDIN" "${CBAI_INPUT_TEXT:-unset}"
+`
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake cbai: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("CBAI_TEST_STDIN", stdinPath)
+
+	// Well past the ~128 KiB per-variable and ~2 MiB total limits execve puts
+	// on the environment.
+	text := strings.Repeat("clipboard ünïcode line\n", 400_000)
+	result := runExecuteWithOptions(context.Background(), "summary", text, Options{
+		InputType:      "image",
+		InputRTF:       "{\\rtf1 hi}",
+		InputImagePath: "/tmp/clip.png",
+		InputImageMime: "image/png",
+		InputSource:    InputSourceClipboard,
+		InputTimestamp: time.Date(2026, 6, 11, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+		Trigger:        "length > 100",
+	})
+
+	if result.Error != nil {
+		t.Fatalf("expected fake cbai to succeed, got %v", result.Error)
+	}
+	if result.Output != "1|unset" {
+		t.Fatalf("expected CBAI_INPUT_STDIN=1 and no CBAI_INPUT_TEXT, got %q", result.Output)
+	}
+
+	data, err := os.ReadFile(stdinPath)
+	if err != nil {
+		t.Fatalf("failed to read captured stdin: %v", err)
+	}
+	var got inputEnvelope
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("expected a JSON envelope on stdin: %v", err)
+	}
+	want := inputEnvelope{
+		Version:   inputEnvelopeVersion,
+		Text:      text,
+		RTF:       "{\\rtf1 hi}",
+		Type:      "image",
+		ImagePath: "/tmp/clip.png",
+		ImageMime: "image/png",
+		Source:    InputSourceClipboard,
+		Trigger:   "length > 100",
+		Timestamp: "2026-06-11T10:00:00Z",
+	}
+	if got != want {
+		t.Fatalf("envelope mismatch: got %d bytes of text, type=%q rtf=%q image=%q/%q source=%q trigger=%q timestamp=%q",
+			len(got.Text), got.Type, got.RTF, got.ImagePath, got.ImageMime, got.Source, got.Trigger, got.Timestamp)
+	}
+}
+
+func TestRunExecuteWithOptions_EnvInputCompatibility(t *testing.T) {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "cbai")
+	script := `#!/bin/sh
+printf '%s|%s|%s|%s' "${CBAI_INPUT_STDIN:-unset}" "$CBAI_INPUT_TEXT" "$CBAI_INPUT_TYPE" "$(cat)"
+`
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake cbai: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	result := runExecuteWithOptions(context.Background(), "summary", "input", Options{
+		InputType: "text",
+		EnvInput:  true,
+	})
+
+	if result.Error != nil {
+		t.Fatalf("expected fake cbai to succeed, got %v", result.Error)
+	}
+	expected := "unset|input|text|"
+	if result.Output != expected {
+		t.Fatalf("expected output %q, got %q", expected, result.Output)
+	}
+}
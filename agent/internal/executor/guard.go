@@ -0,0 +1,31 @@
+package executor
+
+import (
+	"errors"
+
+	"github.com/clipboard-ai/agent/internal/guard"
+)
+
+// errGuardBlocked is the error of a run the sensitive guard blocked, worded
+// as cbai words it.
+var errGuardBlocked = errors.New("clipboard looks like it contains a secret — action skipped")
+
+// scanGuard reports whether the sensitive guard, in the given mode, flags the
+// input, and whether the mode blocks the run. The daemon scans before
+// dispatching and reports a hit in opts, which is taken as is; otherwise
+// (e.g. an /action request) the text and its RTF are scanned here as cbai
+// would.
+func scanGuard(mode string, text string, opts Options) (hit bool, blocked bool) {
+	if opts.SensitiveGuardHit {
+		return true, false
+	}
+	if mode == "off" {
+		return false, false
+	}
+	input := text
+	if opts.InputRTF != "" {
+		input += "\n" + opts.InputRTF
+	}
+	hit = len(guard.Scan(input)) > 0
+	return hit, hit && mode == "block"
+}
@@ -0,0 +1,53 @@
+package executor
+
+import (
+	"strings"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/scheduler"
+)
+
+// LimitsFor returns the concurrency limits a run of action with opts is
+// subject to besides the global one: the action's max_concurrent, and the
+// endpoint_limits of each provider endpoint it may call. A pipeline counts
+// against its steps' endpoints but not their own actions' limits.
+func LimitsFor(cfg *config.Config, action string, opts Options) []scheduler.Limit {
+	if cfg == nil {
+		return nil
+	}
+	var limits []scheduler.Limit
+	if limit := cfg.Actions[action].MaxConcurrent; limit > 0 {
+		limits = append(limits, scheduler.Limit{Key: "action:" + action, Max: limit})
+	}
+	seen := make(map[string]bool)
+	for _, endpoint := range providerEndpoints(cfg, opts) {
+		endpoint = strings.TrimRight(endpoint, "/")
+		if seen[endpoint] {
+			continue
+		}
+		seen[endpoint] = true
+		if limit := cfg.Provider.EndpointLimit(endpoint); limit > 0 {
+			limits = append(limits, scheduler.Limit{Key: "endpoint:" + endpoint, Max: limit})
+		}
+	}
+	return limits
+}
+
+// providerEndpoints returns the provider endpoints a run may call: none for
+// commands, transforms and webhooks, and its steps' for a pipeline.
+func providerEndpoints(cfg *config.Config, opts Options) []string {
+	switch {
+	case opts.Pipeline != nil:
+		var endpoints []string
+		for _, step := range opts.Pipeline.Steps {
+			endpoints = append(endpoints, providerEndpoints(cfg, step.Options)...)
+		}
+		return endpoints
+	case opts.Command != nil, opts.Transform != nil, opts.Webhook != nil:
+		return nil
+	case opts.EndpointOverride != "":
+		return []string{opts.EndpointOverride}
+	default:
+		return []string{cfg.Provider.Endpoint}
+	}
+}
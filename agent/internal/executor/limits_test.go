@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/scheduler"
+)
+
+func TestLimitsFor(t *testing.T) {
+	usePromptFixtures(t)
+	cfg := config.Default()
+	cfg.Provider.EndpointLimits = map[string]int{"http://localhost:11434/v1": 1, "http://gpu-box:11434/v1/": 2}
+	cfg.Actions["caption"] = config.ActionConfig{MaxConcurrent: 1}
+	cfg.Actions["remote"] = config.ActionConfig{Endpoint: "http://gpu-box:11434/v1"}
+	cfg.Actions["fmt"] = config.ActionConfig{Kind: config.ActionKindCommand, Command: []string{"jq"}, MaxConcurrent: 2}
+	cfg.Actions["flow"] = config.ActionConfig{Kind: config.ActionKindPipeline, Pipeline: []string{"fmt", "summarize", "remote", "explain"}}
+
+	local := scheduler.Limit{Key: "endpoint:http://localhost:11434/v1", Max: 1}
+	gpu := scheduler.Limit{Key: "endpoint:http://gpu-box:11434/v1", Max: 2}
+	tests := []struct {
+		action string
+		opts   Options
+		want   []scheduler.Limit
+	}{
+		{action: "summarize", want: []scheduler.Limit{local}},
+		{action: "caption", want: []scheduler.Limit{{Key: "action:caption", Max: 1}, local}},
+		{action: "summarize", opts: Options{EndpointOverride: "http://gpu-box:11434/v1/"}, want: []scheduler.Limit{gpu}},
+		{action: "summarize", opts: Options{EndpointOverride: "https://api.openai.com/v1"}},
+		{action: "fmt", opts: Options{Command: CommandFor(cfg, "fmt", nil)}, want: []scheduler.Limit{{Key: "action:fmt", Max: 2}}},
+		{action: "json-pretty", opts: Options{Transform: TransformFor(cfg, "json-pretty")}},
+		{action: "flow", opts: Options{Pipeline: PipelineFor(cfg, "flow", nil)}, want: []scheduler.Limit{local, gpu}},
+	}
+	for _, tt := range tests {
+		if got := LimitsFor(cfg, tt.action, tt.opts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %+v: expected limits %v, got %v", tt.action, tt.opts.EndpointOverride, tt.want, got)
+		}
+	}
+}
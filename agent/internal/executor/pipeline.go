@@ -0,0 +1,226 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/history"
+	"github.com/clipboard-ai/agent/internal/rules"
+)
+
+// Pipeline step statuses (StepResult.Status).
+const (
+	StepSuccess = "success"
+	StepError   = "error"
+	StepSkipped = "skipped"
+)
+
+// PipelineConfig describes a run of a pipeline action: other actions run in
+// order, each on the previous step's output.
+type PipelineConfig struct {
+	Steps []PipelineStep
+	// OnStepError is config.OnStepErrorAbort or config.OnStepErrorSkip.
+	OnStepError string
+	// Settings supplies the sensitive guard mode and history retention.
+	Settings config.SettingsConfig
+}
+
+// PipelineStep is one action of a pipeline with its own execution options,
+// timeout and retry policy. Its input fields are filled in per run.
+type PipelineStep struct {
+	Action  string
+	Options Options
+	Retry   RetryPolicy
+}
+
+// StepResult reports one step of a pipeline run.
+type StepResult struct {
+	Action    string    `json:"action"`
+	Status    string    `json:"status"` // success, error or skipped
+	Attempts  int       `json:"attempts"`
+	LatencyMs int       `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	Code      ErrorCode `json:"error_code,omitempty"`
+}
+
+// PipelineFor returns the pipeline config for action, or nil when it isn't a
+// pipeline action. Each step runs with the options, timeout_ms and retry
+// policy of the action it names; captures fill its {{match.<group>}}
+// placeholders.
+func PipelineFor(cfg *config.Config, action string, captures map[string]string) *PipelineConfig {
+	if cfg == nil {
+		return nil
+	}
+	actionCfg, ok := cfg.Actions[action]
+	if !ok || actionCfg.Kind != config.ActionKindPipeline || len(actionCfg.Pipeline) == 0 {
+		return nil
+	}
+	pipeline := &PipelineConfig{OnStepError: actionCfg.OnStepError, Settings: cfg.Settings}
+	if pipeline.OnStepError == "" {
+		pipeline.OnStepError = config.OnStepErrorAbort
+	}
+	for _, name := range actionCfg.Pipeline {
+		stepCfg := cfg.Actions[name]
+		step := PipelineStep{
+			Action: name,
+			Options: Options{
+				ModelOverride:    stepCfg.Model,
+				EndpointOverride: stepCfg.Endpoint,
+				Args:             rules.RenderArgs(stepCfg.Args, captures),
+				Prompt:           PromptFor(cfg, name),
+				Command:          CommandFor(cfg, name, captures),
+				Transform:        TransformFor(cfg, name),
+				Webhook:          WebhookFor(cfg, name),
+				EnvInput:         cfg.Settings.ActionInput == "env",
+				Limits:           stepCfg.Limits,
+				Sandbox:          cfg.Settings.Sandbox,
+				HistorySettings:  cfg.Settings,
+				NoHistory:        true,
+				NoNetwork:        stepCfg.NoNetwork,
+				Env:              EnvFor(cfg, name),
+				Cache:            CacheFor(cfg, name),
+			},
+			Retry: RetryPolicyFor(stepCfg),
+		}
+		if stepCfg.TimeoutMs > 0 {
+			step.Options.Timeout = time.Duration(stepCfg.TimeoutMs) * time.Millisecond
+		}
+		pipeline.Steps = append(pipeline.Steps, step)
+	}
+	return pipeline
+}
+
+// runPipeline runs opts.Pipeline's steps in order, feeding each step's output
+// to the next. A failed step aborts the run or, with OnStepError skip, passes
+// its input on unchanged. opts.Timeout, when set, bounds the whole run.
+func runPipeline(ctx context.Context, action string, text string, opts Options) Result {
+	start := time.Now()
+	pipeline := opts.Pipeline
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	result := Result{Action: action}
+	// Scan once up front so every step, and the pipeline's own history
+	// record, treats the input as sensitive.
+	guardHit, blocked := scanGuard(pipeline.Settings.SensitiveGuard, text, opts)
+	if blocked {
+		result.Code = CodeGuardBlocked
+		result.Error = errGuardBlocked
+	}
+
+	input := text
+	succeeded := 0
+	for i, step := range pipeline.Steps {
+		if result.Error != nil {
+			break
+		}
+		stepOpts := step.Options
+		stepOpts.Trigger = opts.Trigger
+		stepOpts.Match = opts.Match
+		stepOpts.SensitiveGuardHit = guardHit
+		stepOpts.InputTimestamp = opts.InputTimestamp
+		if i == 0 {
+			stepOpts.InputSource = opts.InputSource
+			stepOpts.InputType = opts.InputType
+			stepOpts.InputRTF = opts.InputRTF
+			stepOpts.InputImagePath = opts.InputImagePath
+			stepOpts.InputImageMime = opts.InputImageMime
+		} else {
+			stepOpts.InputSource = InputSourcePipeline
+			stepOpts.InputType = "text"
+		}
+
+		stepResult, attempts := runStep(ctx, step, input, stepOpts)
+		report := StepResult{
+			Action:    step.Action,
+			Status:    StepSuccess,
+			Attempts:  attempts,
+			LatencyMs: int(stepResult.Elapsed.Milliseconds()),
+		}
+		result.Usage.PromptTokens += stepResult.Usage.PromptTokens
+		result.Usage.CompletionTokens += stepResult.Usage.CompletionTokens
+		result.Latency.Provider += stepResult.Latency.Provider
+		for _, warning := range stepResult.Warnings {
+			result.Warnings = append(result.Warnings, step.Action+": "+warning)
+		}
+
+		switch {
+		case stepResult.Error == nil:
+			input = stepResult.Output
+			succeeded++
+			if stepResult.Provider != "" || stepResult.Model != "" {
+				result.Provider, result.Model = stepResult.Provider, stepResult.Model
+			}
+		case pipeline.OnStepError == config.OnStepErrorSkip && ctx.Err() == nil:
+			report.Status = StepSkipped
+			report.Error, report.Code = stepResult.Error.Error(), stepResult.Code
+			result.Warnings = append(result.Warnings, fmt.Sprintf("step %d (%s) failed and was skipped: %v", i+1, step.Action, stepResult.Error))
+		default:
+			report.Status = StepError
+			report.Error, report.Code = stepResult.Error.Error(), stepResult.Code
+			result.Error = fmt.Errorf("step %d (%s): %w", i+1, step.Action, stepResult.Error)
+			result.Code = stepResult.Code
+			result.Diagnostics = stepResult.Diagnostics
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				result.Code = CodeTimeout
+			}
+		}
+		result.Steps = append(result.Steps, report)
+	}
+	if result.Error == nil && succeeded == 0 {
+		result.Error = errors.New("every pipeline step failed")
+	}
+	if result.Error == nil {
+		result.Output = input
+	}
+	result.Elapsed = time.Since(start)
+	result.Latency.Process = result.Elapsed
+	applySuperseded(ctx, &result)
+
+	recordOpts := opts
+	recordOpts.SensitiveGuardHit = guardHit
+	recordLocalRun(action, text, recordOpts, config.ActionKindPipeline, stepNames(pipeline.Steps), result, pipeline.Settings)
+	return result
+}
+
+// runStep runs one pipeline step, retrying transient failures under the
+// step's policy, and returns the last result and the attempts made.
+func runStep(ctx context.Context, step PipelineStep, input string, opts Options) (Result, int) {
+	return retry(ctx, step.Retry, nil, nil, func() Result {
+		return runExecuteWithOptions(ctx, step.Action, input, opts)
+	})
+}
+
+// stepNames joins the steps' action names for history, e.g. "ocr | summarize".
+func stepNames(steps []PipelineStep) string {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = step.Action
+	}
+	return strings.Join(names, " | ")
+}
+
+// historySteps converts step reports to their history record form.
+func historySteps(steps []StepResult) []history.Step {
+	if len(steps) == 0 {
+		return nil
+	}
+	out := make([]history.Step, len(steps))
+	for i, step := range steps {
+		out[i] = history.Step{
+			Action:    step.Action,
+			Status:    step.Status,
+			Attempts:  step.Attempts,
+			LatencyMs: step.LatencyMs,
+			Error:     step.Error,
+		}
+	}
+	return out
+}
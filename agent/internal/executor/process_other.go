@@ -0,0 +1,20 @@
+//go:build !unix
+
+package executor
+
+import (
+	"os/exec"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+// startInProcessGroup is a no-op without Unix process groups; cancellation
+// kills the direct child only.
+func startInProcessGroup(cmd *exec.Cmd) {}
+
+func endProcessGroup(cmd *exec.Cmd) {}
+
+// limitedCommand ignores limits: rlimits are only applied on Unix.
+func limitedCommand(_ config.LimitsConfig, name string, args []string) (string, []string) {
+	return name, args
+}
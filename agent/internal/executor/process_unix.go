@@ -0,0 +1,89 @@
+//go:build unix
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+// terminateGrace is how long an action's process group gets to exit after
+// SIGTERM before it is killed.
+var terminateGrace = 2 * time.Second
+
+// groupPollInterval is how often a terminating process group is checked.
+const groupPollInterval = 20 * time.Millisecond
+
+// startInProcessGroup makes cmd the leader of a new process group, so that
+// cancellation (timeout or shutdown) reaches everything the action spawned:
+// the group gets SIGTERM instead of the leader getting SIGKILL.
+func startInProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return signalGroup(cmd.Process.Pid, syscall.SIGTERM)
+	}
+	// Bounds both a leader that ignores SIGTERM and a background grandchild
+	// holding the output pipes open after the leader exits.
+	cmd.WaitDelay = terminateGrace
+}
+
+// endProcessGroup terminates whatever is left of cmd's process group once the
+// leader has exited: SIGTERM, then SIGKILL for members still alive after
+// terminateGrace.
+func endProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	pgid := cmd.Process.Pid
+	if signalGroup(pgid, syscall.Signal(0)) != nil {
+		return
+	}
+	signalGroup(pgid, syscall.SIGTERM)
+	deadline := time.Now().Add(terminateGrace)
+	for time.Now().Before(deadline) {
+		time.Sleep(groupPollInterval)
+		if signalGroup(pgid, syscall.Signal(0)) != nil {
+			return
+		}
+	}
+	signalGroup(pgid, syscall.SIGKILL)
+}
+
+// signalGroup sends sig to process group pgid. A group that no longer exists
+// reports os.ErrProcessDone, which exec.Cmd treats as a no-op cancellation.
+func signalGroup(pgid int, sig syscall.Signal) error {
+	err := syscall.Kill(-pgid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// limitedCommand wraps name and args in a /bin/sh that applies limits with
+// ulimit before exec'ing the command, or returns them unchanged when no limit
+// is set. A limit the shell can't apply (e.g. above the hard limit) fails the
+// action rather than running it unconfined.
+func limitedCommand(limits config.LimitsConfig, name string, args []string) (string, []string) {
+	var steps []string
+	if limits.CPUSeconds > 0 {
+		steps = append(steps, fmt.Sprintf("ulimit -t %d", limits.CPUSeconds))
+	}
+	if limits.MemoryMB > 0 {
+		steps = append(steps, fmt.Sprintf("ulimit -v %d", limits.MemoryMB*1024))
+	}
+	if limits.OpenFiles > 0 {
+		steps = append(steps, fmt.Sprintf("ulimit -n %d", limits.OpenFiles))
+	}
+	if len(steps) == 0 {
+		return name, args
+	}
+	script := strings.Join(append(steps, `exec "$0" "$@"`), " && ")
+	return "/bin/sh", append([]string{"-c", script, name}, args...)
+}
@@ -0,0 +1,132 @@
+//go:build unix
+
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+// shortenTerminateGrace keeps the SIGTERM-to-SIGKILL wait short in tests.
+func shortenTerminateGrace(t *testing.T) {
+	t.Helper()
+	previous := terminateGrace
+	terminateGrace = 200 * time.Millisecond
+	t.Cleanup(func() { terminateGrace = previous })
+}
+
+// grandchildPID returns the PID the fake cbai wrote to pidFile.
+func grandchildPID(t *testing.T, pidFile string) int {
+	t.Helper()
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("fake cbai didn't record its grandchild: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("bad grandchild pid %q", data)
+	}
+	return pid
+}
+
+// processGone reports whether pid exits within a second: it no longer
+// exists, or it is a zombie waiting on a parent that doesn't reap. SIGKILL is
+// delivered asynchronously, so the check polls.
+func processGone(pid int) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+		if err != nil {
+			return true
+		}
+		fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+		if len(fields) > 0 && (fields[0] == "Z" || fields[0] == "X") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRunExecuteWithOptions_EndsGrandchildren(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("inspects /proc")
+	}
+	shortenTerminateGrace(t)
+
+	tests := []struct {
+		name    string
+		script  string
+		timeout time.Duration
+		wantErr bool
+	}{
+		{
+			name:    "timeout",
+			script:  "sleep 30 &\necho $! > \"$CBAI_TEST_PIDFILE\"\nwait\n",
+			timeout: 200 * time.Millisecond,
+			wantErr: true,
+		},
+		{
+			name:    "timeout with SIGTERM ignored",
+			script:  "trap '' TERM\nsleep 30 &\necho $! > \"$CBAI_TEST_PIDFILE\"\nwait\n",
+			timeout: 200 * time.Millisecond,
+			wantErr: true,
+		},
+		{
+			name:   "leader exits while a background grandchild holds stdout",
+			script: "sleep 30 &\necho $! > \"$CBAI_TEST_PIDFILE\"\nprintf done\n",
+		},
+	}
+
+	for _, tt := range tests {
+		pidFile := filepath.Join(t.TempDir(), "grandchild.pid")
+		t.Setenv("CBAI_TEST_PIDFILE", pidFile)
+		useFakeCbai(t, tt.script)
+
+		start := time.Now()
+		result := runExecuteWithOptions(context.Background(), "summary", "input", Options{Timeout: tt.timeout})
+		if (result.Error != nil) != tt.wantErr {
+			t.Fatalf("%s: unexpected error state: %v", tt.name, result.Error)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Fatalf("%s: took %s; the grandchild kept the action waiting", tt.name, elapsed)
+		}
+		if pid := grandchildPID(t, pidFile); !processGone(pid) {
+			t.Errorf("%s: grandchild %d outlived the action", tt.name, pid)
+		}
+	}
+}
+
+func TestRunExecuteWithOptions_AppliesLimits(t *testing.T) {
+	useFakeCbai(t, `printf '%s|%s|%s' "$(ulimit -t)" "$(ulimit -n)" "$*"
+`)
+
+	result := runExecuteWithOptions(context.Background(), "summary", "input", Options{
+		Args:   []string{"it's"},
+		Limits: config.LimitsConfig{CPUSeconds: 5, OpenFiles: 64},
+	})
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v (%s)", result.Error, result.Diagnostics)
+	}
+	if want := "5|64|run summary -- it's"; result.Output != want {
+		t.Fatalf("expected %q, got %q", want, result.Output)
+	}
+}
+
+func TestLimitedCommand(t *testing.T) {
+	name, args := limitedCommand(config.LimitsConfig{}, "cbai", []string{"run", "x"})
+	if name != "cbai" || strings.Join(args, " ") != "run x" {
+		t.Fatalf("expected no wrapper without limits, got %s %q", name, args)
+	}
+
+	name, args = limitedCommand(config.LimitsConfig{MemoryMB: 512}, "cbai", []string{"run", "x"})
+	if name != "/bin/sh" || len(args) != 5 || args[1] != `ulimit -v 524288 && exec "$0" "$@"` || args[2] != "cbai" {
+		t.Fatalf("unexpected wrapper: %s %q", name, args)
+	}
+}
@@ -0,0 +1,220 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/history"
+	"github.com/clipboard-ai/agent/internal/provider"
+)
+
+// maxPromptInputChars mirrors the CLI's MAX_INPUT_CHARS: oversized input is
+// cut before it reaches the model, with a visible marker.
+const maxPromptInputChars = 100_000
+
+// pluginDir is where the CLI loads JS plugin actions from; a plugin file named
+// after an action shadows its prompt template.
+var pluginDir = filepath.Join(config.GetDataDir(), "actions")
+
+// historyPath is where in-process prompt runs are recorded.
+var historyPath = history.Path
+
+// PromptConfig describes an in-process run of a config-defined prompt
+// template action.
+type PromptConfig struct {
+	Template  string
+	Provider  config.ProviderConfig
+	MaxTokens int
+	// Settings supplies safe mode, the sensitive guard mode and history
+	// retention.
+	Settings config.SettingsConfig
+}
+
+// PromptFor returns the in-process prompt config for action, or nil when the
+// action must run through cbai: settings.native_prompts is off, it has no
+// prompt template, or the CLI would resolve its name to a builtin or to a
+// plugin file (<name>.js/.mjs/.cjs) first.
+func PromptFor(cfg *config.Config, action string) *PromptConfig {
+	if cfg == nil || !cfg.Settings.NativePrompts {
+		return nil
+	}
+	actionCfg, ok := cfg.Actions[action]
+	if !ok || strings.TrimSpace(actionCfg.Prompt) == "" || IsBuiltinAction(action) {
+		return nil
+	}
+	for _, ext := range []string{".js", ".mjs", ".cjs"} {
+		if _, err := os.Stat(filepath.Join(pluginDir, action+ext)); err == nil {
+			return nil
+		}
+	}
+
+	maxTokens := actionCfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = cfg.Settings.MaxTokens
+	}
+	return &PromptConfig{
+		Template:  strings.TrimSpace(actionCfg.Prompt),
+		Provider:  cfg.Provider,
+		MaxTokens: maxTokens,
+		Settings:  cfg.Settings,
+	}
+}
+
+// runPrompt runs a prompt template action against the provider directly,
+// applying the same safe-mode, sensitive-guard and history behavior as a
+// daemon-mode `cbai run`.
+func runPrompt(ctx context.Context, action string, text string, opts Options) Result {
+	start := time.Now()
+	prompt := opts.Prompt
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	providerCfg := prompt.Provider
+	if opts.ModelOverride != "" {
+		providerCfg.Model = opts.ModelOverride
+	}
+	if opts.EndpointOverride != "" {
+		providerCfg.Endpoint = opts.EndpointOverride
+	}
+
+	guardHit := opts.SensitiveGuardHit
+	var code ErrorCode
+	var providerElapsed time.Duration
+	response, err := func() (provider.Response, error) {
+		if text == "" {
+			return provider.Response{}, fmt.Errorf("action %q needs text input, got %q content", action, opts.InputType)
+		}
+		var blocked bool
+		if guardHit, blocked = scanGuard(prompt.Settings.SensitiveGuard, text, opts); blocked {
+			code = CodeGuardBlocked
+			return provider.Response{}, errGuardBlocked
+		}
+		if prompt.Settings.SafeMode && provider.IsCloud(providerCfg.Type, providerCfg.Endpoint) {
+			name := providerCfg.Type
+			if name == "" {
+				name = providerCfg.Endpoint
+			}
+			code = CodeSafeModeBlocked
+			return provider.Response{}, fmt.Errorf("safe mode: blocked cloud call to %s (daemon auto-triggered)", name)
+		}
+
+		client := provider.NewClient(provider.Config{
+			Type:      providerCfg.Type,
+			Endpoint:  providerCfg.Endpoint,
+			Model:     providerCfg.Model,
+			APIKey:    providerCfg.APIKey,
+			MaxTokens: prompt.MaxTokens,
+		}, nil)
+		providerStart := time.Now()
+		defer func() { providerElapsed = time.Since(providerStart) }()
+		return client.Complete(ctx, provider.Request{
+			Prompt:  renderPrompt(prompt.Template, capInput(text), opts.Args),
+			OnToken: opts.OnToken,
+		})
+	}()
+	elapsed := time.Since(start)
+
+	var warnings []string
+	if err == nil && response.Truncated {
+		slog.Warn("prompt action output truncated at max_tokens",
+			"action", action,
+			"max_tokens", prompt.MaxTokens,
+		)
+		warnings = append(warnings, fmt.Sprintf("output was truncated at max_tokens=%d", prompt.MaxTokens))
+	}
+	var statusErr *provider.StatusError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		code = CodeTimeout
+	case errors.Is(err, provider.ErrUnreachable):
+		code = CodeProviderUnreachable
+	case errors.As(err, &statusErr):
+		code = httpStatusCode(statusErr.StatusCode)
+	}
+
+	model := response.Model
+	if model == "" {
+		model = providerCfg.Model
+	}
+	result := Result{
+		Action:   action,
+		Output:   response.Content,
+		Error:    err,
+		Elapsed:  elapsed,
+		Code:     code,
+		Provider: providerCfg.Type,
+		Model:    model,
+		Usage:    Usage{PromptTokens: response.PromptTokens, CompletionTokens: response.CompletionTokens},
+		Latency:  Latency{Provider: providerElapsed, Process: elapsed},
+		Warnings: warnings,
+	}
+	applySuperseded(ctx, &result)
+	if result.Code != CodeSuperseded && !opts.NoHistory {
+		recordPromptRun(action, text, opts, providerCfg, prompt.Settings, guardHit, response, err, elapsed)
+	}
+	return result
+}
+
+// recordPromptRun appends the run to history in the CLI's record format. A
+// write failure is logged, never surfaced as an action error.
+func recordPromptRun(action string, text string, opts Options, providerCfg config.ProviderConfig, settings config.SettingsConfig, guardHit bool, response provider.Response, runErr error, elapsed time.Duration) {
+	trigger := opts.Trigger
+	if trigger == "" {
+		trigger = "daemon"
+	}
+	record := history.Record{
+		Action:           action,
+		Args:             opts.Args,
+		Source:           "daemon",
+		Trigger:          trigger,
+		Provider:         providerCfg.Type,
+		Model:            providerCfg.Model,
+		LatencyMs:        int(elapsed.Milliseconds()),
+		Status:           "success",
+		Input:            text,
+		Output:           response.Content,
+		PromptTokens:     response.PromptTokens,
+		CompletionTokens: response.CompletionTokens,
+	}
+	if runErr != nil {
+		record.Status = "error"
+		record.Error = runErr.Error()
+	}
+	if guardHit {
+		record.Input = "[sensitive content omitted]"
+		record.Output = ""
+	}
+	if err := history.Append(historyPath(), record, settings); err != nil {
+		slog.Warn("failed to write history", "action", action, "error", err)
+	}
+}
+
+// renderPrompt mirrors the CLI's renderPromptTemplate: {{input}} and {{args}}
+// are interpolated, and a template using neither gets the input appended.
+func renderPrompt(template string, text string, args []string) string {
+	if strings.Contains(template, "{{input}}") || strings.Contains(template, "{{args}}") {
+		return strings.NewReplacer("{{input}}", text, "{{args}}", strings.Join(args, " ")).Replace(template)
+	}
+	return template + "\n\n" + text
+}
+
+// capInput truncates text to maxPromptInputChars runes.
+func capInput(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxPromptInputChars {
+		return text
+	}
+	return string(runes[:maxPromptInputChars]) + "\n…[truncated]"
+}
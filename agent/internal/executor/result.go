@@ -0,0 +1,116 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// resultEnvelopeVersion is the newest result envelope version understood;
+// cbai writes one when CBAI_RESULT_FORMAT=json (see cli/src/lib/result.ts).
+const resultEnvelopeVersion = 1
+
+// ErrorCode classifies why an action failed, so callers can react without
+// matching on error text.
+type ErrorCode string
+
+const (
+	CodeSafeModeBlocked     ErrorCode = "safe_mode_blocked"
+	CodeProviderUnreachable ErrorCode = "provider_unreachable"
+	CodeGuardBlocked        ErrorCode = "guard_blocked"
+	CodeTimeout             ErrorCode = "timeout"
+	CodeSuperseded          ErrorCode = "superseded"
+	CodeRateLimited         ErrorCode = "rate_limited"      // HTTP 429 from the provider or webhook
+	CodeServerError         ErrorCode = "server_error"      // HTTP 5xx from the provider or webhook
+	CodeTemporaryFailure    ErrorCode = "temporary_failure" // a command exited with EX_TEMPFAIL (75)
+)
+
+// Usage is the token usage an action reported.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Latency breaks down where an action's time went; Result.Elapsed is the
+// total. Zero fields weren't reported.
+type Latency struct {
+	Provider time.Duration // waiting on the model
+	Process  time.Duration // cbai's own runtime, including Provider
+}
+
+// resultEnvelope is the JSON document cbai prints as the last line of stdout
+// in result-envelope mode.
+type resultEnvelope struct {
+	Version  int    `json:"version"`
+	OK       bool   `json:"ok"`
+	Output   string `json:"output"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Usage    *Usage `json:"usage"`
+	Latency  struct {
+		ProviderMs int64 `json:"provider_ms"`
+		TotalMs    int64 `json:"total_ms"`
+	} `json:"latency"`
+	Error *struct {
+		Code    ErrorCode `json:"code"`
+		Message string    `json:"message"`
+	} `json:"error"`
+	Warnings []string `json:"warnings"`
+}
+
+// parseResultEnvelope reads the envelope from the last non-empty line of
+// stdout. It reports false for output from a cbai that predates the protocol
+// (or a newer envelope version), which callers treat as plain text.
+func parseResultEnvelope(stdout []byte) (resultEnvelope, bool) {
+	trimmed := bytes.TrimRight(stdout, " \t\r\n")
+	line := trimmed[bytes.LastIndexByte(trimmed, '\n')+1:]
+	var envelope resultEnvelope
+	if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &envelope) != nil {
+		return resultEnvelope{}, false
+	}
+	if envelope.Version < 1 || envelope.Version > resultEnvelopeVersion {
+		return resultEnvelope{}, false
+	}
+	return envelope, true
+}
+
+// applyEnvelope fills result's typed fields from envelope. The envelope's
+// error message replaces the bare exit status error.
+func applyEnvelope(result *Result, envelope resultEnvelope) {
+	result.Output = envelope.Output
+	result.Provider = envelope.Provider
+	result.Model = envelope.Model
+	if envelope.Usage != nil {
+		result.Usage = *envelope.Usage
+	}
+	result.Latency = Latency{
+		Provider: time.Duration(envelope.Latency.ProviderMs) * time.Millisecond,
+		Process:  time.Duration(envelope.Latency.TotalMs) * time.Millisecond,
+	}
+	result.Warnings = envelope.Warnings
+	if envelope.Error != nil {
+		result.Code = envelope.Error.Code
+		if envelope.Error.Message != "" {
+			result.Error = actionError(envelope.Error.Message)
+		}
+	}
+}
+
+// legacyErrorCode classifies the plain-text error output of a cbai that
+// predates the result envelope.
+func legacyErrorCode(output string) ErrorCode {
+	switch {
+	case strings.Contains(output, "safe mode: blocked"):
+		return CodeSafeModeBlocked
+	case strings.Contains(output, "looks like it contains a secret"):
+		return CodeGuardBlocked
+	default:
+		return ""
+	}
+}
+
+// actionError is a failure message reported by the action itself.
+type actionError string
+
+func (e actionError) Error() string { return string(e) }
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseResultEnvelope(t *testing.T) {
+	tests := []struct {
+		name   string
+		stdout string
+		ok     bool
+		output string
+	}{
+		{name: "envelope only", stdout: `{"version":1,"ok":true,"output":"hi"}` + "\n", ok: true, output: "hi"},
+		{name: "plugin logging before the envelope", stdout: "debug line\n{\"version\":1,\"ok\":true,\"output\":\"hi\"}\n\n", ok: true, output: "hi"},
+		{name: "legacy text output", stdout: "Summary:\n───────\nhi\n"},
+		{name: "legacy JSON action output", stdout: `{"type":"code"}`},
+		{name: "newer envelope version", stdout: `{"version":2,"ok":true,"output":"hi"}`},
+		{name: "empty", stdout: ""},
+	}
+
+	for _, tt := range tests {
+		envelope, ok := parseResultEnvelope([]byte(tt.stdout))
+		if ok != tt.ok || envelope.Output != tt.output {
+			t.Errorf("%s: got ok=%v output=%q, want ok=%v output=%q", tt.name, ok, envelope.Output, tt.ok, tt.output)
+		}
+	}
+}
+
+func TestLegacyErrorCode(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   ErrorCode
+	}{
+		{stderr: "Error: safe mode: blocked cloud call to openai (daemon auto-triggered)", want: CodeSafeModeBlocked},
+		{stderr: "Error: clipboard looks like it contains a secret — action skipped.", want: CodeGuardBlocked},
+		{stderr: "Error: Clipboard is empty", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := legacyErrorCode(tt.stderr); got != tt.want {
+			t.Errorf("legacyErrorCode(%q) = %q, want %q", tt.stderr, got, tt.want)
+		}
+	}
+}
+
+// useFakeCbai puts a cbai shell script with the given body first on PATH.
+func useFakeCbai(t *testing.T, body string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cbai"), []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatalf("failed to write fake cbai: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRunExecuteWithOptions_ParsesResultEnvelope(t *testing.T) {
+	useFakeCbai(t, `[ "$CBAI_RESULT_FORMAT" = json ] || exit 3
+echo '{"version":1,"ok":true,"output":"a summary","provider":"ollama","model":"mistral","usage":{"prompt_tokens":12,"completion_tokens":3},"latency":{"provider_ms":40,"total_ms":90},"warnings":["output was truncated at max_tokens=80"]}'
+`)
+
+	result := runExecuteWithOptions(context.Background(), "summary", "input", Options{})
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	if result.Output != "a summary" || result.Provider != "ollama" || result.Model != "mistral" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Usage != (Usage{PromptTokens: 12, CompletionTokens: 3}) {
+		t.Fatalf("unexpected usage: %+v", result.Usage)
+	}
+	if result.Latency != (Latency{Provider: 40 * time.Millisecond, Process: 90 * time.Millisecond}) {
+		t.Fatalf("unexpected latency: %+v", result.Latency)
+	}
+	if len(result.Warnings) != 1 {
+		t.Fatalf("expected one warning, got %q", result.Warnings)
+	}
+}
+
+func TestRunExecuteWithOptions_EnvelopeErrors(t *testing.T) {
+	useFakeCbai(t, `echo 'Error: safe mode: blocked cloud call to openai (daemon auto-triggered)' >&2
+echo '{"version":1,"ok":false,"output":"","error":{"code":"safe_mode_blocked","message":"safe mode: blocked cloud call to openai (daemon auto-triggered)"}}'
+exit 1
+`)
+
+	result := runExecuteWithOptions(context.Background(), "summary", "input", Options{})
+	if result.Code != CodeSafeModeBlocked {
+		t.Fatalf("expected code %q, got %q", CodeSafeModeBlocked, result.Code)
+	}
+	if result.Error == nil || result.Error.Error() != "safe mode: blocked cloud call to openai (daemon auto-triggered)" {
+		t.Fatalf("expected the envelope's error message, got %v", result.Error)
+	}
+}
+
+func TestRunExecuteWithOptions_LegacyOutputFallback(t *testing.T) {
+	useFakeCbai(t, `echo 'Error: safe mode: blocked cloud call to openai (daemon auto-triggered)' >&2
+exit 1
+`)
+
+	result := runExecuteWithOptions(context.Background(), "summary", "input", Options{})
+	if result.Error == nil || result.Code != CodeSafeModeBlocked {
+		t.Fatalf("expected a classified legacy failure, got code=%q err=%v", result.Code, result.Error)
+	}
+	if result.Output != "" || !strings.Contains(result.Diagnostics, "safe mode") {
+		t.Fatalf("expected stderr in diagnostics only, got output=%q diagnostics=%q", result.Output, result.Diagnostics)
+	}
+}
+
+func TestRunExecuteWithOptions_Timeout(t *testing.T) {
+	useFakeCbai(t, "exec sleep 5\n")
+
+	result := runExecuteWithOptions(context.Background(), "summary", "input", Options{Timeout: 100 * time.Millisecond})
+	if result.Code != CodeTimeout || result.Error == nil {
+		t.Fatalf("expected a timeout, got code=%q err=%v", result.Code, result.Error)
+	}
+}
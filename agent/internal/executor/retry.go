@@ -0,0 +1,144 @@
+package executor
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+// jitterFactor returns a random number in [0, 1) for retry jitter; tests
+// replace it.
+var jitterFactor = rand.Float64
+
+// RetryPolicy is how a run that fails transiently is retried: up to Attempts
+// runs in all, waiting Backoff before the first retry and Multiplier times as
+// long before each one after, capped at MaxBackoff, with each wait varied by
+// up to ±Jitter of itself.
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	Multiplier float64
+	MaxBackoff time.Duration
+	Jitter     float64
+}
+
+// RetryPolicyFor returns the retry policy an action configures with
+// retry_count, retry_backoff_ms, retry_backoff_multiplier,
+// retry_backoff_max_ms and retry_jitter.
+func RetryPolicyFor(actionCfg config.ActionConfig) RetryPolicy {
+	return RetryPolicy{
+		Attempts:   actionCfg.RetryCount + 1,
+		Backoff:    time.Duration(actionCfg.RetryBackoffMs) * time.Millisecond,
+		Multiplier: actionCfg.RetryBackoffMultiplier,
+		MaxBackoff: time.Duration(actionCfg.RetryBackoffMaxMs) * time.Millisecond,
+		Jitter:     actionCfg.RetryJitter,
+	}
+}
+
+// Delay returns the wait before the given retry, counting from 1.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := float64(p.Backoff)
+	for i := 1; i < retry && p.Multiplier > 1; i++ {
+		delay *= p.Multiplier
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	// Cap before and after jitter, so waits that reached the cap still
+	// spread out below it.
+	if p.MaxBackoff > 0 {
+		delay = min(delay, float64(p.MaxBackoff))
+	}
+	if p.Jitter > 0 {
+		delay *= 1 + p.Jitter*(2*jitterFactor()-1)
+	}
+	if p.MaxBackoff > 0 {
+		delay = min(delay, float64(p.MaxBackoff))
+	}
+	return time.Duration(delay)
+}
+
+// Retryable reports whether a failed run may succeed if retried: it timed
+// out, couldn't reach its provider or webhook, was rate limited, got a server
+// error, or was a command reporting a temporary failure. Anything else, like
+// a safe mode block or a guard rejection, would only fail again.
+func Retryable(result Result) bool {
+	if result.Error == nil {
+		return false
+	}
+	switch result.Code {
+	case CodeTimeout, CodeProviderUnreachable, CodeRateLimited, CodeServerError, CodeTemporaryFailure:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExecuteWithRetry runs action like ExecuteWithOptions, retrying retryable
+// failures under policy, and returns the last result and the number of runs.
+// acquire, when set, is called before each run for a scheduler slot, which is
+// released as soon as the run ends so that no slot is held through a backoff
+// wait; if it fails before the first run, ExecuteWithRetry returns its error
+// after 0 runs. onRetry, when set, is called with each failed result and the
+// wait before the next run.
+func ExecuteWithRetry(ctx context.Context, action string, text string, opts Options, policy RetryPolicy, acquire func() (func(), error), onRetry func(attempt int, result Result, delay time.Duration)) (Result, int) {
+	return retry(ctx, policy, acquire, onRetry, func() Result {
+		return ExecuteWithOptions(ctx, action, text, opts)
+	})
+}
+
+// retry calls run until it succeeds, fails for good, or has run
+// policy.Attempts times, holding a slot from acquire for each run. A wait cut
+// short by ctx, or a slot that can't be had, ends the retries with the last
+// run's result.
+func retry(ctx context.Context, policy RetryPolicy, acquire func() (func(), error), onRetry func(attempt int, result Result, delay time.Duration), run func() Result) (Result, int) {
+	attempts := max(policy.Attempts, 1)
+	var result Result
+	for attempt := 1; ; attempt++ {
+		release := func() {}
+		if acquire != nil {
+			var err error
+			if release, err = acquire(); err != nil {
+				if attempt == 1 {
+					return Result{Error: err}, 0
+				}
+				applySuperseded(ctx, &result)
+				return result, attempt - 1
+			}
+		}
+		result = run()
+		release()
+		if attempt == attempts || !Retryable(result) || ctx.Err() != nil {
+			return result, attempt
+		}
+		delay := policy.Delay(attempt)
+		if onRetry != nil {
+			onRetry(attempt, result, delay)
+		}
+		if delay <= 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			applySuperseded(ctx, &result)
+			return result, attempt
+		case <-time.After(delay):
+		}
+	}
+}
+
+// httpStatusCode classifies an HTTP failure status as rate limiting or a
+// server error, both worth retrying, or neither.
+func httpStatusCode(status int) ErrorCode {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return CodeRateLimited
+	case status >= 500:
+		return CodeServerError
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,188 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	factor := 0.5
+	orig := jitterFactor
+	jitterFactor = func() float64 { return factor }
+	t.Cleanup(func() { jitterFactor = orig })
+
+	policy := RetryPolicyFor(config.ActionConfig{
+		RetryCount:             5,
+		RetryBackoffMs:         100,
+		RetryBackoffMultiplier: 2,
+		RetryBackoffMaxMs:      500,
+	})
+	tests := []struct {
+		retry  int
+		jitter float64
+		factor float64
+		want   time.Duration
+	}{
+		{retry: 1, want: 100 * time.Millisecond},
+		{retry: 2, want: 200 * time.Millisecond},
+		{retry: 3, want: 400 * time.Millisecond},
+		{retry: 4, want: 500 * time.Millisecond},
+		{retry: 10, want: 500 * time.Millisecond},
+		{retry: 2, jitter: 0.5, factor: 0, want: 100 * time.Millisecond},
+		{retry: 2, jitter: 0.5, factor: 1, want: 300 * time.Millisecond},
+		{retry: 4, jitter: 0.5, factor: 0, want: 250 * time.Millisecond},
+		{retry: 4, jitter: 0.5, factor: 1, want: 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		policy.Jitter, factor = tt.jitter, tt.factor
+		if got := policy.Delay(tt.retry); got != tt.want {
+			t.Errorf("retry %d jitter %g factor %g: expected %s, got %s", tt.retry, tt.jitter, tt.factor, tt.want, got)
+		}
+	}
+
+	fixed := RetryPolicyFor(config.ActionConfig{RetryBackoffMs: 250})
+	if got := fixed.Delay(3); got != 250*time.Millisecond {
+		t.Fatalf("expected a fixed backoff without a multiplier, got %s", got)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		result Result
+		want   bool
+	}{
+		{result: Result{}, want: false},
+		{result: Result{Error: failed, Code: CodeTimeout}, want: true},
+		{result: Result{Error: failed, Code: CodeProviderUnreachable}, want: true},
+		{result: Result{Error: failed, Code: CodeRateLimited}, want: true},
+		{result: Result{Error: failed, Code: CodeServerError}, want: true},
+		{result: Result{Error: failed, Code: CodeTemporaryFailure}, want: true},
+		{result: Result{Error: failed, Code: CodeSafeModeBlocked}, want: false},
+		{result: Result{Error: failed, Code: CodeSuperseded}, want: false},
+		{result: Result{Error: failed}, want: false},
+	}
+	for _, tt := range tests {
+		if got := Retryable(tt.result); got != tt.want {
+			t.Errorf("code %q: expected retryable %v, got %v", tt.result.Code, tt.want, got)
+		}
+	}
+}
+
+func TestHTTPStatusCode(t *testing.T) {
+	tests := []struct {
+		status int
+		want   ErrorCode
+	}{
+		{status: 400, want: ""},
+		{status: 401, want: ""},
+		{status: 429, want: CodeRateLimited},
+		{status: 500, want: CodeServerError},
+		{status: 503, want: CodeServerError},
+	}
+	for _, tt := range tests {
+		if got := httpStatusCode(tt.status); got != tt.want {
+			t.Errorf("status %d: expected %q, got %q", tt.status, tt.want, got)
+		}
+	}
+}
+
+func TestExecuteWithRetry(t *testing.T) {
+	usePromptFixtures(t)
+	marker := t.TempDir() + "/ran"
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+	tests := []struct {
+		name         string
+		script       string
+		wantErr      bool
+		wantAttempts int
+		wantRetries  int
+	}{
+		{name: "succeeds", script: `echo ok`, wantAttempts: 1},
+		{name: "transient then succeeds", script: `[ -e "$1" ] && echo ok || { touch "$1"; exit 75; }`, wantAttempts: 2, wantRetries: 1},
+		{name: "always transient", script: `exit 75`, wantErr: true, wantAttempts: 3, wantRetries: 2},
+		{name: "permanent", script: `exit 1`, wantErr: true, wantAttempts: 1},
+	}
+	for i, tt := range tests {
+		retries := 0
+		onRetry := func(attempt int, result Result, delay time.Duration) {
+			retries++
+			if result.Code != CodeTemporaryFailure || delay != time.Millisecond {
+				t.Errorf("%s: unexpected retry of code %q after %s", tt.name, result.Code, delay)
+			}
+		}
+		opts := commandOptions("/bin/sh", "-c", tt.script, "sh", marker+string(rune('a'+i)))
+		result, attempts := ExecuteWithRetry(context.Background(), "flaky", "input", opts, policy, nil, onRetry)
+		if (result.Error != nil) != tt.wantErr || attempts != tt.wantAttempts || retries != tt.wantRetries {
+			t.Errorf("%s: expected error %v after %d attempts and %d retries, got %v after %d and %d",
+				tt.name, tt.wantErr, tt.wantAttempts, tt.wantRetries, result.Error, attempts, retries)
+		}
+	}
+}
+
+func TestExecuteWithRetry_SupersededWhileWaiting(t *testing.T) {
+	usePromptFixtures(t)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Minute}
+	onRetry := func(int, Result, time.Duration) { cancel(ErrSuperseded) }
+
+	start := time.Now()
+	result, attempts := ExecuteWithRetry(ctx, "flaky", "input", commandOptions("/bin/sh", "-c", "exit 75"), policy, nil, onRetry)
+	if result.Code != CodeSuperseded || attempts != 1 {
+		t.Fatalf("expected a superseded run after 1 attempt, got code %q after %d", result.Code, attempts)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("superseding didn't cut the backoff short")
+	}
+}
+
+func TestExecuteWithRetry_ReleasesSlotBetweenAttempts(t *testing.T) {
+	usePromptFixtures(t)
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+	opts := commandOptions("/bin/sh", "-c", "exit 75")
+	errFull := errors.New("queue full")
+
+	tests := []struct {
+		name         string
+		slots        int // slots acquire grants before failing
+		wantAttempts int
+		wantErr      error
+	}{
+		{name: "slot for every attempt", slots: 3, wantAttempts: 3},
+		{name: "no slot for a retry", slots: 1, wantAttempts: 1},
+		{name: "no slot at all", slots: 0, wantAttempts: 0, wantErr: errFull},
+	}
+	for _, tt := range tests {
+		held, granted := false, 0
+		acquire := func() (func(), error) {
+			if held {
+				t.Fatalf("%s: slot acquired while one is held", tt.name)
+			}
+			if granted == tt.slots {
+				return nil, errFull
+			}
+			held, granted = true, granted+1
+			return func() { held = false }, nil
+		}
+		onRetry := func(int, Result, time.Duration) {
+			if held {
+				t.Errorf("%s: slot held through the backoff wait", tt.name)
+			}
+		}
+		result, attempts := ExecuteWithRetry(context.Background(), "flaky", "input", opts, policy, acquire, onRetry)
+		if attempts != tt.wantAttempts || held {
+			t.Errorf("%s: expected %d attempts and the slot released, got %d (held %v)", tt.name, tt.wantAttempts, attempts, held)
+		}
+		if tt.wantErr != nil && !errors.Is(result.Error, tt.wantErr) {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, result.Error)
+		}
+		if tt.wantErr == nil && result.Code != CodeTemporaryFailure {
+			t.Errorf("%s: expected the last run's failure, got code %q", tt.name, result.Code)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package executor
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/history"
+	"github.com/clipboard-ai/agent/internal/sandbox"
+)
+
+// systemPaths are the read-only system locations cbai and its JavaScript
+// runtime need under the filesystem sandbox. Missing ones are skipped.
+var systemPaths = []string{"/usr", "/lib", "/lib64", "/lib32", "/bin", "/sbin", "/etc", "/opt", "/nix", "/proc", "/dev"}
+
+// sandboxPolicy returns the confinement requested by opts. The filesystem
+// policy allows reading the system, the installs of programs (cbai and its
+// runtime, or a command), and the agent's data dir (config and plugins), and
+// writing only the temp dir that holds image inputs and cbai's history file
+// (see runHistory).
+func sandboxPolicy(opts Options, programs ...string) sandbox.Policy {
+	policy := sandbox.Policy{Filesystem: opts.Sandbox, NoNetwork: opts.NoNetwork}
+	if !opts.Sandbox {
+		return policy
+	}
+	policy.ReadOnly = append(policy.ReadOnly, systemPaths...)
+	for _, program := range programs {
+		if prefix, ok := installPrefix(program); ok {
+			policy.ReadOnly = append(policy.ReadOnly, prefix)
+		}
+	}
+	policy.ReadOnly = append(policy.ReadOnly, config.GetDataDir())
+	policy.ReadWrite = []string{os.TempDir(), "/dev/null"}
+	return policy
+}
+
+// runHistory returns a history file in the temp dir for a cbai run that
+// mustn't write the shared one: a sandboxed run can't, in the read-only data
+// dir, and a NoHistory run shouldn't. Its cleanup func moves the records a
+// sandboxed run wrote there to the shared history file, and discards a
+// NoHistory run's. It returns "" when the temp file can't be set up.
+func runHistory(action string, opts Options) (string, func()) {
+	dir, err := os.MkdirTemp("", "cbai-history-")
+	if err != nil {
+		slog.Warn("failed to set up action history", "action", action, "error", err)
+		return "", func() {}
+	}
+	historyFile := filepath.Join(dir, "history.jsonl")
+	return historyFile, func() {
+		defer os.RemoveAll(dir)
+		if opts.NoHistory {
+			return
+		}
+		if err := history.Import(historyPath(), historyFile, opts.HistorySettings); err != nil {
+			slog.Warn("failed to record sandboxed action history", "action", action, "error", err)
+		}
+	}
+}
+
+// installPrefix returns the directory a program on PATH is installed under:
+// the parent of the bin/ directory holding its resolved executable, which
+// covers bundled modules in lib/ alongside it. ~/bin stays as is, so the home
+// directory isn't opened up.
+func installPrefix(program string) (string, bool) {
+	path, err := exec.LookPath(program)
+	if err != nil {
+		return "", false
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	dir := filepath.Dir(path)
+	home, _ := os.UserHomeDir()
+	if filepath.Base(dir) == "bin" && filepath.Dir(dir) != home {
+		dir = filepath.Dir(dir)
+	}
+	return dir, true
+}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/sandbox"
+)
+
+// TestMain lets the test binary act as the sandbox helper, as the agent
+// binary does.
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == sandbox.HelperCommand {
+		os.Exit(sandbox.RunHelper(os.Args[2:], os.Stderr))
+	}
+	os.Exit(m.Run())
+}
+
+func TestSandboxPolicy(t *testing.T) {
+	useFakeCbai(t, "exit 0\n")
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	if policy := sandboxPolicy(Options{NoNetwork: true}, "cbai"); policy.Filesystem || !policy.NoNetwork || len(policy.ReadWrite) != 0 {
+		t.Fatalf("unexpected network-only policy: %+v", policy)
+	}
+
+	policy := sandboxPolicy(Options{Sandbox: true}, "cbai")
+	if !policy.Filesystem || policy.NoNetwork {
+		t.Fatalf("unexpected filesystem policy: %+v", policy)
+	}
+	if !slices.Equal(policy.ReadWrite, []string{tmp, "/dev/null"}) {
+		t.Fatalf("expected only the temp dir to be writable, got %v", policy.ReadWrite)
+	}
+	cbaiPrefix, ok := installPrefix("cbai")
+	if !ok || !slices.Contains(policy.ReadOnly, cbaiPrefix) {
+		t.Fatalf("expected the cbai install %q to be readable, got %v", cbaiPrefix, policy.ReadOnly)
+	}
+}
+
+func TestRunExecuteWithOptions_ReportsSandboxStatus(t *testing.T) {
+	base := t.TempDir()
+	tmp, outside := filepath.Join(base, "tmp"), filepath.Join(base, "outside")
+	for _, dir := range []string{tmp, outside} {
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("TMPDIR", tmp)
+	useFakeCbai(t, `echo ok > "$TMPDIR/image" || exit 3
+if { echo leaked > "$OUTSIDE/leak"; } 2>/dev/null; then echo escaped; else echo confined; fi
+`)
+
+	result := runExecuteWithOptions(context.Background(), "summary", "input", Options{
+		Sandbox: true,
+		Env:     EnvPolicy{Set: map[string]string{"OUTSIDE": outside}},
+	})
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v (stderr %q)", result.Error, result.Diagnostics)
+	}
+	if result.Sandbox == nil {
+		t.Fatal("expected a sandbox status")
+	}
+	if runtime.GOOS != "linux" || result.Sandbox.Filesystem == sandbox.StatusUnsupported {
+		if !result.Sandbox.Degraded() || result.Sandbox.Reason == "" {
+			t.Fatalf("expected a degraded status with a reason, got %+v", result.Sandbox)
+		}
+		t.Skipf("filesystem sandbox unavailable: %s", result.Sandbox.Reason)
+	}
+	if result.Sandbox.Filesystem != sandbox.StatusEnforced || result.Sandbox.Network != sandbox.StatusOff {
+		t.Fatalf("unexpected sandbox status: %+v", result.Sandbox)
+	}
+	if got := strings.TrimSpace(result.Output); got != "confined" {
+		t.Fatalf("expected the action to be confined, got %q", got)
+	}
+}
+
+func TestRunExecuteWithOptions_SandboxRecordsHistory(t *testing.T) {
+	// The shared history file lives outside the writable temp dir, as the
+	// data dir does.
+	historyFile := usePromptFixtures(t)
+	tmp := filepath.Join(t.TempDir(), "tmp")
+	if err := os.Mkdir(tmp, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TMPDIR", tmp)
+	useFakeCbai(t, `case "$CBAI_HISTORY_FILE" in "$TMPDIR"/*) ;; *) exit 3 ;; esac
+echo '{"id":"run-1","action":"summary","status":"success"}' >> "$CBAI_HISTORY_FILE" || exit 4
+echo ok
+`)
+
+	result := runExecuteWithOptions(context.Background(), "summary", "input", Options{Sandbox: true, HistorySettings: config.Default().Settings})
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v (stderr %q)", result.Error, result.Diagnostics)
+	}
+	data, err := os.ReadFile(historyFile)
+	if err != nil || !strings.Contains(string(data), `"id":"run-1"`) {
+		t.Fatalf("expected the sandboxed run's record in history, got %q (%v)", data, err)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(tmp, "cbai-history-*")); len(leftovers) != 0 {
+		t.Fatalf("expected the run's history dir to be removed, got %v", leftovers)
+	}
+}
+
+func TestRunExecuteWithOptions_NoSandboxStatusByDefault(t *testing.T) {
+	useFakeCbai(t, "echo ok\n")
+
+	result := runExecuteWithOptions(context.Background(), "summary", "input", Options{})
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	if result.Sandbox != nil {
+		t.Fatalf("expected no sandbox status, got %+v", result.Sandbox)
+	}
+}
@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/history"
+)
+
+// ErrSuperseded is the cancellation cause of a run that newer clipboard
+// content for the same action made stale (actions.<name>.supersede).
+var ErrSuperseded = errors.New("superseded by newer clipboard content")
+
+// applySuperseded reports a run whose context was cancelled with
+// ErrSuperseded as superseded rather than failed.
+func applySuperseded(ctx context.Context, result *Result) {
+	if result.Error != nil && errors.Is(context.Cause(ctx), ErrSuperseded) {
+		result.Error = ErrSuperseded
+		result.Code = CodeSuperseded
+	}
+}
+
+// RecordSuperseded appends a run superseded while queued or running to
+// history with status superseded. A superseded run doesn't record itself, so
+// whoever cancelled it records it once, whatever the action's kind.
+func RecordSuperseded(action string, text string, opts Options, settings config.SettingsConfig, elapsed time.Duration) {
+	trigger := opts.Trigger
+	if trigger == "" {
+		trigger = "daemon"
+	}
+	record := history.Record{
+		Action:    action,
+		Args:      opts.Args,
+		Source:    "daemon",
+		Trigger:   trigger,
+		LatencyMs: int(elapsed.Milliseconds()),
+		Status:    string(CodeSuperseded),
+		Input:     text,
+		Error:     ErrSuperseded.Error(),
+	}
+	if opts.SensitiveGuardHit {
+		record.Input = "[sensitive content omitted]"
+	}
+	if err := history.Append(historyPath(), record, settings); err != nil {
+		slog.Warn("failed to write history", "action", action, "error", err)
+	}
+}
@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/transform"
+)
+
+// transformKind is recorded in history as the provider of a transform run.
+const transformKind = "transform"
+
+// TransformConfig describes an in-process run of a built-in transform (see
+// package transform).
+type TransformConfig struct {
+	Fn transform.Func
+	// Settings supplies history retention.
+	Settings config.SettingsConfig
+}
+
+// TransformFor returns the transform config for action, or nil when action
+// isn't a transform name or the config defines it as a prompt, command or
+// webhook action, which takes precedence.
+func TransformFor(cfg *config.Config, action string) *TransformConfig {
+	fn, ok := transform.Lookup(action)
+	if !ok {
+		return nil
+	}
+	if cfg == nil {
+		return &TransformConfig{Fn: fn}
+	}
+	if actionCfg, ok := cfg.Actions[action]; ok {
+		if strings.TrimSpace(actionCfg.Prompt) != "" || (actionCfg.Kind != "" && actionCfg.Kind != config.ActionKindCbai) {
+			return nil
+		}
+	}
+	return &TransformConfig{Fn: fn, Settings: cfg.Settings}
+}
+
+// IsTransformAction reports whether name is a built-in transform.
+func IsTransformAction(name string) bool {
+	_, ok := transform.Lookup(name)
+	return ok
+}
+
+// runTransform applies opts.Transform to the clipboard text.
+func runTransform(ctx context.Context, action string, text string, opts Options) Result {
+	start := time.Now()
+	result := Result{Action: action}
+	if err := ctx.Err(); err != nil {
+		result.Error = err
+	} else if text == "" {
+		result.Error = errors.New("transform needs text input")
+	} else {
+		result.Output, result.Error = opts.Transform.Fn(text)
+	}
+	result.Elapsed = time.Since(start)
+	result.Latency.Process = result.Elapsed
+	applySuperseded(ctx, &result)
+	recordLocalRun(action, text, opts, transformKind, action, result, opts.Transform.Settings)
+	return result
+}
@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/history"
+)
+
+func TestTransformFor(t *testing.T) {
+	cfg := config.Default()
+	cfg.Actions["json-pretty"] = config.ActionConfig{Enabled: true, Trigger: "contains:{"}
+	cfg.Actions["uppercase"] = config.ActionConfig{Prompt: "Shout this: {{input}}"}
+	cfg.Actions["sha256"] = config.ActionConfig{Kind: config.ActionKindCommand, Command: []string{"sha256sum"}}
+
+	tests := []struct {
+		name   string
+		cfg    *config.Config
+		action string
+		want   bool
+	}{
+		{name: "configured transform", cfg: cfg, action: "json-pretty", want: true},
+		{name: "unconfigured transform", cfg: cfg, action: "base64-decode", want: true},
+		{name: "no config", cfg: nil, action: "md5", want: true},
+		{name: "prompt action takes precedence", cfg: cfg, action: "uppercase", want: false},
+		{name: "command action takes precedence", cfg: cfg, action: "sha256", want: false},
+		{name: "cbai action", cfg: cfg, action: "summarize", want: false},
+	}
+	for _, tt := range tests {
+		if got := TransformFor(tt.cfg, tt.action); (got != nil) != tt.want {
+			t.Errorf("%s: TransformFor(%q) = %+v, want transform: %v", tt.name, tt.action, got, tt.want)
+		}
+	}
+}
+
+func TestRunExecuteWithOptions_RunsTransform(t *testing.T) {
+	historyFile := usePromptFixtures(t)
+	cfg := config.Default()
+	cfg.Settings.HistoryEnabled = true
+	// No cbai on PATH: a transform runs in-process.
+	t.Setenv("PATH", t.TempDir())
+
+	tests := []struct {
+		name    string
+		action  string
+		text    string
+		want    string
+		wantErr string
+	}{
+		{name: "success", action: "json-minify", text: "{\n  \"a\": 1\n}", want: `{"a":1}`},
+		{name: "invalid input", action: "json-minify", text: "{", wantErr: "invalid JSON"},
+		{name: "no text", action: "sha256", text: "", wantErr: "needs text input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runExecuteWithOptions(context.Background(), tt.action, tt.text, Options{Transform: TransformFor(cfg, tt.action)})
+			if tt.wantErr != "" {
+				if result.Error == nil || !strings.Contains(result.Error.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, result.Error)
+				}
+				return
+			}
+			if result.Error != nil || result.Output != tt.want {
+				t.Fatalf("expected %q, got %q (%v)", tt.want, result.Output, result.Error)
+			}
+		})
+	}
+
+	data, err := os.ReadFile(historyFile)
+	if err != nil {
+		t.Fatalf("expected history records: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(tests) {
+		t.Fatalf("expected %d history records, got %d", len(tests), len(lines))
+	}
+	var record history.Record
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("corrupt history record: %v", err)
+	}
+	if record.Action != "json-minify" || record.Provider != transformKind || record.Status != "success" || record.Output != `{"a":1}` {
+		t.Fatalf("unexpected history record: %+v", record)
+	}
+}
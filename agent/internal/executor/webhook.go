@@ -0,0 +1,255 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+// defaultSignatureHeader carries a webhook body's HMAC-SHA256 signature when
+// the action doesn't name another header.
+const defaultSignatureHeader = "X-Clipboard-AI-Signature"
+
+// webhookPlaceholderRe matches the placeholders of a webhook body template.
+var webhookPlaceholderRe = regexp.MustCompile(`\{\{\s*(input|action|type|trigger|args|match\.([A-Za-z0-9_]+))\s*\}\}`)
+
+// webhookClient sends webhook requests. Redirects are not followed, so the
+// body and its signature only ever reach the configured URL.
+var webhookClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// WebhookConfig describes a run of a webhook action: an HTTP request to a
+// local service carrying the clipboard event, with no cbai or provider
+// involved.
+type WebhookConfig struct {
+	Request config.WebhookConfig
+	// Settings supplies the sensitive guard mode and history retention.
+	Settings config.SettingsConfig
+}
+
+// WebhookFor returns the webhook config for action, or nil when it isn't a
+// webhook action.
+func WebhookFor(cfg *config.Config, action string) *WebhookConfig {
+	if cfg == nil {
+		return nil
+	}
+	actionCfg, ok := cfg.Actions[action]
+	if !ok || actionCfg.Kind != config.ActionKindWebhook || actionCfg.Webhook.URL == "" {
+		return nil
+	}
+	return &WebhookConfig{Request: actionCfg.Webhook, Settings: cfg.Settings}
+}
+
+// webhookEvent is the JSON body sent when a webhook action has no body
+// template.
+type webhookEvent struct {
+	Action    string            `json:"action"`
+	Text      string            `json:"text"`
+	Type      string            `json:"type,omitempty"`
+	Trigger   string            `json:"trigger,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Match     map[string]string `json:"match,omitempty"`
+	Sensitive bool              `json:"sensitive,omitempty"`
+	Timestamp string            `json:"timestamp"`
+}
+
+// runWebhook sends opts.Webhook's request with the clipboard text, applying
+// the sensitive guard as a prompt run would. The response body is the
+// output; a non-2xx status is an error.
+func runWebhook(ctx context.Context, action string, text string, opts Options) Result {
+	start := time.Now()
+	webhook := opts.Webhook
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	guardHit := opts.SensitiveGuardHit
+	result := Result{Action: action}
+	result.Output, result.Error = func() (string, error) {
+		if text == "" {
+			return "", fmt.Errorf("action %q needs text input, got %q content", action, opts.InputType)
+		}
+		var blocked bool
+		if guardHit, blocked = scanGuard(webhook.Settings.SensitiveGuard, text, opts); blocked {
+			result.Code = CodeGuardBlocked
+			return "", errGuardBlocked
+		}
+		if guardHit {
+			result.Warnings = append(result.Warnings, "clipboard looks like it contains a secret; sent anyway (sensitive_guard = warn)")
+		}
+
+		req, err := newWebhookRequest(ctx, action, text, opts, guardHit)
+		if err != nil {
+			return "", err
+		}
+		resp, err := webhookClient.Do(req)
+		if err != nil {
+			if ctx.Err() == nil {
+				result.Code = CodeProviderUnreachable
+			}
+			return "", err
+		}
+		defer resp.Body.Close()
+		body := newCappedBuffer(maxStdoutBytes)
+		if _, err := io.Copy(body, resp.Body); err != nil {
+			return "", fmt.Errorf("reading webhook response: %w", err)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			result.Code = httpStatusCode(resp.StatusCode)
+			result.Diagnostics = body.String()
+			return "", fmt.Errorf("webhook returned %s", resp.Status)
+		}
+		if body.Len() == 0 {
+			return resp.Status, nil
+		}
+		return body.String(), nil
+	}()
+	if errors.Is(result.Error, context.DeadlineExceeded) {
+		result.Code = CodeTimeout
+		result.Error = fmt.Errorf("webhook timed out after %s", timeout)
+	}
+	result.Elapsed = time.Since(start)
+	result.Latency.Process = result.Elapsed
+	applySuperseded(ctx, &result)
+
+	recordOpts := opts
+	recordOpts.SensitiveGuardHit = guardHit
+	recordLocalRun(action, text, recordOpts, config.ActionKindWebhook, redactURL(webhook.Request.URL), result, webhook.Settings)
+	return result
+}
+
+// newWebhookRequest builds the request for opts.Webhook: its method, URL and
+// headers, the rendered body, and the body's signature when a secret is set.
+func newWebhookRequest(ctx context.Context, action string, text string, opts Options, guardHit bool) (*http.Request, error) {
+	spec := opts.Webhook.Request
+	method := strings.ToUpper(spec.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	var body []byte
+	if method != http.MethodGet {
+		contentType := "application/json"
+		for name, value := range spec.Headers {
+			if strings.EqualFold(name, "Content-Type") {
+				contentType = value
+			}
+		}
+		if spec.Body == "" {
+			event, err := json.Marshal(webhookEvent{
+				Action:    action,
+				Text:      text,
+				Type:      opts.InputType,
+				Trigger:   opts.Trigger,
+				Args:      opts.Args,
+				Match:     opts.Match,
+				Sensitive: guardHit,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			if err != nil {
+				return nil, err
+			}
+			body = event
+		} else {
+			body = []byte(renderWebhookBody(spec.Body, contentType, action, text, opts))
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, spec.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range spec.Headers {
+		req.Header.Set(name, value)
+	}
+	if spec.Secret != "" {
+		header := spec.SignatureHeader
+		if header == "" {
+			header = defaultSignatureHeader
+		}
+		mac := hmac.New(sha256.New, []byte(spec.Secret))
+		mac.Write(body)
+		req.Header.Set(header, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return req, nil
+}
+
+// renderWebhookBody expands {{input}}, {{action}}, {{type}}, {{trigger}},
+// {{args}} and {{match.<group>}} in template. Values are escaped for the
+// body's content type: as JSON string contents for JSON, form-encoded for
+// application/x-www-form-urlencoded, and verbatim otherwise.
+func renderWebhookBody(template string, contentType string, action string, text string, opts Options) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	escape := func(value string) string { return value }
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		escape = jsonStringContents
+	case mediaType == "application/x-www-form-urlencoded":
+		escape = url.QueryEscape
+	}
+	return webhookPlaceholderRe.ReplaceAllStringFunc(template, func(placeholder string) string {
+		groups := webhookPlaceholderRe.FindStringSubmatch(placeholder)
+		switch groups[1] {
+		case "input":
+			return escape(text)
+		case "action":
+			return escape(action)
+		case "type":
+			return escape(opts.InputType)
+		case "trigger":
+			return escape(opts.Trigger)
+		case "args":
+			return escape(strings.Join(opts.Args, " "))
+		default:
+			return escape(opts.Match[groups[2]])
+		}
+	})
+}
+
+// jsonStringContents returns value encoded as a JSON string, without the
+// surrounding quotes, so a template can place it inside "...".
+func jsonStringContents(value string) string {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	encoded := strings.TrimSuffix(out.String(), "\n")
+	return encoded[1 : len(encoded)-1]
+}
+
+// redactURL drops the userinfo and query from rawURL, which may carry
+// credentials, for recording in history.
+func redactURL(rawURL string) string {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	target.User = nil
+	target.RawQuery = ""
+	target.Fragment = ""
+	return target.String()
+}
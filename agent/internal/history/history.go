@@ -0,0 +1,224 @@
+package history
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+// Lock timings match the CLI's withHistoryLock so both writers cooperate on
+// the same lockfile.
+const (
+	lockTimeout  = 2 * time.Second
+	lockStale    = 30 * time.Second
+	lockInterval = 20 * time.Millisecond
+)
+
+// Record is one action run, in the history.jsonl format the CLI writes (see
+// cli/src/lib/history.ts).
+type Record struct {
+	ID               string   `json:"id"`
+	Timestamp        string   `json:"timestamp"`
+	Action           string   `json:"action"`
+	Args             []string `json:"args"`
+	Source           string   `json:"source"`
+	Trigger          string   `json:"trigger"`
+	Provider         string   `json:"provider"`
+	Model            string   `json:"model"`
+	LatencyMs        int      `json:"latency_ms"`
+	Status           string   `json:"status"`
+	Copy             bool     `json:"copy"`
+	Input            string   `json:"input"`
+	Output           string   `json:"output,omitempty"`
+	Error            string   `json:"error,omitempty"`
+	PromptTokens     int      `json:"prompt_tokens,omitempty"`
+	CompletionTokens int      `json:"completion_tokens,omitempty"`
+	Steps            []Step   `json:"steps,omitempty"`
+	Cached           bool     `json:"cached,omitempty"` // served from the result cache
+}
+
+// Step is one step of a pipeline run.
+type Step struct {
+	Action    string `json:"action"`
+	Status    string `json:"status"` // success, error or skipped
+	Attempts  int    `json:"attempts"`
+	LatencyMs int    `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Path returns the history file: $CBAI_HISTORY_FILE, else history.jsonl in
+// the data directory.
+func Path() string {
+	if path := os.Getenv("CBAI_HISTORY_FILE"); path != "" {
+		return path
+	}
+	return filepath.Join(config.GetDataDir(), "history.jsonl")
+}
+
+// Append writes record to the history file at path under the retention
+// settings, filling in its ID and timestamp when empty. It is a no-op when
+// history is disabled.
+func Append(path string, record Record, settings config.SettingsConfig) error {
+	if !settings.HistoryEnabled {
+		return nil
+	}
+	if record.ID == "" {
+		record.ID = newID()
+	}
+	if record.Timestamp == "" {
+		record.Timestamp = time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+	if record.Args == nil {
+		record.Args = []string{}
+	}
+	if limit := settings.HistoryTruncateChars; limit > 0 {
+		record.Input = truncateRunes(record.Input, limit)
+		record.Output = truncateRunes(record.Output, limit)
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return appendLines(path, append(line, '\n'), settings.HistoryMaxEntries)
+}
+
+// Import appends the records another writer left in the history file at src
+// to the history file at path, as they are, and removes src. It collects the
+// records of a sandboxed cbai run, which can't write the shared file. Like
+// Append it is a no-op when history is disabled.
+func Import(path string, src string, settings config.SettingsConfig) error {
+	if !settings.HistoryEnabled {
+		os.Remove(src)
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer os.Remove(src)
+	if strings.TrimSpace(string(data)) == "" {
+		return nil
+	}
+	if !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	return appendLines(path, data, settings.HistoryMaxEntries)
+}
+
+// appendLines appends JSONL data to the history file at path under its lock,
+// then compacts the file to maxEntries.
+func appendLines(path string, data []byte, maxEntries int) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return withLock(path, func() error {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(data); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(path, 0600); err != nil {
+			return err
+		}
+		return compact(path, maxEntries)
+	})
+}
+
+// compact keeps the newest maxEntries records once the file grows 10% past
+// the limit, as the CLI does.
+func compact(path string, maxEntries int) error {
+	if maxEntries < 0 {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) <= maxEntries*11/10 {
+		return nil
+	}
+
+	kept := lines[len(lines)-maxEntries:]
+	next := ""
+	if len(kept) > 0 {
+		next = strings.Join(kept, "\n") + "\n"
+	}
+	tmp := fmt.Sprintf("%s.tmp-%d-%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.WriteFile(tmp, []byte(next), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+// withLock serializes history writers through an O_EXCL lockfile shared with
+// the CLI. A lock older than lockStale is stolen, and if the lock still can't
+// be taken within lockTimeout fn runs anyway rather than dropping the record.
+func withLock(path string, fn func() error) error {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			defer os.Remove(lockPath)
+			defer lock.Close()
+			return fn()
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fn()
+		}
+		time.Sleep(lockInterval)
+	}
+}
+
+func truncateRunes(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value
+	}
+	return string(runes[:limit])
+}
+
+// newID returns an ID shaped like the CLI's: base-36 milliseconds, a dash,
+// and a random suffix.
+func newID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return strconv.FormatInt(time.Now().UnixMilli(), 36) + "-" + hex.EncodeToString(suffix)
+}
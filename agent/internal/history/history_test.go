@@ -0,0 +1,146 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open history: %v", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("corrupt history line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAppend_WritesRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "history.jsonl")
+	settings := config.Default().Settings
+
+	err := Append(path, Record{Action: "tweet", Source: "daemon", Status: "success", Input: "hello", Output: "hi"}, settings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records := readRecords(t, path)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	record := records[0]
+	if record.ID == "" || record.Action != "tweet" || record.Output != "hi" || record.Args == nil {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+	if _, err := time.Parse(time.RFC3339, record.Timestamp); err != nil {
+		t.Fatalf("expected an RFC 3339 timestamp, got %q", record.Timestamp)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat history: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected history mode 0600, got %v", info.Mode().Perm())
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Fatal("expected the lockfile to be removed")
+	}
+}
+
+func TestAppend_RetentionSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	settings := config.Default().Settings
+	settings.HistoryMaxEntries = 10
+	settings.HistoryTruncateChars = 4
+
+	for i := 0; i < 12; i++ {
+		if err := Append(path, Record{Action: "a", Input: "ünïcode input"}, settings); err != nil {
+			t.Fatalf("append %d: %v", i, err)
+		}
+	}
+
+	records := readRecords(t, path)
+	if len(records) != 10 {
+		t.Fatalf("expected compaction to 10 records, got %d", len(records))
+	}
+	if records[0].Input != "ünïc" {
+		t.Fatalf("expected input truncated to 4 runes, got %q", records[0].Input)
+	}
+
+	settings.HistoryEnabled = false
+	if err := Append(path, Record{Action: "skipped"}, settings); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := readRecords(t, path); got[len(got)-1].Action == "skipped" {
+		t.Fatal("expected no record with history disabled")
+	}
+}
+
+func TestAppend_StealsStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	lockPath := path + ".lock"
+	if err := os.WriteFile(lockPath, nil, 0600); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+	stale := time.Now().Add(-2 * lockStale)
+	if err := os.Chtimes(lockPath, stale, stale); err != nil {
+		t.Fatalf("age lock: %v", err)
+	}
+
+	if err := Append(path, Record{Action: "a"}, config.Default().Settings); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"action":"a"`) {
+		t.Fatalf("expected the record to be written, got %q", data)
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.jsonl")
+	src := filepath.Join(dir, "run", "history.jsonl")
+	if err := Append(path, Record{ID: "first", Action: "a"}, config.Default().Settings); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(src), 0700); err != nil {
+		t.Fatal(err)
+	}
+	// The last line lacks its newline, as a killed writer may leave it.
+	if err := os.WriteFile(src, []byte(`{"id":"second","action":"b"}`+"\n"+`{"id":"third","action":"c"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := config.Default().Settings
+	settings.HistoryMaxEntries = 2
+	if err := Import(path, src, settings); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records := readRecords(t, path)
+	if len(records) != 2 || records[0].ID != "second" || records[1].ID != "third" {
+		t.Fatalf("expected the imported records, compacted to 2, got %+v", records)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatal("expected the imported file to be removed")
+	}
+	if err := Import(path, src, settings); err != nil {
+		t.Fatalf("expected a missing file to import nothing, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
@@ -21,16 +22,15 @@ import (
 	"github.com/clipboard-ai/agent/internal/clipboard"
 	"github.com/clipboard-ai/agent/internal/config"
 	"github.com/clipboard-ai/agent/internal/executor"
+	"github.com/clipboard-ai/agent/internal/history"
+	"github.com/clipboard-ai/agent/internal/rules"
+	"github.com/clipboard-ai/agent/internal/sandbox"
+	"github.com/clipboard-ai/agent/internal/scheduler"
 )
 
 const maxActionRequestBodyBytes = 10 << 20
 const maxClipboardImageBytes = 25 << 20
 
-// maxConcurrentActionRequests bounds in-flight /action executions so a burst of
-// HTTP requests can't spawn unbounded LLM-calling subprocesses. Excess requests
-// get 429 rather than queueing.
-const maxConcurrentActionRequests = 4
-
 // Server provides HTTP API over Unix socket
 type Server struct {
 	socketPath string
@@ -40,7 +40,13 @@ type Server struct {
 	version    string
 	startTime  time.Time
 	listener   net.Listener
-	actionSem  chan struct{}
+	// scheduler bounds /action runs, shared with clipboard-triggered ones.
+	scheduler *scheduler.Scheduler
+	// ignoredEvents reports the settings.ignore counter; nil reports 0.
+	ignoredEvents func() int64
+	// rulesEngine selects /action routes; nil leaves the action's own
+	// model and endpoint in effect.
+	rulesEngine *rules.Engine
 }
 
 // SetConfig atomically swaps the config used by /config and /action.
@@ -50,6 +56,36 @@ func (s *Server) SetConfig(cfg *config.Config) {
 	s.config = cfg
 }
 
+// SetRulesEngine swaps the rules engine /action uses to pick an action's
+// route. Call it alongside SetConfig on reload.
+func (s *Server) SetRulesEngine(engine *rules.Engine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rulesEngine = engine
+}
+
+// SetIgnoredEventsSource sets the counter reported as ignored_events by
+// /status.
+func (s *Server) SetIgnoredEventsSource(source func() int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ignoredEvents = source
+}
+
+// SetScheduler replaces the server's own action scheduler with one shared
+// with the daemon's clipboard-triggered actions.
+func (s *Server) SetScheduler(sched *scheduler.Scheduler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduler = sched
+}
+
+func (s *Server) schedulerSnapshot() *scheduler.Scheduler {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.scheduler
+}
+
 func (s *Server) configSnapshot() *config.Config {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -58,10 +94,12 @@ func (s *Server) configSnapshot() *config.Config {
 
 // StatusResponse is returned by /status endpoint
 type StatusResponse struct {
-	Status    string `json:"status"`
-	Uptime    string `json:"uptime"`
-	Version   string `json:"version"`
-	Clipboard struct {
+	Status        string          `json:"status"`
+	Uptime        string          `json:"uptime"`
+	Version       string          `json:"version"`
+	IgnoredEvents int64           `json:"ignored_events"` // clipboard events dropped by settings.ignore
+	Queue         scheduler.Stats `json:"queue"`          // action slots, queue depth and wait times
+	Clipboard     struct {
 		Text      string `json:"text"`
 		Type      string `json:"type"`
 		Timestamp string `json:"timestamp"`
@@ -86,6 +124,7 @@ type ConfigResponse struct {
 	Provider config.ProviderConfig          `json:"provider"`
 	Actions  map[string]config.ActionConfig `json:"actions"`
 	Settings config.SettingsConfig          `json:"settings"`
+	Triggers map[string]string              `json:"triggers,omitempty"`
 }
 
 // HistoryRecord mirrors CLI history records stored in history.jsonl.
@@ -105,6 +144,10 @@ type HistoryRecord struct {
 	Output    string   `json:"output,omitempty"`
 	Error     string   `json:"error,omitempty"`
 	ReplayOf  string   `json:"replay_of,omitempty"`
+	// Steps reports each step of a pipeline run.
+	Steps []history.Step `json:"steps,omitempty"`
+	// Cached is true for a run served from the result cache.
+	Cached bool `json:"cached,omitempty"`
 }
 
 // HistoryResponse is returned by /history.
@@ -121,7 +164,7 @@ func NewServer(socketPath string, monitor *clipboard.Monitor, cfg *config.Config
 		config:     cfg,
 		version:    version,
 		startTime:  time.Now(),
-		actionSem:  make(chan struct{}, maxConcurrentActionRequests),
+		scheduler:  scheduler.New(scheduler.OptionsFrom(cfg.Settings)),
 	}
 }
 
@@ -203,6 +246,12 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		Uptime:  time.Since(s.startTime).Round(time.Second).String(),
 		Version: s.version,
 	}
+	s.mu.RLock()
+	if s.ignoredEvents != nil {
+		resp.IgnoredEvents = s.ignoredEvents()
+	}
+	resp.Queue = s.scheduler.Stats()
+	s.mu.RUnlock()
 	resp.Clipboard.Text = truncate(displayText, 100)
 	resp.Clipboard.Type = string(current.Type)
 	resp.Clipboard.Timestamp = current.Timestamp.Format(time.RFC3339)
@@ -294,11 +343,40 @@ func redactedConfigResponse(cfg *config.Config) ConfigResponse {
 
 	return ConfigResponse{
 		Provider: provider,
-		Actions:  cfg.Actions,
+		Actions:  redactedActions(cfg.Actions),
 		Settings: settings,
+		Triggers: cfg.Triggers,
 	}
 }
 
+// redactedActions copies actions with the values of their env entries and
+// webhook headers, which may hold tokens, and their webhook signing secrets
+// redacted.
+func redactedActions(actions map[string]config.ActionConfig) map[string]config.ActionConfig {
+	redacted := make(map[string]config.ActionConfig, len(actions))
+	for name, action := range actions {
+		action.Env = redactedValues(action.Env)
+		action.Webhook.Headers = redactedValues(action.Webhook.Headers)
+		if action.Webhook.Secret != "" {
+			action.Webhook.Secret = "<redacted>"
+		}
+		redacted[name] = action
+	}
+	return redacted
+}
+
+// redactedValues copies values with every value replaced by "<redacted>".
+func redactedValues(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(values))
+	for key := range values {
+		redacted[key] = "<redacted>"
+	}
+	return redacted
+}
+
 // ActionRequest for triggering an action
 type ActionRequest struct {
 	Action      string   `json:"action"`
@@ -308,39 +386,96 @@ type ActionRequest struct {
 	ImageMime   string   `json:"image_mime,omitempty"`
 	Type        string   `json:"type,omitempty"`
 	Args        []string `json:"args,omitempty"`
+	Debug       bool     `json:"debug,omitempty"` // include the action's stderr in the response
+	// Stream asks for the response as newline-delimited ActionStreamEvents,
+	// with a prompt action's tokens sent as they are generated.
+	Stream bool `json:"stream,omitempty"`
 }
 
 // ActionResponse from triggering an action
 type ActionResponse struct {
-	Success bool   `json:"success"`
-	Action  string `json:"action"`
-	Result  string `json:"result,omitempty"`
-	Error   string `json:"error,omitempty"`
-}
-
-// builtinActionNames is the set of action ids and aliases the CLI registry
-// ships (see cli/src/lib/builtin-actions.ts). Kept here so the agent can reject
-// requests for unknown actions before spawning a subprocess. Custom/plugin
-// actions are still accepted when the user has configured them (see isKnownAction).
-var builtinActionNames = map[string]struct{}{
-	"summary": {}, "summarize": {}, "sum": {},
-	"explain":   {},
-	"translate": {},
-	"improve":   {},
-	"extract":   {},
-	"tldr":      {},
-	"classify":  {},
-	"summarize_url": {}, "summarize-url": {},
-	"caption": {}, "describe": {}, "describe-image": {},
-	"ocr": {}, "extract-text": {},
+	Success   bool               `json:"success"`
+	Action    string             `json:"action"`
+	Result    string             `json:"result,omitempty"`
+	Error     string             `json:"error,omitempty"`
+	ErrorCode executor.ErrorCode `json:"error_code,omitempty"` // e.g. safe_mode_blocked, timeout
+	Route     *rules.Route       `json:"route,omitempty"`      // routes entry that chose the model/endpoint, if any
+	Provider  string             `json:"provider,omitempty"`
+	Model     string             `json:"model,omitempty"`
+	Usage     *executor.Usage    `json:"usage,omitempty"`
+	Warnings  []string           `json:"warnings,omitempty"`
+	// Diagnostics is the action's stderr, returned only for debug requests.
+	Diagnostics string `json:"diagnostics,omitempty"`
+	// Sandbox reports the protections applied to the action, when any were
+	// requested.
+	Sandbox *sandbox.Status `json:"sandbox,omitempty"`
+	// Steps reports each step of a pipeline action.
+	Steps []executor.StepResult `json:"steps,omitempty"`
+	// Cached is true when the result was served from the result cache.
+	Cached bool `json:"cached,omitempty"`
+	// Attempts is how many times the action ran, when transient failures
+	// were retried.
+	Attempts int `json:"attempts,omitempty"`
+}
+
+// ActionStreamEvent is one line of a streamed /action response: a token of
+// the output, a retry that discards the tokens sent so far, or the final
+// response.
+type ActionStreamEvent struct {
+	Token    string          `json:"token,omitempty"`
+	Retry    *ActionRetry    `json:"retry,omitempty"`
+	Response *ActionResponse `json:"response,omitempty"`
+}
+
+// ActionRetry reports a failed attempt that is about to be retried.
+type ActionRetry struct {
+	Attempt   int                `json:"attempt"`
+	Error     string             `json:"error"`
+	ErrorCode executor.ErrorCode `json:"error_code,omitempty"`
+	DelayMs   int64              `json:"delay_ms"`
+}
+
+// actionStream writes a streamed /action response, flushing each event.
+type actionStream struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+}
+
+func newActionStream(w http.ResponseWriter) *actionStream {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	return &actionStream{w: w, encoder: json.NewEncoder(w)}
+}
+
+func (s *actionStream) send(event ActionStreamEvent) {
+	if err := s.encoder.Encode(event); err != nil {
+		return
+	}
+	http.NewResponseController(s.w).Flush()
+}
+
+func (s *actionStream) token(token string) {
+	if token != "" {
+		s.send(ActionStreamEvent{Token: token})
+	}
+}
+
+func (s *actionStream) retry(attempt int, result executor.Result, delay time.Duration) {
+	s.send(ActionStreamEvent{Retry: &ActionRetry{
+		Attempt:   attempt,
+		Error:     result.Error.Error(),
+		ErrorCode: result.Code,
+		DelayMs:   delay.Milliseconds(),
+	}})
 }
 
 var actionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 
-// isKnownAction reports whether name is a builtin action or one the user has
-// configured (covers plugin/custom actions the agent can't enumerate itself).
+// isKnownAction reports whether name is a builtin action, a built-in
+// transform, or one the user has configured (covers plugin/custom actions the
+// agent can't enumerate itself).
 func isKnownAction(cfg *config.Config, name string) bool {
-	if _, ok := builtinActionNames[name]; ok {
+	if executor.IsBuiltinAction(name) || executor.IsTransformAction(name) {
 		return true
 	}
 	if cfg != nil {
@@ -383,23 +518,14 @@ func (s *Server) handleAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Bound concurrent executions; shed load with 429 instead of queueing.
-	if s.actionSem != nil {
-		select {
-		case s.actionSem <- struct{}{}:
-			defer func() { <-s.actionSem }()
-		default:
-			writeJSONError(w, http.StatusTooManyRequests, "Too many concurrent actions")
-			return
-		}
-	}
-
 	// Use clipboard content if payload not provided
 	inputText := req.Text
 	inputRTF := req.RTF
 	inputType := strings.TrimSpace(req.Type)
 	imageMime := req.ImageMime
 	var imageBytes []byte
+	inputSource := executor.InputSourceRequest
+	inputTimestamp := time.Now()
 
 	if inputText == "" && inputRTF == "" && req.ImageBase64 == "" {
 		current := s.monitor.Current()
@@ -408,6 +534,8 @@ func (s *Server) handleAction(w http.ResponseWriter, r *http.Request) {
 		imageBytes = current.Image
 		imageMime = current.ImageMime
 		inputType = string(current.Type)
+		inputSource = executor.InputSourceClipboard
+		inputTimestamp = current.Timestamp
 	}
 
 	if req.ImageBase64 != "" {
@@ -439,16 +567,75 @@ func (s *Server) handleAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	opts := executor.Options{
-		InputType: inputType,
-		InputRTF:  inputRTF,
-		Args:      req.Args,
-	}
 	cfg := s.configSnapshot()
+	opts := executor.Options{
+		InputType:       inputType,
+		InputRTF:        inputRTF,
+		InputSource:     inputSource,
+		InputTimestamp:  inputTimestamp,
+		Args:            req.Args,
+		Prompt:          executor.PromptFor(cfg, req.Action),
+		Command:         executor.CommandFor(cfg, req.Action, nil),
+		Transform:       executor.TransformFor(cfg, req.Action),
+		Webhook:         executor.WebhookFor(cfg, req.Action),
+		Pipeline:        executor.PipelineFor(cfg, req.Action, nil),
+		Cache:           executor.CacheFor(cfg, req.Action),
+		EnvInput:        cfg.Settings.ActionInput == "env",
+		Sandbox:         cfg.Settings.Sandbox,
+		HistorySettings: cfg.Settings,
+		Env:             executor.EnvFor(cfg, req.Action),
+	}
+	var route *rules.Route
 	if actionCfg, ok := cfg.Actions[req.Action]; ok {
-		opts.ModelOverride = actionCfg.Model
-		opts.EndpointOverride = actionCfg.Endpoint
+		s.mu.RLock()
+		engine := s.rulesEngine
+		s.mu.RUnlock()
+		if engine != nil {
+			route = engine.Route(req.Action, clipboard.Content{
+				Text:  inputText,
+				RTF:   inputRTF,
+				Image: imageBytes,
+				Type:  clipboard.ContentType(inputType),
+			})
+		}
+		opts.ModelOverride, opts.EndpointOverride = route.Overrides(actionCfg)
+		opts.Limits = actionCfg.Limits
+		opts.NoNetwork = actionCfg.NoNetwork
+		if route != nil {
+			slog.Info("action route selected",
+				"action", req.Action,
+				"route", route.Index,
+				"model", opts.ModelOverride,
+				"endpoint", opts.EndpointOverride,
+			)
+		}
 	}
+	// Wait for a run slot, within the action's and its endpoint's limits,
+	// ahead of clipboard-triggered actions; shed load with 429 once the
+	// queue is full. The first attempt runs in this slot, and each retry
+	// waits for a new one, so no slot is held through a backoff wait.
+	actionScheduler := s.schedulerSnapshot()
+	limits := executor.LimitsFor(cfg, req.Action, opts)
+	held, err := actionScheduler.Acquire(r.Context(), scheduler.PriorityManual, "", limits...)
+	if err != nil {
+		if r.Context().Err() == nil {
+			writeJSONError(w, http.StatusTooManyRequests, "Too many concurrent actions")
+		}
+		return
+	}
+	defer func() {
+		if held != nil {
+			held()
+		}
+	}()
+	acquire := func() (func(), error) {
+		if release := held; release != nil {
+			held = nil
+			return release, nil
+		}
+		return actionScheduler.Acquire(r.Context(), scheduler.PriorityManual, "", limits...)
+	}
+
 	if len(imageBytes) > 0 {
 		path, err := executor.WriteTempImage(imageBytes)
 		if err != nil {
@@ -460,21 +647,46 @@ func (s *Server) handleAction(w http.ResponseWriter, r *http.Request) {
 		opts.InputImageMime = imageMime
 	}
 
-	result := executor.ExecuteWithOptions(r.Context(), req.Action, inputText, opts)
+	var stream *actionStream
+	var onRetry func(attempt int, result executor.Result, delay time.Duration)
+	if req.Stream {
+		stream = newActionStream(w)
+		opts.OnToken = stream.token
+		onRetry = stream.retry
+	}
+
+	result, attempts := executor.ExecuteWithRetry(r.Context(), req.Action, inputText, opts, executor.RetryPolicyFor(cfg.Actions[req.Action]), acquire, onRetry)
+	response := ActionResponse{
+		Success:  result.Error == nil,
+		Action:   req.Action,
+		Route:    route,
+		Provider: result.Provider,
+		Model:    result.Model,
+		Warnings: result.Warnings,
+		Sandbox:  result.Sandbox,
+		Steps:    result.Steps,
+		Cached:   result.Cached,
+	}
+	if attempts > 1 {
+		response.Attempts = attempts
+	}
+	if result.Usage != (executor.Usage{}) {
+		response.Usage = &result.Usage
+	}
 	if result.Error != nil {
-		writeJSON(w, ActionResponse{
-			Success: false,
-			Action:  req.Action,
-			Error:   result.Error.Error(),
-		})
+		response.Error = result.Error.Error()
+		response.ErrorCode = result.Code
+	} else {
+		response.Result = result.Output
+	}
+	if req.Debug {
+		response.Diagnostics = result.Diagnostics
+	}
+	if stream != nil {
+		stream.send(ActionStreamEvent{Response: &response})
 		return
 	}
-
-	writeJSON(w, ActionResponse{
-		Success: true,
-		Action:  req.Action,
-		Result:  result.Output,
-	})
+	writeJSON(w, response)
 }
 
 func readHistoryRecords(path string, limit int) ([]HistoryRecord, int, error) {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -18,6 +19,8 @@ import (
 	"github.com/clipboard-ai/agent/internal/clipboard"
 	"github.com/clipboard-ai/agent/internal/config"
 	"github.com/clipboard-ai/agent/internal/executor"
+	"github.com/clipboard-ai/agent/internal/rules"
+	"github.com/clipboard-ai/agent/internal/scheduler"
 )
 
 func newTestServer() *Server {
@@ -77,6 +80,49 @@ func TestHandleStatus_GET(t *testing.T) {
 	}
 }
 
+func TestHandleStatus_ReportsIgnoredEvents(t *testing.T) {
+	s := newTestServer()
+	s.SetIgnoredEventsSource(func() int64 { return 3 })
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	w := httptest.NewRecorder()
+
+	s.handleStatus(w, req)
+
+	var resp StatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.IgnoredEvents != 3 {
+		t.Fatalf("expected ignored_events 3, got %d", resp.IgnoredEvents)
+	}
+}
+
+func TestHandleStatus_ReportsQueue(t *testing.T) {
+	s := newTestServer()
+	sched := scheduler.New(scheduler.Options{MaxConcurrent: 2, QueueSize: 8, Overflow: config.QueueOverflowCoalesce})
+	release, err := sched.Acquire(context.Background(), scheduler.PriorityTriggered, "summarize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	s.SetScheduler(sched)
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	w := httptest.NewRecorder()
+
+	s.handleStatus(w, req)
+
+	var resp StatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	queue := resp.Queue
+	if queue.MaxConcurrent != 2 || queue.QueueSize != 8 || queue.Overflow != config.QueueOverflowCoalesce || queue.Running != 1 || queue.Submitted != 1 {
+		t.Fatalf("unexpected queue status %+v", queue)
+	}
+}
+
 func TestHandleStatus_WrongMethod(t *testing.T) {
 	s := newTestServer()
 
@@ -190,6 +236,15 @@ func TestHandleConfig_RedactsSecrets(t *testing.T) {
 	s := newTestServer()
 	s.config.Provider.APIKey = "sk-test-secret"
 	s.config.Settings.HTTPAuthToken = "http-test-secret"
+	s.config.Actions["deploy"] = config.ActionConfig{Env: map[string]string{"DEPLOY_TOKEN": "env-test-secret"}}
+	s.config.Actions["notify"] = config.ActionConfig{
+		Kind: config.ActionKindWebhook,
+		Webhook: config.WebhookConfig{
+			URL:     "https://hooks.example.com/clip",
+			Headers: map[string]string{"Authorization": "Bearer header-test-secret"},
+			Secret:  "hmac-test-secret",
+		},
+	}
 
 	req := httptest.NewRequest(http.MethodGet, "/config", nil)
 	w := httptest.NewRecorder()
@@ -201,7 +256,7 @@ func TestHandleConfig_RedactsSecrets(t *testing.T) {
 	}
 
 	body := w.Body.String()
-	for _, leaked := range []string{"sk-test-secret", "http-test-secret"} {
+	for _, leaked := range []string{"sk-test-secret", "http-test-secret", "env-test-secret", "header-test-secret", "hmac-test-secret"} {
 		if bytes.Contains([]byte(body), []byte(leaked)) {
 			t.Fatalf("response leaked secret %q: %s", leaked, body)
 		}
@@ -217,6 +272,16 @@ func TestHandleConfig_RedactsSecrets(t *testing.T) {
 	if resp.Settings.HTTPAuthToken != "<redacted>" {
 		t.Fatalf("expected redacted http auth token, got %q", resp.Settings.HTTPAuthToken)
 	}
+	if got := resp.Actions["deploy"].Env["DEPLOY_TOKEN"]; got != "<redacted>" {
+		t.Fatalf("expected the env key kept with its value redacted, got %q", got)
+	}
+	if webhook := resp.Actions["notify"].Webhook; webhook.Secret != "<redacted>" ||
+		webhook.Headers["Authorization"] != "<redacted>" || webhook.URL != "https://hooks.example.com/clip" {
+		t.Fatalf("expected the webhook secret and headers redacted, got %+v", webhook)
+	}
+	if s.config.Actions["deploy"].Env["DEPLOY_TOKEN"] != "env-test-secret" {
+		t.Fatal("redacting the response changed the running config")
+	}
 }
 
 func TestHandleConfig_UsesSwappedConfig(t *testing.T) {
@@ -372,8 +437,9 @@ func TestHandleAction_UsesRequestText(t *testing.T) {
 	s := newTestServer()
 
 	var gotText string
-	executor.SetExecuteWithOptionsFunc(func(ctx context.Context, action string, text string, _ executor.Options) executor.Result {
-		gotText = text
+	var gotOpts executor.Options
+	executor.SetExecuteWithOptionsFunc(func(ctx context.Context, action string, text string, opts executor.Options) executor.Result {
+		gotText, gotOpts = text, opts
 		return executor.Result{
 			Action: action,
 			Output: "ok",
@@ -401,6 +467,9 @@ func TestHandleAction_UsesRequestText(t *testing.T) {
 	if gotText != "hello from request" {
 		t.Fatalf("expected text 'hello from request', got %q", gotText)
 	}
+	if gotOpts.InputSource != executor.InputSourceRequest || gotOpts.InputTimestamp.IsZero() {
+		t.Fatalf("expected request input stamped now, got source %q at %v", gotOpts.InputSource, gotOpts.InputTimestamp)
+	}
 	if resp.Result != "ok" {
 		t.Fatalf("expected result 'ok', got %q", resp.Result)
 	}
@@ -447,6 +516,333 @@ func TestHandleAction_AppliesConfiguredOverrides(t *testing.T) {
 	}
 }
 
+func TestHandleAction_RunsCommandAction(t *testing.T) {
+	s := newTestServer()
+	s.config.Settings.HistoryEnabled = false
+	s.config.Actions["upper"] = config.ActionConfig{
+		Enabled: true,
+		Kind:    config.ActionKindCommand,
+		Command: []string{"tr", "a-z", "A-Z"},
+	}
+
+	body, _ := json.Marshal(ActionRequest{Action: "upper", Text: "hello"})
+	req := httptest.NewRequest(http.MethodPost, "/action", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	s.handleAction(w, req)
+
+	var resp ActionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Success || resp.Result != "HELLO" {
+		t.Fatalf("expected the command's output, got %+v", resp)
+	}
+}
+
+func TestHandleAction_RetriesTransientFailures(t *testing.T) {
+	s := newTestServer()
+	s.config.Settings.HistoryEnabled = false
+	marker := filepath.Join(t.TempDir(), "ran")
+	// Fails with EX_TEMPFAIL on the first run and succeeds on the second.
+	s.config.Actions["flaky"] = config.ActionConfig{
+		Enabled:    true,
+		Kind:       config.ActionKindCommand,
+		Command:    []string{"/bin/sh", "-c", `[ -e "$1" ] && echo ok || { touch "$1"; exit 75; }`, "sh", marker},
+		RetryCount: 2,
+	}
+	s.config.Actions["broken"] = config.ActionConfig{
+		Enabled:    true,
+		Kind:       config.ActionKindCommand,
+		Command:    []string{"/bin/sh", "-c", `echo run >> "$1"; exit 1`, "sh", marker + ".broken"},
+		RetryCount: 2,
+	}
+
+	tests := []struct {
+		action       string
+		wantSuccess  bool
+		wantAttempts int
+	}{
+		{action: "flaky", wantSuccess: true, wantAttempts: 2},
+		{action: "broken", wantSuccess: false, wantAttempts: 0},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(ActionRequest{Action: tt.action, Text: "hello"})
+		req := httptest.NewRequest(http.MethodPost, "/action", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		s.handleAction(w, req)
+
+		var resp ActionResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Success != tt.wantSuccess || resp.Attempts != tt.wantAttempts {
+			t.Fatalf("%s: expected success %v after %d attempts, got %+v", tt.action, tt.wantSuccess, tt.wantAttempts, resp)
+		}
+	}
+	// A plain failure isn't worth retrying.
+	if data, _ := os.ReadFile(marker + ".broken"); strings.Count(string(data), "run") != 1 {
+		t.Fatalf("expected the failing command to run once, got %q", data)
+	}
+}
+
+func TestHandleAction_RunsTransform(t *testing.T) {
+	s := newTestServer()
+	s.config.Settings.HistoryEnabled = false
+
+	body, _ := json.Marshal(ActionRequest{Action: "base64-decode", Text: "aGVsbG8="})
+	req := httptest.NewRequest(http.MethodPost, "/action", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	s.handleAction(w, req)
+
+	var resp ActionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Success || resp.Result != "hello" {
+		t.Fatalf("expected the decoded text, got %+v", resp)
+	}
+}
+
+func TestHandleAction_RunsPipeline(t *testing.T) {
+	s := newTestServer()
+	s.config.Settings.HistoryEnabled = false
+	s.config.Actions["decode_upper"] = config.ActionConfig{
+		Kind:     config.ActionKindPipeline,
+		Pipeline: []string{"base64-decode", "uppercase"},
+	}
+
+	body, _ := json.Marshal(ActionRequest{Action: "decode_upper", Text: "aGVsbG8="})
+	req := httptest.NewRequest(http.MethodPost, "/action", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	s.handleAction(w, req)
+
+	var resp ActionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Success || resp.Result != "HELLO" {
+		t.Fatalf("expected the decoded, uppercased text, got %+v", resp)
+	}
+	if len(resp.Steps) != 2 || resp.Steps[0].Action != "base64-decode" || resp.Steps[1].Status != executor.StepSuccess {
+		t.Fatalf("expected a report for each step, got %+v", resp.Steps)
+	}
+}
+
+func TestIsKnownAction(t *testing.T) {
+	cfg := config.Default()
+	cfg.Actions["tweet"] = config.ActionConfig{Prompt: "Rewrite as a tweet: {{input}}"}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "summarize", want: true},
+		{name: "describe-image", want: true},
+		{name: "json-pretty", want: true},
+		{name: "sha256", want: true},
+		{name: "tweet", want: true},
+		{name: "nonexistent", want: false},
+	}
+	for _, tt := range tests {
+		if got := isKnownAction(cfg, tt.name); got != tt.want {
+			t.Errorf("isKnownAction(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandleAction_AppliesMatchingRoute(t *testing.T) {
+	s := newTestServer()
+	s.config.Actions["summarize"] = config.ActionConfig{
+		Model: "llama3.2:1b",
+		Routes: []config.RouteConfig{
+			{When: "length > 20", Model: "llama3.1:70b"},
+			{When: "mime:code", Endpoint: "http://localhost:11435/v1"},
+		},
+	}
+	engine, err := rules.NewEngineFromConfig(s.config)
+	if err != nil {
+		t.Fatalf("failed to create rules engine: %v", err)
+	}
+	s.SetRulesEngine(engine)
+
+	var gotOptions executor.Options
+	executor.SetExecuteWithOptionsFunc(func(ctx context.Context, action string, text string, opts executor.Options) executor.Result {
+		gotOptions = opts
+		return executor.Result{Action: action, Output: "ok"}
+	})
+	defer executor.ResetExecuteFunc()
+
+	tests := []struct {
+		text         string
+		wantModel    string
+		wantEndpoint string
+		wantRoute    int
+	}{
+		{text: "a paragraph well over twenty characters", wantModel: "llama3.1:70b", wantRoute: 0},
+		{text: "short", wantModel: "llama3.2:1b", wantRoute: -1},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(ActionRequest{Action: "summarize", Text: tt.text})
+		req := httptest.NewRequest(http.MethodPost, "/action", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		s.handleAction(w, req)
+
+		var resp ActionResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if gotOptions.ModelOverride != tt.wantModel {
+			t.Errorf("%q: expected model %q, got %q", tt.text, tt.wantModel, gotOptions.ModelOverride)
+		}
+		if gotOptions.EndpointOverride != tt.wantEndpoint {
+			t.Errorf("%q: expected endpoint %q, got %q", tt.text, tt.wantEndpoint, gotOptions.EndpointOverride)
+		}
+		switch {
+		case tt.wantRoute < 0 && resp.Route != nil:
+			t.Errorf("%q: expected no route, got %+v", tt.text, resp.Route)
+		case tt.wantRoute >= 0 && (resp.Route == nil || resp.Route.Index != tt.wantRoute):
+			t.Errorf("%q: expected route %d, got %+v", tt.text, tt.wantRoute, resp.Route)
+		}
+	}
+}
+
+func TestHandleAction_RunsPromptActionsInProcess(t *testing.T) {
+	s := newTestServer()
+	s.config.Actions["tweet"] = config.ActionConfig{Prompt: "Rewrite as a tweet:"}
+
+	var gotOptions executor.Options
+	executor.SetExecuteWithOptionsFunc(func(ctx context.Context, action string, text string, opts executor.Options) executor.Result {
+		gotOptions = opts
+		return executor.Result{Action: action, Output: "ok"}
+	})
+	defer executor.ResetExecuteFunc()
+
+	for _, action := range []string{"tweet", "summarize"} {
+		body, _ := json.Marshal(ActionRequest{Action: action, Text: "hello"})
+		s.handleAction(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/action", bytes.NewBuffer(body)))
+
+		if wantPrompt := action == "tweet"; (gotOptions.Prompt != nil) != wantPrompt {
+			t.Fatalf("%s: expected in-process prompt %v, got %+v", action, wantPrompt, gotOptions.Prompt)
+		}
+	}
+}
+
+func TestHandleAction_StreamsTokens(t *testing.T) {
+	s := newTestServer()
+	s.config.Actions["tweet"] = config.ActionConfig{Prompt: "Rewrite as a tweet:", RetryCount: 1}
+
+	attempt := 0
+	executor.SetExecuteWithOptionsFunc(func(ctx context.Context, action string, text string, opts executor.Options) executor.Result {
+		attempt++
+		if opts.OnToken == nil {
+			t.Fatal("expected a token callback for a streamed request")
+		}
+		if attempt == 1 {
+			opts.OnToken("par")
+			return executor.Result{Action: action, Error: errors.New("provider returned HTTP 429"), Code: executor.CodeRateLimited}
+		}
+		for _, token := range []string{"hello", " world"} {
+			opts.OnToken(token)
+		}
+		return executor.Result{Action: action, Output: "hello world"}
+	})
+	defer executor.ResetExecuteFunc()
+
+	body, _ := json.Marshal(ActionRequest{Action: "tweet", Text: "hello", Stream: true})
+	w := httptest.NewRecorder()
+	s.handleAction(w, httptest.NewRequest(http.MethodPost, "/action", bytes.NewBuffer(body)))
+
+	if got := w.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Fatalf("expected an NDJSON stream, got %q", got)
+	}
+	var events []ActionStreamEvent
+	decoder := json.NewDecoder(w.Body)
+	for decoder.More() {
+		var event ActionStreamEvent
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatalf("failed to decode event: %v", err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 5 {
+		t.Fatalf("expected 5 events, got %+v", events)
+	}
+	if events[0].Token != "par" || events[1].Retry == nil || events[1].Retry.ErrorCode != executor.CodeRateLimited ||
+		events[2].Token != "hello" || events[3].Token != " world" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if final := events[4].Response; final == nil || !final.Success || final.Result != "hello world" || final.Attempts != 2 {
+		t.Fatalf("expected the final response last, got %+v", events[4])
+	}
+}
+
+func TestHandleAction_ReportsTypedResult(t *testing.T) {
+	s := newTestServer()
+
+	executor.SetExecuteWithOptionsFunc(func(ctx context.Context, action string, text string, opts executor.Options) executor.Result {
+		if text == "blocked" {
+			return executor.Result{Action: action, Error: errors.New("safe mode: blocked cloud call to openai"), Code: executor.CodeSafeModeBlocked}
+		}
+		return executor.Result{
+			Action:   action,
+			Output:   "ok",
+			Provider: "ollama",
+			Model:    "mistral",
+			Usage:    executor.Usage{PromptTokens: 12, CompletionTokens: 3},
+			Warnings: []string{"output was truncated at max_tokens=80"},
+		}
+	})
+	defer executor.ResetExecuteFunc()
+
+	decode := func(text string) ActionResponse {
+		body, _ := json.Marshal(ActionRequest{Action: "summarize", Text: text})
+		w := httptest.NewRecorder()
+		s.handleAction(w, httptest.NewRequest(http.MethodPost, "/action", bytes.NewBuffer(body)))
+		var resp ActionResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	resp := decode("hello")
+	if !resp.Success || resp.Model != "mistral" || resp.Usage == nil || resp.Usage.CompletionTokens != 3 || len(resp.Warnings) != 1 {
+		t.Fatalf("unexpected success response: %+v", resp)
+	}
+	resp = decode("blocked")
+	if resp.Success || resp.ErrorCode != executor.CodeSafeModeBlocked || resp.Usage != nil {
+		t.Fatalf("unexpected failure response: %+v", resp)
+	}
+}
+
+func TestHandleAction_DiagnosticsOnlyWhenDebugging(t *testing.T) {
+	s := newTestServer()
+
+	executor.SetExecuteWithOptionsFunc(func(ctx context.Context, action string, text string, opts executor.Options) executor.Result {
+		return executor.Result{Action: action, Output: "ok", Diagnostics: "plugin: cache miss"}
+	})
+	defer executor.ResetExecuteFunc()
+
+	for _, debug := range []bool{false, true} {
+		body, _ := json.Marshal(ActionRequest{Action: "summarize", Text: "hello", Debug: debug})
+		w := httptest.NewRecorder()
+		s.handleAction(w, httptest.NewRequest(http.MethodPost, "/action", bytes.NewBuffer(body)))
+
+		var resp ActionResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if got := resp.Diagnostics != ""; got != debug {
+			t.Errorf("debug=%v: expected diagnostics %v, got %q", debug, debug, resp.Diagnostics)
+		}
+	}
+}
+
 func TestHandleAction_PassesArgs(t *testing.T) {
 	s := newTestServer()
 
@@ -696,10 +1092,14 @@ func TestHandleAction_PassesInjectedFlagAsPositionalArg(t *testing.T) {
 
 func TestHandleAction_RateLimitsWhenSaturated(t *testing.T) {
 	s := newTestServer()
-	// Saturate the concurrency semaphore so the next request is shed.
-	for i := 0; i < cap(s.actionSem); i++ {
-		s.actionSem <- struct{}{}
+	// Fill the only slot with no room to queue, so the next request is shed.
+	sched := scheduler.New(scheduler.Options{MaxConcurrent: 1, Overflow: config.QueueOverflowReject})
+	release, err := sched.Acquire(context.Background(), scheduler.PriorityTriggered, "summarize")
+	if err != nil {
+		t.Fatal(err)
 	}
+	defer release()
+	s.SetScheduler(sched)
 
 	executor.SetExecuteWithOptionsFunc(func(ctx context.Context, action string, text string, opts executor.Options) executor.Result {
 		t.Fatal("executor must not run when the action limiter is saturated")
@@ -718,6 +1118,79 @@ func TestHandleAction_RateLimitsWhenSaturated(t *testing.T) {
 	}
 }
 
+func TestHandleAction_RespectsActionLimit(t *testing.T) {
+	s := newTestServer()
+	cfg := config.Default()
+	cfg.Actions["summarize"] = config.ActionConfig{Enabled: true, Trigger: "length > 200", MaxConcurrent: 1}
+	s.SetConfig(cfg)
+	// Slots are free overall, but summarize's only one is taken.
+	sched := scheduler.New(scheduler.Options{MaxConcurrent: 4, Overflow: config.QueueOverflowReject})
+	release, err := sched.Acquire(context.Background(), scheduler.PriorityTriggered, "summarize", scheduler.Limit{Key: "action:summarize", Max: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetScheduler(sched)
+
+	ran := 0
+	executor.SetExecuteWithOptionsFunc(func(ctx context.Context, action string, text string, opts executor.Options) executor.Result {
+		ran++
+		return executor.Result{Output: "ok"}
+	})
+	defer executor.ResetExecuteFunc()
+
+	for _, tt := range []struct {
+		action string
+		want   int
+	}{{"summarize", http.StatusTooManyRequests}, {"explain", http.StatusOK}} {
+		body, _ := json.Marshal(ActionRequest{Action: tt.action, Text: "hi"})
+		w := httptest.NewRecorder()
+		s.handleAction(w, httptest.NewRequest(http.MethodPost, "/action", bytes.NewBuffer(body)))
+		if w.Code != tt.want {
+			t.Fatalf("%s: expected %d, got %d", tt.action, tt.want, w.Code)
+		}
+	}
+	if ran != 1 {
+		t.Fatalf("expected only explain to run, ran %d actions", ran)
+	}
+	release()
+}
+
+func TestHandleAction_ReleasesSlotBetweenRetries(t *testing.T) {
+	s := newTestServer()
+	cfg := config.Default()
+	cfg.Actions["summarize"] = config.ActionConfig{Enabled: true, Trigger: "length > 200", RetryCount: 1, RetryBackoffMs: 1}
+	s.SetConfig(cfg)
+	// With one slot and no queue, the retry only runs if the first attempt
+	// gave its slot up.
+	sched := scheduler.New(scheduler.Options{MaxConcurrent: 1, Overflow: config.QueueOverflowReject})
+	s.SetScheduler(sched)
+
+	ran := 0
+	executor.SetExecuteWithOptionsFunc(func(ctx context.Context, action string, text string, opts executor.Options) executor.Result {
+		ran++
+		if ran == 1 {
+			return executor.Result{Error: errors.New("busy"), Code: executor.CodeServerError}
+		}
+		return executor.Result{Output: "ok"}
+	})
+	defer executor.ResetExecuteFunc()
+
+	body, _ := json.Marshal(ActionRequest{Action: "summarize", Text: "hi"})
+	w := httptest.NewRecorder()
+	s.handleAction(w, httptest.NewRequest(http.MethodPost, "/action", bytes.NewBuffer(body)))
+
+	var resp ActionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Success || resp.Attempts != 2 {
+		t.Fatalf("expected success after 2 attempts, got %+v", resp)
+	}
+	if stats := sched.Stats(); stats.Started != 2 || stats.Running != 0 {
+		t.Fatalf("expected a slot per attempt, all released, got %+v", stats)
+	}
+}
+
 func TestErrorResponsesAreJSON(t *testing.T) {
 	s := newTestServer()
 
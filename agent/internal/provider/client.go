@@ -0,0 +1,401 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultMaxTokens caps completions when neither the action nor settings set
+// max_tokens, matching the CLI's AIClient.
+const DefaultMaxTokens = 1024
+
+// defaultTemperature matches the CLI's AIClient.generate.
+const defaultTemperature = 0.7
+
+// maxErrorBodyBytes bounds how much of a failed response is read for its
+// error message.
+const maxErrorBodyBytes = 64 << 10
+
+// ErrNoCompletion is returned when the provider answers without any content.
+var ErrNoCompletion = errors.New("provider returned no completion choices")
+
+// ErrUnreachable wraps transport failures: the provider couldn't be reached
+// or dropped the connection before answering.
+var ErrUnreachable = errors.New("provider unreachable")
+
+// StatusError is a non-2xx response from the provider.
+type StatusError struct {
+	StatusCode int
+	Message    string // the provider's own message, if it sent one
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("provider returned HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("provider returned HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// Config describes the provider a Client talks to.
+type Config struct {
+	Type      string // ollama, openai, anthropic
+	Endpoint  string // base URL; empty uses DefaultEndpoint(Type)
+	Model     string
+	APIKey    string
+	MaxTokens int // <= 0 uses DefaultMaxTokens
+}
+
+// Request is a single chat completion.
+type Request struct {
+	System string
+	Prompt string
+	// OnToken, when set, streams the completion and receives each token as it
+	// arrives; the full content is still returned in the Response.
+	OnToken func(token string)
+}
+
+// Response is a completed chat completion.
+type Response struct {
+	Content          string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	// Truncated reports that generation stopped at the max_tokens cap.
+	Truncated bool
+}
+
+// Client is a minimal chat client for OpenAI-compatible endpoints and the
+// native Ollama API.
+type Client struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// NewClient creates a client for cfg. A nil httpClient uses
+// http.DefaultClient; request deadlines come from the caller's context.
+func NewClient(cfg Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if strings.TrimSpace(cfg.Endpoint) == "" {
+		cfg.Endpoint = DefaultEndpoint(cfg.Type)
+	}
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = DefaultMaxTokens
+	}
+	return &Client{config: cfg, httpClient: httpClient}
+}
+
+// DefaultEndpoint returns the base URL used for a provider type without an
+// explicit endpoint (see cli/src/lib/provider-endpoints.ts).
+func DefaultEndpoint(providerType string) string {
+	switch providerType {
+	case "openai":
+		return "https://api.openai.com/v1"
+	case "anthropic":
+		return "https://api.anthropic.com/v1/"
+	default:
+		return "http://localhost:11434/v1"
+	}
+}
+
+// IsCloud reports whether a provider call leaves the machine: an explicit
+// endpoint counts as local only for localhost/127.0.0.1/::1, and without one
+// only ollama is local. Mirrors the CLI's safe-mode check.
+func IsCloud(providerType string, endpoint string) bool {
+	if strings.TrimSpace(endpoint) != "" {
+		parsed, err := url.Parse(endpoint)
+		if err != nil {
+			return true
+		}
+		switch parsed.Hostname() {
+		case "localhost", "127.0.0.1", "::1":
+			return false
+		}
+		return true
+	}
+	return providerType != "ollama"
+}
+
+// usesOllamaAPI reports whether the client should speak Ollama's native
+// /api/chat protocol: an ollama provider whose endpoint is not the /v1
+// OpenAI-compatible path.
+func (c *Client) usesOllamaAPI() bool {
+	if c.config.Type != "ollama" {
+		return false
+	}
+	return !strings.HasSuffix(strings.TrimRight(c.config.Endpoint, "/"), "/v1")
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+func (r Request) messages() []chatMessage {
+	var messages []chatMessage
+	if r.System != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: r.System})
+	}
+	return append(messages, chatMessage{Role: "user", Content: r.Prompt})
+}
+
+// Complete runs a chat completion, streaming when req.OnToken is set.
+func (c *Client) Complete(ctx context.Context, req Request) (Response, error) {
+	if c.usesOllamaAPI() {
+		return c.completeOllama(ctx, req)
+	}
+	return c.completeOpenAI(ctx, req)
+}
+
+func (c *Client) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := strings.TrimRight(c.config.Endpoint, "/") + path
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, statusError(resp)
+	}
+	return resp, nil
+}
+
+// statusError builds a *StatusError from a non-2xx response, preferring the
+// provider's own message ({"error": {"message": ...}} or {"error": "..."}).
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	var parsed struct {
+		Error json.RawMessage `json:"error"`
+	}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &parsed) == nil && len(parsed.Error) > 0 {
+		var text string
+		var object struct {
+			Message string `json:"message"`
+		}
+		switch {
+		case json.Unmarshal(parsed.Error, &text) == nil && text != "":
+			message = text
+		case json.Unmarshal(parsed.Error, &object) == nil && object.Message != "":
+			message = object.Message
+		}
+	}
+	return &StatusError{StatusCode: resp.StatusCode, Message: message}
+}
+
+type openAIRequest struct {
+	Model         string              `json:"model"`
+	Messages      []chatMessage       `json:"messages"`
+	Temperature   float64             `json:"temperature"`
+	MaxTokens     int                 `json:"max_tokens"`
+	Stream        bool                `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOption `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOption struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message *struct {
+			Content *string `json:"content"`
+		} `json:"message"`
+		Delta *struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (c *Client) completeOpenAI(ctx context.Context, req Request) (Response, error) {
+	body := openAIRequest{
+		Model:       c.config.Model,
+		Messages:    req.messages(),
+		Temperature: defaultTemperature,
+		MaxTokens:   c.config.MaxTokens,
+	}
+	if req.OnToken != nil {
+		body.Stream = true
+		body.StreamOptions = &openAIStreamOption{IncludeUsage: true}
+	}
+
+	resp, err := c.post(ctx, "/chat/completions", body)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	if req.OnToken == nil {
+		var parsed openAIResponse
+		if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+			return Response{}, fmt.Errorf("decode provider response: %w", err)
+		}
+		if len(parsed.Choices) == 0 || parsed.Choices[0].Message == nil || parsed.Choices[0].Message.Content == nil {
+			return Response{}, ErrNoCompletion
+		}
+		result := Response{Content: *parsed.Choices[0].Message.Content, Model: parsed.Model}
+		if reason := parsed.Choices[0].FinishReason; reason != nil && *reason == "length" {
+			result.Truncated = true
+		}
+		if parsed.Usage != nil {
+			result.PromptTokens = parsed.Usage.PromptTokens
+			result.CompletionTokens = parsed.Usage.CompletionTokens
+		}
+		return result, nil
+	}
+
+	// Server-sent events: one "data: {chunk}" per line, ending with
+	// "data: [DONE]".
+	result := Response{Model: c.config.Model}
+	var content strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return Response{}, fmt.Errorf("decode provider stream: %w", err)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.PromptTokens = chunk.Usage.PromptTokens
+			result.CompletionTokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		if reason := chunk.Choices[0].FinishReason; reason != nil && *reason == "length" {
+			result.Truncated = true
+		}
+		if delta := chunk.Choices[0].Delta; delta != nil && delta.Content != "" {
+			content.WriteString(delta.Content)
+			req.OnToken(delta.Content)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Response{}, fmt.Errorf("read provider stream: %w", err)
+	}
+	if content.Len() == 0 {
+		return Response{}, ErrNoCompletion
+	}
+	result.Content = content.String()
+	return result, nil
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict"`
+}
+
+type ollamaResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+func (c *Client) completeOllama(ctx context.Context, req Request) (Response, error) {
+	resp, err := c.post(ctx, "/api/chat", ollamaRequest{
+		Model:    c.config.Model,
+		Messages: req.messages(),
+		Stream:   req.OnToken != nil,
+		Options: ollamaOptions{
+			Temperature: defaultTemperature,
+			NumPredict:  c.config.MaxTokens,
+		},
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	// A non-streaming reply is a single object; a streaming one is
+	// newline-delimited objects, the last with done=true. Decoding a stream
+	// of values handles both.
+	result := Response{Model: c.config.Model}
+	var content strings.Builder
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk ollamaResponse
+		if err := decoder.Decode(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			return Response{}, fmt.Errorf("decode provider response: %w", err)
+		}
+		if chunk.Error != "" {
+			return Response{}, fmt.Errorf("provider error: %s", chunk.Error)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if token := chunk.Message.Content; token != "" {
+			content.WriteString(token)
+			if req.OnToken != nil {
+				req.OnToken(token)
+			}
+		}
+		if chunk.Done {
+			result.PromptTokens = chunk.PromptEvalCount
+			result.CompletionTokens = chunk.EvalCount
+			result.Truncated = chunk.DoneReason == "length"
+			break
+		}
+	}
+	if content.Len() == 0 {
+		return Response{}, ErrNoCompletion
+	}
+	result.Content = content.String()
+	return result, nil
+}
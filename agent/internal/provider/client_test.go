@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newStandIn starts an httptest server that records the decoded request body
+// and answers with handler.
+func newStandIn(t *testing.T, wantPath string, handler func(w http.ResponseWriter, body map[string]any)) (*httptest.Server, *map[string]any) {
+	t.Helper()
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != wantPath {
+			t.Errorf("expected request to %s, got %s", wantPath, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		got["authorization"] = r.Header.Get("Authorization")
+		handler(w, got)
+	}))
+	t.Cleanup(server.Close)
+	return server, &got
+}
+
+func TestComplete_OpenAICompatible(t *testing.T) {
+	server, got := newStandIn(t, "/v1/chat/completions", func(w http.ResponseWriter, _ map[string]any) {
+		fmt.Fprint(w, `{"model":"gpt-4o-mini","choices":[{"message":{"content":"a summary"},"finish_reason":"stop"}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`)
+	})
+
+	client := NewClient(Config{Type: "openai", Endpoint: server.URL + "/v1", Model: "gpt-4o-mini", APIKey: "sk-test", MaxTokens: 256}, server.Client())
+	resp, err := client.Complete(context.Background(), Request{System: "be brief", Prompt: "summarize this"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Content != "a summary" || resp.Model != "gpt-4o-mini" || resp.Truncated {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp.PromptTokens != 12 || resp.CompletionTokens != 3 {
+		t.Fatalf("expected usage 12/3, got %d/%d", resp.PromptTokens, resp.CompletionTokens)
+	}
+	body := *got
+	if body["max_tokens"] != float64(256) || body["model"] != "gpt-4o-mini" || body["stream"] != nil {
+		t.Fatalf("unexpected request body: %v", body)
+	}
+	if body["authorization"] != "Bearer sk-test" {
+		t.Fatalf("expected bearer auth, got %v", body["authorization"])
+	}
+	messages, _ := body["messages"].([]any)
+	if len(messages) != 2 {
+		t.Fatalf("expected system and user messages, got %v", body["messages"])
+	}
+}
+
+func TestComplete_DefaultMaxTokensAndTruncation(t *testing.T) {
+	server, got := newStandIn(t, "/v1/chat/completions", func(w http.ResponseWriter, _ map[string]any) {
+		fmt.Fprint(w, `{"model":"m","choices":[{"message":{"content":"cut"},"finish_reason":"length"}]}`)
+	})
+
+	resp, err := NewClient(Config{Type: "ollama", Endpoint: server.URL + "/v1", Model: "m"}, server.Client()).
+		Complete(context.Background(), Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Truncated {
+		t.Fatal("expected finish_reason=length to mark the response truncated")
+	}
+	if (*got)["max_tokens"] != float64(DefaultMaxTokens) {
+		t.Fatalf("expected default max_tokens %d, got %v", DefaultMaxTokens, (*got)["max_tokens"])
+	}
+	if (*got)["authorization"] != "" {
+		t.Fatalf("expected no auth header without an API key, got %v", (*got)["authorization"])
+	}
+}
+
+func TestComplete_OpenAIStreaming(t *testing.T) {
+	server, got := newStandIn(t, "/v1/chat/completions", func(w http.ResponseWriter, _ map[string]any) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"model":"m","choices":[{"delta":{"role":"assistant"}}]}`,
+			`{"model":"m","choices":[{"delta":{"content":"Hel"}}]}`,
+			`{"model":"m","choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+			`{"model":"m","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2}}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	var tokens []string
+	resp, err := NewClient(Config{Type: "openai", Endpoint: server.URL + "/v1", Model: "m"}, server.Client()).
+		Complete(context.Background(), Request{Prompt: "hi", OnToken: func(token string) { tokens = append(tokens, token) }})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "Hello" || strings.Join(tokens, "|") != "Hel|lo" {
+		t.Fatalf("expected streamed tokens Hel|lo, got %q (%q)", tokens, resp.Content)
+	}
+	if resp.PromptTokens != 5 || resp.CompletionTokens != 2 {
+		t.Fatalf("expected usage from the final chunk, got %+v", resp)
+	}
+	if (*got)["stream"] != true {
+		t.Fatalf("expected stream=true in request, got %v", (*got)["stream"])
+	}
+}
+
+func TestComplete_OllamaNative(t *testing.T) {
+	server, got := newStandIn(t, "/api/chat", func(w http.ResponseWriter, _ map[string]any) {
+		fmt.Fprint(w, `{"model":"mistral","message":{"role":"assistant","content":"native"},"done":true,"done_reason":"stop","prompt_eval_count":9,"eval_count":1}`)
+	})
+
+	resp, err := NewClient(Config{Type: "ollama", Endpoint: server.URL, Model: "mistral", MaxTokens: 64}, server.Client()).
+		Complete(context.Background(), Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "native" || resp.PromptTokens != 9 || resp.CompletionTokens != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	options, _ := (*got)["options"].(map[string]any)
+	if options["num_predict"] != float64(64) || (*got)["stream"] != false {
+		t.Fatalf("expected num_predict=64 and stream=false, got %v", *got)
+	}
+}
+
+func TestComplete_OllamaNativeStreaming(t *testing.T) {
+	server, _ := newStandIn(t, "/api/chat", func(w http.ResponseWriter, _ map[string]any) {
+		fmt.Fprintln(w, `{"model":"mistral","message":{"content":"one "},"done":false}`)
+		fmt.Fprintln(w, `{"model":"mistral","message":{"content":"two"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"mistral","message":{"content":""},"done":true,"done_reason":"length","eval_count":2}`)
+	})
+
+	var tokens []string
+	resp, err := NewClient(Config{Type: "ollama", Endpoint: server.URL + "/", Model: "mistral"}, server.Client()).
+		Complete(context.Background(), Request{Prompt: "hi", OnToken: func(token string) { tokens = append(tokens, token) }})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "one two" || len(tokens) != 2 || !resp.Truncated || resp.CompletionTokens != 2 {
+		t.Fatalf("unexpected streamed response: %+v tokens=%q", resp, tokens)
+	}
+}
+
+func TestComplete_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{name: "openai error object", status: http.StatusUnauthorized, body: `{"error":{"message":"invalid api key"}}`, want: "HTTP 401: invalid api key"},
+		{name: "ollama error string", status: http.StatusNotFound, body: `{"error":"model \"x\" not found"}`, want: `HTTP 404: model "x" not found`},
+		{name: "plain body", status: http.StatusBadGateway, body: "upstream down", want: "HTTP 502: upstream down"},
+		{name: "no choices", status: http.StatusOK, body: `{"model":"m","choices":[]}`, want: ErrNoCompletion.Error()},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		}))
+		_, err := NewClient(Config{Type: "openai", Endpoint: server.URL, Model: "m"}, server.Client()).
+			Complete(context.Background(), Request{Prompt: "hi"})
+		server.Close()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+		var statusErr *StatusError
+		if tt.status != http.StatusOK && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.status) {
+			t.Errorf("%s: expected a *StatusError with code %d, got %v", tt.name, tt.status, err)
+		}
+	}
+}
+
+func TestComplete_ContextCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewClient(Config{Type: "openai", Endpoint: server.URL, Model: "m"}, server.Client()).
+		Complete(ctx, Request{Prompt: "hi"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestComplete_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	endpoint := server.URL
+	server.Close()
+
+	_, err := NewClient(Config{Type: "openai", Endpoint: endpoint, Model: "m"}, nil).
+		Complete(context.Background(), Request{Prompt: "hi"})
+	if !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("expected ErrUnreachable, got %v", err)
+	}
+}
+
+func TestIsCloud(t *testing.T) {
+	tests := []struct {
+		providerType string
+		endpoint     string
+		want         bool
+	}{
+		{providerType: "ollama", want: false},
+		{providerType: "openai", want: true},
+		{providerType: "custom", want: true},
+		{providerType: "openai", endpoint: "http://localhost:8080/v1", want: false},
+		{providerType: "ollama", endpoint: "http://[::1]:11434", want: false},
+		{providerType: "ollama", endpoint: "http://gpu-box.lan:11434/v1", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := IsCloud(tt.providerType, tt.endpoint); got != tt.want {
+			t.Errorf("IsCloud(%q, %q) = %v, want %v", tt.providerType, tt.endpoint, got, tt.want)
+		}
+	}
+}
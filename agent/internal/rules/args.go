@@ -0,0 +1,29 @@
+package rules
+
+import "regexp"
+
+var matchPlaceholderRe = regexp.MustCompile(`\{\{\s*match\.([A-Za-z0-9_]+)\s*\}\}`)
+
+// Args renders the action's args templates with the match's captures.
+func (m Match) Args() []string {
+	return RenderArgs(m.Config.Args, m.Captures)
+}
+
+// RenderArgs expands {{match.<group>}} placeholders in each template with the
+// named regex capture of the same name. A group that did not participate in
+// the match expands to "". An argument that renders empty is kept, so the
+// arguments after it keep their positions.
+func RenderArgs(templates []string, captures map[string]string) []string {
+	if len(templates) == 0 {
+		return nil
+	}
+	args := make([]string, 0, len(templates))
+	for _, template := range templates {
+		rendered := matchPlaceholderRe.ReplaceAllStringFunc(template, func(placeholder string) string {
+			name := matchPlaceholderRe.FindStringSubmatch(placeholder)[1]
+			return captures[name]
+		})
+		args = append(args, rendered)
+	}
+	return args
+}
@@ -0,0 +1,45 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+func TestRenderArgs(t *testing.T) {
+	captures := map[string]string{"lang": "Spanish", "tone": "formal"}
+
+	tests := []struct {
+		name      string
+		templates []string
+		want      []string
+	}{
+		{name: "no templates", templates: nil, want: nil},
+		{name: "literal", templates: []string{"French"}, want: []string{"French"}},
+		{name: "placeholder", templates: []string{"{{match.lang}}"}, want: []string{"Spanish"}},
+		{name: "spaced placeholder", templates: []string{"{{ match.lang }}"}, want: []string{"Spanish"}},
+		{name: "embedded", templates: []string{"--tone={{match.tone}}", "{{match.lang}}"}, want: []string{"--tone=formal", "Spanish"}},
+		{name: "missing group kept empty", templates: []string{"{{match.missing}}", "{{match.lang}}"}, want: []string{"", "Spanish"}},
+		{name: "positions stable", templates: []string{"{{match.missing}}", "--to", "fr"}, want: []string{"", "--to", "fr"}},
+		{name: "other braces untouched", templates: []string{"{{input}}"}, want: []string{"{{input}}"}},
+	}
+
+	for _, tt := range tests {
+		got := RenderArgs(tt.templates, captures)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: RenderArgs(%q) = %q, want %q", tt.name, tt.templates, got, tt.want)
+		}
+	}
+}
+
+func TestMatchArgs_UsesCaptures(t *testing.T) {
+	match := Match{
+		ActionName: "translate",
+		Config:     config.ActionConfig{Args: []string{"{{match.lang}}"}},
+		Captures:   map[string]string{"lang": "German"},
+	}
+	if got := match.Args(); !reflect.DeepEqual(got, []string{"German"}) {
+		t.Fatalf("expected [German], got %q", got)
+	}
+}
@@ -5,77 +5,405 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/clipboard-ai/agent/internal/clipboard"
 	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/script"
 )
 
 var lengthExprRe = regexp.MustCompile(`^length\s*(>=|<=|!=|==|=|>|<)\s*(-?\d+)\s*$`)
 
 // Engine evaluates trigger rules against clipboard content
 type Engine struct {
-	actions map[string]config.ActionConfig
-	regexes map[string]*regexp.Regexp
+	actions    map[string]config.ActionConfig
+	triggers   map[string]string // action name -> trigger with @macros expanded
+	regexes    map[string]*regexp.Regexp
+	schedules  map[string]schedule
+	sequences  map[string]sequence
+	predicates map[string]structuredPredicate
+	languages  map[string]string          // lang: condition -> canonical language
+	scripts    map[string]*script.Program // action name -> compiled trigger_script
+	routes     map[string][]compiledRoute // action name -> routes, in config order
+	// repeaters marks actions whose trigger uses a repeat: condition; they are
+	// the only ones EvaluateRepeats considers.
+	repeaters map[string]bool
+	history   *eventHistory
+	ignore    []ignoreRule // settings.ignore, checked by Ignored
+	// current is the event being evaluated and payloads its lazily parsed
+	// structured forms, kept for the next call on the same content; both
+	// guarded by history.mu.
+	current  historyEvent
+	payloads *payloadCache
+	now      func() time.Time
+}
+
+// ignoreRule is a settings.ignore expression, kept alongside its macro
+// expansion so logs can name the rule as the user wrote it.
+type ignoreRule struct {
+	source   string
+	expanded string
+}
+
+// compiledRoute is an action's routes entry with macros in When expanded.
+type compiledRoute struct {
+	config.RouteConfig
+	index    int
+	expanded string
+}
+
+// Route is the routes entry chosen for an action's input.
+type Route struct {
+	Index    int    `json:"index"` // position in the action's routes list
+	When     string `json:"when"`
+	Model    string `json:"model,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// Overrides returns the model and endpoint overrides to run action with: the
+// route's where it sets them, otherwise the action's own. r may be nil.
+func (r *Route) Overrides(action config.ActionConfig) (model string, endpoint string) {
+	model, endpoint = action.Model, action.Endpoint
+	if r == nil {
+		return model, endpoint
+	}
+	if r.Model != "" {
+		model = r.Model
+	}
+	if r.Endpoint != "" {
+		endpoint = r.Endpoint
+	}
+	return model, endpoint
 }
 
 // Match represents a triggered action
 type Match struct {
 	ActionName string
 	Config     config.ActionConfig
+	// Captures holds the named capture groups of the regex: conditions that
+	// matched (outside a NOT) while evaluating the trigger. Later conditions
+	// overwrite earlier ones with the same group name.
+	Captures map[string]string
+	// Route is the first of the action's routes whose When matched the
+	// content, or nil.
+	Route *Route
 }
 
-// NewEngine creates a new rules engine. Regex operands are compiled only for
-// ENABLED actions, and a single invalid pattern is logged and skipped rather
-// than aborting construction — one bad trigger must not stop the daemon.
+// NewEngine creates a new rules engine. Regex operands and time:/weekday:
+// schedules are compiled only for ENABLED actions, and a single invalid
+// condition is logged and skipped rather than aborting construction — one bad
+// trigger must not stop the daemon.
 func NewEngine(actions map[string]config.ActionConfig) (*Engine, error) {
-	e := &Engine{actions: actions, regexes: make(map[string]*regexp.Regexp)}
+	return NewEngineWithTriggers(actions, nil)
+}
+
+// NewEngineWithTriggers creates a rules engine whose action triggers may
+// reference the named [triggers] macros as @name. Macros are expanded once
+// here; an action whose trigger fails to expand is logged and never matches
+// (config validation normally rejects such configs before they get here).
+func NewEngineWithTriggers(actions map[string]config.ActionConfig, macros map[string]string) (*Engine, error) {
+	return newEngine(actions, macros, nil)
+}
+
+// newEngine creates a rules engine like NewEngineWithTriggers, using the
+// trigger scripts in scripts that config validation already compiled and
+// compiling any others.
+func newEngine(actions map[string]config.ActionConfig, macros map[string]string, scripts map[string]*script.Program) (*Engine, error) {
+	e := &Engine{
+		actions:    actions,
+		triggers:   make(map[string]string, len(actions)),
+		regexes:    make(map[string]*regexp.Regexp),
+		schedules:  make(map[string]schedule),
+		sequences:  make(map[string]sequence),
+		predicates: make(map[string]structuredPredicate),
+		languages:  make(map[string]string),
+		scripts:    make(map[string]*script.Program),
+		routes:     make(map[string][]compiledRoute),
+		repeaters:  make(map[string]bool),
+		history:    &eventHistory{},
+		now:        time.Now,
+	}
 	for actionName, action := range actions {
+		// Routes also apply to manual /action runs, so they are compiled
+		// for disabled actions too.
+		e.compileRoutes(actionName, action.Routes, macros)
 		if !action.Enabled {
 			continue
 		}
-		for _, pattern := range e.regexOperands(action.Trigger) {
-			if _, ok := e.regexes[pattern]; ok {
-				continue
+		trigger, err := config.ExpandTrigger(action.Trigger, macros)
+		if err != nil {
+			slog.Warn("skipping trigger with invalid macro reference",
+				"action", actionName,
+				"error", err,
+			)
+			continue
+		}
+		if strings.TrimSpace(action.TriggerScript) != "" {
+			program := scripts[actionName]
+			if program == nil {
+				var err error
+				if program, err = script.Compile(actionName, action.TriggerScript); err != nil {
+					slog.Warn("skipping action with invalid trigger script",
+						"action", actionName,
+						"error", err,
+					)
+					continue
+				}
 			}
-			compiled, err := regexp.Compile(pattern)
-			if err != nil {
-				slog.Warn("skipping invalid regex trigger",
-					"action", actionName,
-					"pattern", pattern,
-					"error", err,
-				)
-				continue
+			e.scripts[actionName] = program
+		}
+		e.triggers[actionName] = trigger
+		for _, cond := range e.conditions(trigger) {
+			e.compileCondition(actionName, cond)
+			if strings.HasPrefix(cond, "repeat:") {
+				e.repeaters[actionName] = true
 			}
-			e.regexes[pattern] = compiled
 		}
 	}
 
 	return e, nil
 }
 
-// regexOperands walks a trigger expression and returns the operands of all
-// regex: conditions, using the same quote-aware parser as evaluation.
-func (e *Engine) regexOperands(trigger string) []string {
+// NewEngineFromConfig creates a rules engine for cfg: its actions, [triggers]
+// macros and settings.ignore rules. An ignore rule that fails to expand is
+// logged and skipped.
+func NewEngineFromConfig(cfg *config.Config) (*Engine, error) {
+	e, err := newEngine(cfg.Actions, cfg.Triggers, cfg.Scripts)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range cfg.Settings.Ignore {
+		expanded, err := config.ExpandTrigger(rule, cfg.Triggers)
+		if err != nil || strings.TrimSpace(expanded) == "" {
+			slog.Warn("skipping invalid ignore rule", "rule", rule, "error", err)
+			continue
+		}
+		e.ignore = append(e.ignore, ignoreRule{source: rule, expanded: expanded})
+		for _, cond := range e.conditions(expanded) {
+			e.compileCondition("settings.ignore", cond)
+		}
+	}
+	return e, nil
+}
+
+// compileRoutes expands and compiles an action's routes. A route whose When
+// fails to expand is logged and skipped.
+func (e *Engine) compileRoutes(actionName string, routes []config.RouteConfig, macros map[string]string) {
+	for i, route := range routes {
+		expanded, err := config.ExpandTrigger(route.When, macros)
+		if err != nil || strings.TrimSpace(expanded) == "" {
+			slog.Warn("skipping invalid route",
+				"action", actionName,
+				"route", i,
+				"error", err,
+			)
+			continue
+		}
+		e.routes[actionName] = append(e.routes[actionName], compiledRoute{RouteConfig: route, index: i, expanded: expanded})
+		for _, cond := range e.conditions(expanded) {
+			e.compileCondition(actionName, cond)
+		}
+	}
+}
+
+// SetClock replaces the clock used by time:/weekday: and repeat:/after:
+// conditions, so their evaluation is deterministic in tests.
+func (e *Engine) SetClock(now func() time.Time) {
+	e.now = now
+}
+
+// InheritState carries the clipboard event history used by repeat:/after:
+// conditions over from the engine this one replaces, so a config hot-reload
+// doesn't reset a gesture in progress.
+func (e *Engine) InheritState(previous *Engine) {
+	if previous != nil && previous.history != nil {
+		e.history = previous.history
+	}
+}
+
+// compileCondition prepares the compiled form of a single condition, logging
+// and skipping it when invalid (it then never matches).
+func (e *Engine) compileCondition(actionName string, cond string) {
+	if pattern, ok := strings.CutPrefix(cond, "regex:"); ok {
+		if _, ok := e.regexes[pattern]; ok {
+			return
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			slog.Warn("skipping invalid regex trigger",
+				"action", actionName,
+				"pattern", pattern,
+				"error", err,
+			)
+			return
+		}
+		e.regexes[pattern] = compiled
+		return
+	}
+
+	if isScheduleCondition(cond) {
+		if _, ok := e.schedules[cond]; ok {
+			return
+		}
+		parsed, err := parseSchedule(cond)
+		if err != nil {
+			slog.Warn("skipping invalid schedule trigger",
+				"action", actionName,
+				"condition", cond,
+				"error", err,
+			)
+			return
+		}
+		e.schedules[cond] = parsed
+		return
+	}
+
+	if isSequenceCondition(cond) {
+		if _, ok := e.sequences[cond]; ok {
+			return
+		}
+		parsed, err := parseSequence(cond)
+		if err != nil {
+			slog.Warn("skipping invalid sequence trigger",
+				"action", actionName,
+				"condition", cond,
+				"error", err,
+			)
+			return
+		}
+		e.sequences[cond] = parsed
+		return
+	}
+
+	if structuredFormatOf(cond) != "" {
+		if _, ok := e.predicates[cond]; ok {
+			return
+		}
+		parsed, err := parseStructuredPredicate(cond)
+		if err != nil {
+			slog.Warn("skipping invalid structured-data trigger",
+				"action", actionName,
+				"condition", cond,
+				"error", err,
+			)
+			return
+		}
+		e.predicates[cond] = parsed
+		return
+	}
+
+	if isLanguageCondition(cond) {
+		if _, ok := e.languages[cond]; ok {
+			return
+		}
+		language, err := parseLanguage(cond)
+		if err != nil {
+			slog.Warn("skipping invalid language trigger",
+				"action", actionName,
+				"condition", cond,
+				"error", err,
+			)
+			return
+		}
+		e.languages[cond] = language
+	}
+}
+
+// conditions walks a trigger expression and returns all of its conditions,
+// using the same quote-aware parser as evaluation.
+func (e *Engine) conditions(trigger string) []string {
 	var collected []string
-	p := triggerParser{input: strings.TrimSpace(trigger), engine: e, collectRegex: &collected}
+	p := triggerParser{input: strings.TrimSpace(trigger), engine: e, collect: &collected}
 	p.parseExpr()
 	return collected
 }
 
-// Evaluate checks all rules against content and returns matches
+// Evaluate checks all rules against content and returns matches. The content
+// is then recorded in the event history used by repeat:/after: conditions.
 func (e *Engine) Evaluate(content clipboard.Content) []Match {
+	return e.evaluate(content, false)
+}
+
+// EvaluateRepeats is Evaluate restricted to actions whose trigger uses a
+// repeat: condition. The handler calls it for content the clipboard dedupe
+// window suppresses: such a duplicate is exactly what completes a repeat:
+// gesture, but must not re-fire every other action.
+func (e *Engine) EvaluateRepeats(content clipboard.Content) []Match {
+	return e.evaluate(content, true)
+}
+
+// Ignored reports whether content matches one of the settings.ignore rules,
+// returning the first matching rule as written in the config. Ignored content
+// is not recorded in the event history.
+func (e *Engine) Ignored(content clipboard.Content) (string, bool) {
+	if len(e.ignore) == 0 {
+		return "", false
+	}
+
+	e.history.mu.Lock()
+	defer e.history.mu.Unlock()
+	e.beginEvent(content)
+
+	for _, rule := range e.ignore {
+		if matched, _ := e.checkTrigger(rule.expanded, content); matched {
+			return rule.source, true
+		}
+	}
+	return "", false
+}
+
+// Route returns the first of the action's routes whose When matches content,
+// or nil. Like Ignored it does not record content in the event history; it
+// serves manual runs, while Evaluate sets Match.Route itself.
+func (e *Engine) Route(actionName string, content clipboard.Content) *Route {
+	if len(e.routes[actionName]) == 0 {
+		return nil
+	}
+
+	e.history.mu.Lock()
+	defer e.history.mu.Unlock()
+	e.beginEvent(content)
+	return e.selectRoute(actionName, content)
+}
+
+// selectRoute picks the action's route for the current event. Caller holds
+// history.mu.
+func (e *Engine) selectRoute(actionName string, content clipboard.Content) *Route {
+	for _, route := range e.routes[actionName] {
+		if matched, _ := e.checkTrigger(route.expanded, content); matched {
+			return &Route{Index: route.index, When: route.When, Model: route.Model, Endpoint: route.Endpoint}
+		}
+	}
+	return nil
+}
+
+func (e *Engine) evaluate(content clipboard.Content, repeatsOnly bool) []Match {
 	var matches []Match
 
+	e.history.mu.Lock()
+	defer e.history.mu.Unlock()
+	e.beginEvent(content)
+	defer e.history.record(e.current)
+
 	for name, action := range e.actions {
 		if !action.Enabled {
 			continue
 		}
+		if repeatsOnly && !e.repeaters[name] {
+			continue
+		}
 
-		if e.checkTrigger(action.Trigger, content) {
+		trigger, ok := e.triggers[name]
+		if !ok {
+			continue
+		}
+		if matched, captures := e.checkAction(name, trigger, content); matched {
 			matches = append(matches, Match{
 				ActionName: name,
 				Config:     action,
+				Captures:   captures,
+				Route:      e.selectRoute(name, content),
 			})
 		}
 	}
@@ -83,25 +411,77 @@ func (e *Engine) Evaluate(content clipboard.Content) []Match {
 	return matches
 }
 
-// checkTrigger evaluates a trigger expression
-func (e *Engine) checkTrigger(trigger string, content clipboard.Content) bool {
+// beginEvent sets content as the event conditions are evaluated against.
+// The parsed payloads carry over while the content is the same, so Ignored
+// and then Evaluate on one clipboard event parse it once. Caller holds
+// history.mu.
+func (e *Engine) beginEvent(content clipboard.Content) {
+	signature := content.Signature
+	if signature == "" {
+		signature = content.Text
+	}
+	e.current = historyEvent{signature: signature, contentType: content.Type, at: e.now()}
+	if e.payloads == nil || e.payloads.text != content.Text {
+		e.payloads = newPayloadCache(content.Text)
+	}
+}
+
+// checkAction evaluates an action's trigger expression and, when it has one,
+// its trigger_script. An action with only a script is decided by the script;
+// with both, both must match. A script runtime error is logged and counts as
+// no match.
+func (e *Engine) checkAction(name string, trigger string, content clipboard.Content) (bool, map[string]string) {
+	program := e.scripts[name]
+
+	matched, captures := true, map[string]string(nil)
+	if program == nil || strings.TrimSpace(trigger) != "" {
+		matched, captures = e.checkTrigger(trigger, content)
+	}
+	if !matched || program == nil {
+		return matched, captures
+	}
+
+	scriptMatched, err := program.Match(content)
+	if err != nil {
+		slog.Warn("trigger script failed",
+			"action", name,
+			"error", err,
+		)
+		return false, nil
+	}
+	if !scriptMatched {
+		return false, nil
+	}
+	return true, captures
+}
+
+// checkTrigger evaluates a trigger expression, returning the named regex
+// capture groups collected along the way when it matches.
+func (e *Engine) checkTrigger(trigger string, content clipboard.Content) (bool, map[string]string) {
 	trigger = strings.TrimSpace(trigger)
 	if trigger == "" {
-		return false
+		return false, nil
 	}
 
 	parser := triggerParser{
-		input:   trigger,
-		engine:  e,
-		content: content,
+		input:    trigger,
+		engine:   e,
+		content:  content,
+		captures: make(map[string]string),
 	}
 	result, ok := parser.parseExpr()
 	if !ok {
-		return false
+		return false, nil
 	}
 
 	parser.skipSpaces()
-	return parser.pos == len(parser.input) && result
+	if parser.pos != len(parser.input) || !result {
+		return false, nil
+	}
+	if len(parser.captures) == 0 {
+		return true, nil
+	}
+	return true, parser.captures
 }
 
 type triggerParser struct {
@@ -109,9 +489,13 @@ type triggerParser struct {
 	pos     int
 	engine  *Engine
 	content clipboard.Content
-	// When set, conditions are not evaluated; regex: operands are collected here
-	// (used by NewEngine to compile patterns up front).
-	collectRegex *[]string
+	// captures receives named groups from matching regex: conditions; negated
+	// is true while parsing the operand of a NOT, whose captures are ignored.
+	captures map[string]string
+	negated  bool
+	// When set, conditions are not evaluated but collected here (used by
+	// NewEngine to compile regex patterns and schedules up front).
+	collect *[]string
 }
 
 func (p *triggerParser) parseExpr() (bool, bool) {
@@ -156,7 +540,9 @@ func (p *triggerParser) parseAnd() (bool, bool) {
 
 func (p *triggerParser) parseUnary() (bool, bool) {
 	if p.consumeKeyword("NOT") {
+		p.negated = !p.negated
 		result, ok := p.parseUnary()
+		p.negated = !p.negated
 		if !ok {
 			return false, false
 		}
@@ -184,13 +570,17 @@ func (p *triggerParser) parsePrimary() (bool, bool) {
 	if !ok {
 		return false, false
 	}
-	if p.collectRegex != nil {
-		if operand, ok := strings.CutPrefix(cond, "regex:"); ok {
-			*p.collectRegex = append(*p.collectRegex, operand)
-		}
+	if p.collect != nil {
+		*p.collect = append(*p.collect, cond)
 		return true, true
 	}
-	return p.engine.evaluateCondition(cond, p.content), true
+	matched := p.engine.evaluateCondition(cond, p.content)
+	if matched && !p.negated && p.captures != nil {
+		if pattern, ok := strings.CutPrefix(cond, "regex:"); ok {
+			p.engine.collectCaptures(pattern, p.content.Text, p.captures)
+		}
+	}
+	return matched, true
 }
 
 func (p *triggerParser) readCondition() (string, bool) {
@@ -205,6 +595,12 @@ func (p *triggerParser) readCondition() (string, bool) {
 		}
 	}
 
+	// json:/yaml:/csv: predicates contain their own parentheses and quoted
+	// literals, e.g. json:has($.stack) or json:$.msg == "a AND b".
+	if structuredFormatOf(p.input[p.pos:]) != "" {
+		return p.readStructuredCondition()
+	}
+
 	start := p.pos
 	for p.pos < len(p.input) {
 		if p.input[p.pos] == ')' {
@@ -258,6 +654,41 @@ func (p *triggerParser) readOperandCondition(prefix string) (string, bool) {
 	return prefix + operand, true
 }
 
+// readStructuredCondition reads a json:/yaml:/csv: condition up to the next
+// AND/OR keyword or unbalanced ")" outside quotes and brackets.
+func (p *triggerParser) readStructuredCondition() (string, bool) {
+	start := p.pos
+	depth := 0
+	var quote byte
+	for p.pos < len(p.input) {
+		ch := p.input[p.pos]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote == '"' && p.pos+1 < len(p.input) {
+				p.pos++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			if depth == 0 {
+				return strings.TrimSpace(p.input[start:p.pos]), p.pos > start
+			}
+			depth--
+		case depth == 0 && (p.peekKeyword("AND") || p.peekKeyword("OR")):
+			return strings.TrimSpace(p.input[start:p.pos]), true
+		}
+		p.pos++
+	}
+	if quote != 0 {
+		return "", false // unterminated quote
+	}
+	return strings.TrimSpace(p.input[start:p.pos]), true
+}
+
 func (p *triggerParser) skipSpaces() {
 	for p.pos < len(p.input) && isSpace(p.input[p.pos]) {
 		p.pos++
@@ -346,9 +777,51 @@ func (e *Engine) evaluateCondition(cond string, content clipboard.Content) bool
 		return string(content.Type) == mimeType
 	}
 
+	// time:HH:MM-HH:MM / weekday:mon-fri, optionally followed by tz:<zone>
+	if isScheduleCondition(cond) {
+		compiled, ok := e.schedules[cond]
+		return ok && compiled.matches(e.now())
+	}
+
+	// json:/yaml:/csv: JSONPath-style predicates over the parsed payload
+	if structuredFormatOf(cond) != "" {
+		compiled, ok := e.predicates[cond]
+		return ok && e.payloads != nil && compiled.matches(e.payloads)
+	}
+
+	// lang:go — heuristic programming-language detection
+	if isLanguageCondition(cond) {
+		language, ok := e.languages[cond]
+		return ok && e.payloads != nil && e.payloads.detectedLanguage() == language
+	}
+
+	// repeat:N within:D / after:type within:D (history.mu is held by evaluate)
+	if isSequenceCondition(cond) {
+		compiled, ok := e.sequences[cond]
+		return ok && compiled.matches(e.history.events, e.current)
+	}
+
 	return false
 }
 
+// collectCaptures copies the named capture groups of pattern's first match in
+// text into captures.
+func (e *Engine) collectCaptures(pattern string, text string, captures map[string]string) {
+	compiled, ok := e.regexes[pattern]
+	if !ok {
+		return
+	}
+	submatches := compiled.FindStringSubmatch(text)
+	if submatches == nil {
+		return
+	}
+	for i, name := range compiled.SubexpNames() {
+		if name != "" && i < len(submatches) {
+			captures[name] = submatches[i]
+		}
+	}
+}
+
 // checkLength evaluates length comparisons
 func (e *Engine) checkLength(cond string, text string) bool {
 	length := utf8.RuneCountInString(text)
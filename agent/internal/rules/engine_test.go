@@ -2,10 +2,12 @@ package rules
 
 import (
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/clipboard-ai/agent/internal/clipboard"
 	"github.com/clipboard-ai/agent/internal/config"
+	"github.com/clipboard-ai/agent/internal/script"
 )
 
 func makeContent(text string, contentType clipboard.ContentType) clipboard.Content {
@@ -393,3 +395,268 @@ func TestEvaluate_MultipleActions(t *testing.T) {
 		t.Fatalf("expected second match 'action_b', got %q", matches[1].ActionName)
 	}
 }
+
+func TestNewEngineWithTriggers_ExpandsMacros(t *testing.T) {
+	engine, err := NewEngineWithTriggers(map[string]config.ActionConfig{
+		"short_url": {Enabled: true, Trigger: "@is_url AND length < 30"},
+		"email":     {Enabled: true, Trigger: `regex:^\w+@\w+\.com$`},
+	}, map[string]string{
+		"is_url": `regex:"^https?://\S+$"`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	matches := engine.Evaluate(makeContent("https://example.com", clipboard.ContentTypeURL))
+	if len(matches) != 1 || matches[0].ActionName != "short_url" {
+		t.Fatalf("expected short_url to match a short URL, got %+v", matches)
+	}
+	if len(engine.Evaluate(makeContent("https://example.com/"+strings.Repeat("a", 40), clipboard.ContentTypeURL))) != 0 {
+		t.Fatal("expected no match for a long URL")
+	}
+	if len(engine.Evaluate(makeContent("me@example.com", clipboard.ContentTypeText))) != 1 {
+		t.Fatal("an @ inside a regex operand must not be treated as a macro reference")
+	}
+}
+
+func TestNewEngineWithTriggers_UnknownMacroIsSkippedNotFatal(t *testing.T) {
+	engine, err := NewEngineWithTriggers(map[string]config.ActionConfig{
+		"broken": {Enabled: true, Trigger: "@missing OR length > 0"},
+		"good":   {Enabled: true, Trigger: "length > 0"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error for unknown macro, got %v", err)
+	}
+
+	matches := engine.Evaluate(makeContent("hello", clipboard.ContentTypeText))
+	if len(matches) != 1 || matches[0].ActionName != "good" {
+		t.Fatalf("expected only the valid action to match, got %+v", matches)
+	}
+}
+
+func TestIgnored_MatchesSettingsIgnoreRules(t *testing.T) {
+	engine, err := NewEngineFromConfig(&config.Config{
+		Actions: map[string]config.ActionConfig{
+			"summarize": {Enabled: true, Trigger: "length > 0"},
+		},
+		Triggers: map[string]string{"is_secret": `regex:"^sk-[A-Za-z0-9]+$"`},
+		Settings: config.SettingsConfig{
+			Ignore: []string{"@is_secret", "length > 10000", "mime:image AND time:00:00-06:00 tz:UTC"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	engine.SetClock(fixedClock(t, "2026-03-04T03:00:00Z"))
+
+	tests := []struct {
+		content clipboard.Content
+		rule    string
+	}{
+		{content: makeContent("sk-abc123", clipboard.ContentTypeText), rule: "@is_secret"},
+		{content: makeContent(strings.Repeat("a", 10001), clipboard.ContentTypeText), rule: "length > 10000"},
+		{content: makeContent("", clipboard.ContentTypeImage), rule: "mime:image AND time:00:00-06:00 tz:UTC"},
+		{content: makeContent("hello", clipboard.ContentTypeText)},
+	}
+	for _, tt := range tests {
+		rule, ignored := engine.Ignored(tt.content)
+		if ignored != (tt.rule != "") || rule != tt.rule {
+			t.Errorf("Ignored(%q) = %q, %v; want %q", tt.content.Text, rule, ignored, tt.rule)
+		}
+	}
+}
+
+func TestIgnored_InvalidRuleIsSkippedNotFatal(t *testing.T) {
+	engine, err := NewEngineFromConfig(&config.Config{
+		Settings: config.SettingsConfig{Ignore: []string{"@missing", "contains:password"}},
+	})
+	if err != nil {
+		t.Fatalf("expected no error for invalid ignore rule, got %v", err)
+	}
+
+	if _, ignored := engine.Ignored(makeContent("hello", clipboard.ContentTypeText)); ignored {
+		t.Fatal("an invalid ignore rule must not match")
+	}
+	if rule, ignored := engine.Ignored(makeContent("password: hunter2", clipboard.ContentTypeText)); !ignored || rule != "contains:password" {
+		t.Fatalf("expected the valid rule to still match, got %q, %v", rule, ignored)
+	}
+}
+
+func TestIgnored_DoesNotRecordHistory(t *testing.T) {
+	engine, err := NewEngineFromConfig(&config.Config{
+		Actions: map[string]config.ActionConfig{
+			"double": {Enabled: true, Trigger: "repeat:2 within:10s"},
+		},
+		Settings: config.SettingsConfig{Ignore: []string{"contains:never"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content := makeContent("hello", clipboard.ContentTypeText)
+
+	engine.Ignored(content)
+	if len(engine.Evaluate(content)) != 0 {
+		t.Fatal("checking ignore rules must not count as a copy for repeat:")
+	}
+}
+
+func TestEvaluate_SelectsFirstMatchingRoute(t *testing.T) {
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"explain": {
+			Enabled: true,
+			Trigger: "length > 0",
+			Model:   "mistral",
+			Routes: []config.RouteConfig{
+				{When: "length > 40", Model: "llama3.1:70b"},
+				{When: "lang:go", Model: "qwen2.5-coder", Endpoint: "http://localhost:11435/v1"},
+			},
+		},
+	})
+
+	tests := []struct {
+		text         string
+		wantRoute    int
+		wantModel    string
+		wantEndpoint string
+	}{
+		{text: "package main\n\nfunc main() {\n\tx := 1\n}", wantRoute: 1, wantModel: "qwen2.5-coder", wantEndpoint: "http://localhost:11435/v1"},
+		{text: "package main\n\nfunc main() {\n\tvalue := compute()\n\tfmt.Println(value)\n}", wantRoute: 0, wantModel: "llama3.1:70b"},
+		{text: "hello", wantRoute: -1, wantModel: "mistral"},
+	}
+	for _, tt := range tests {
+		matches := engine.Evaluate(makeContent(tt.text, clipboard.ContentTypeCode))
+		if len(matches) != 1 {
+			t.Fatalf("%q: expected 1 match, got %d", tt.text, len(matches))
+		}
+		route := matches[0].Route
+		if tt.wantRoute < 0 && route != nil || tt.wantRoute >= 0 && (route == nil || route.Index != tt.wantRoute) {
+			t.Errorf("%q: expected route %d, got %+v", tt.text, tt.wantRoute, route)
+		}
+		model, endpoint := route.Overrides(matches[0].Config)
+		if model != tt.wantModel || endpoint != tt.wantEndpoint {
+			t.Errorf("%q: expected %q/%q, got %q/%q", tt.text, tt.wantModel, tt.wantEndpoint, model, endpoint)
+		}
+	}
+}
+
+func TestRoute_AppliesToDisabledActionsAndSkipsInvalidRoutes(t *testing.T) {
+	engine, err := NewEngineWithTriggers(map[string]config.ActionConfig{
+		"summarize": {
+			Enabled: false,
+			Routes: []config.RouteConfig{
+				{When: "@missing", Model: "broken"},
+				{When: "@long", Model: "llama3.1:70b"},
+			},
+		},
+	}, map[string]string{"long": "length > 10"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	route := engine.Route("summarize", makeContent("well over ten characters", clipboard.ContentTypeText))
+	if route == nil || route.Index != 1 || route.Model != "llama3.1:70b" || route.When != "@long" {
+		t.Fatalf("expected the @long route, got %+v", route)
+	}
+	if route := engine.Route("summarize", makeContent("short", clipboard.ContentTypeText)); route != nil {
+		t.Fatalf("expected no route for short input, got %+v", route)
+	}
+}
+
+func TestEvaluate_RegexNamedCapturesOnMatch(t *testing.T) {
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"translate": {Enabled: true, Trigger: `regex:"^translate to (?P<lang>\w+):" AND length > 5`},
+	})
+
+	matches := engine.Evaluate(makeContent("translate to Spanish: good morning", clipboard.ContentTypeText))
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matches))
+	}
+	if got := matches[0].Captures["lang"]; got != "Spanish" {
+		t.Fatalf("expected lang capture 'Spanish', got %q", got)
+	}
+}
+
+func TestEvaluate_RegexCapturesIgnoredUnderNOTAndFailedMatch(t *testing.T) {
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"negated": {Enabled: true, Trigger: `length > 0 AND NOT regex:"(?P<word>secret)" OR regex:"(?P<n>\d+)"`},
+		"plain":   {Enabled: true, Trigger: `length > 0 OR regex:"(?P<n>\d+)"`},
+	})
+
+	for _, match := range engine.Evaluate(makeContent("secret 42", clipboard.ContentTypeText)) {
+		if _, ok := match.Captures["word"]; ok {
+			t.Fatalf("%s: captures from a negated regex must be ignored", match.ActionName)
+		}
+		if match.Captures["n"] != "42" {
+			t.Fatalf("%s: expected n capture '42', got %q", match.ActionName, match.Captures["n"])
+		}
+	}
+
+	for _, match := range engine.Evaluate(makeContent("no digits", clipboard.ContentTypeText)) {
+		if match.Captures != nil {
+			t.Fatalf("%s: expected no captures, got %v", match.ActionName, match.Captures)
+		}
+	}
+}
+
+func TestEvaluate_TriggerScript(t *testing.T) {
+	script := `
+def match(content):
+    return content.text.startswith("CORE-") and content.text[5:].isdigit()
+`
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"script_only":    {Enabled: true, TriggerScript: script},
+		"script_and_dsl": {Enabled: true, Trigger: "length > 7", TriggerScript: script},
+	})
+
+	names := func(text string) map[string]bool {
+		found := map[string]bool{}
+		for _, m := range engine.Evaluate(makeContent(text, clipboard.ContentTypeText)) {
+			found[m.ActionName] = true
+		}
+		return found
+	}
+
+	if got := names("CORE-1234"); !got["script_only"] || !got["script_and_dsl"] {
+		t.Fatalf("expected both actions to match a long key, got %v", got)
+	}
+	if got := names("CORE-1"); !got["script_only"] || got["script_and_dsl"] {
+		t.Fatalf("expected the trigger expression to be ANDed with the script, got %v", got)
+	}
+	if got := names("WEB-1234"); len(got) != 0 {
+		t.Fatalf("expected no match when the script returns False, got %v", got)
+	}
+}
+
+func TestNewEngineFromConfig_UsesCompiledScripts(t *testing.T) {
+	cfg := config.Default()
+	cfg.Actions = map[string]config.ActionConfig{
+		"jira": {Enabled: true, TriggerScript: "def match(content):\n    return False"},
+	}
+	// A program validation compiled is used as is, not compiled again.
+	program, err := script.Compile("jira", "def match(content):\n    return True")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Scripts = map[string]*script.Program{"jira": program}
+
+	engine, err := NewEngineFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matches := engine.Evaluate(makeContent("CORE-1", clipboard.ContentTypeText)); len(matches) != 1 {
+		t.Fatalf("expected the compiled script to match, got %+v", matches)
+	}
+}
+
+func TestEvaluate_TriggerScriptErrorsNeverMatch(t *testing.T) {
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"bad_compile": {Enabled: true, Trigger: "length > 0", TriggerScript: "def match(:"},
+		"bad_runtime": {Enabled: true, TriggerScript: "def match(content):\n    return 1 // 0"},
+		"good":        {Enabled: true, Trigger: "length > 0"},
+	})
+
+	matches := engine.Evaluate(makeContent("hello", clipboard.ContentTypeText))
+	if len(matches) != 1 || matches[0].ActionName != "good" {
+		t.Fatalf("expected only the valid action to match, got %+v", matches)
+	}
+}
@@ -0,0 +1,144 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxLanguageSample bounds how much of the clipboard text language detection
+// looks at, so a multi-megabyte paste costs the same as a large snippet.
+const maxLanguageSample = 64 << 10
+
+// languageAliases maps the names accepted by lang: to the canonical language
+// detectLanguage reports:
+//
+//	lang:go   lang:python   lang:ts   lang:shell
+var languageAliases = map[string]string{
+	"go": "go", "golang": "go",
+	"python": "python", "py": "python",
+	"javascript": "javascript", "js": "javascript",
+	"typescript": "typescript", "ts": "typescript",
+	"rust": "rust", "rs": "rust",
+	"java":  "java",
+	"shell": "shell", "sh": "shell", "bash": "shell",
+	"sql": "sql",
+}
+
+// languageSignals are per-language patterns; each one found in the text scores
+// a point. Patterns are specific enough that prose rarely scores at all.
+var languageSignals = map[string][]*regexp.Regexp{
+	"go": {
+		regexp.MustCompile(`(?m)^package \w+\s*$`),
+		regexp.MustCompile(`\bfunc (?:\([^)]*\) )?\w+\(`),
+		regexp.MustCompile(`\w+ :=`),
+		regexp.MustCompile(`\berr != nil\b`),
+		regexp.MustCompile(`\bfmt\.\w+\(`),
+		regexp.MustCompile(`(?m)^import \($`),
+	},
+	"python": {
+		regexp.MustCompile(`(?m)^\s*def \w+\(.*\)(?:\s*->\s*[^:]+)?:\s*$`),
+		regexp.MustCompile(`(?m)^\s*(?:from [\w.]+ )?import [\w.]+(?: as \w+)?\s*$`),
+		regexp.MustCompile(`\bself\.\w+`),
+		regexp.MustCompile(`(?m)^\s*(?:elif|except)\b.*:\s*$`),
+		regexp.MustCompile(`__name__ == ['"]__main__['"]`),
+		regexp.MustCompile(`\bprint\(`),
+	},
+	"javascript": {
+		regexp.MustCompile(`\b(?:const|let) \w+ = `),
+		regexp.MustCompile(`=> \{?`),
+		regexp.MustCompile(`\bfunction\s*\w*\s*\(`),
+		regexp.MustCompile(`\bconsole\.\w+\(`),
+		regexp.MustCompile(`\brequire\(['"]`),
+		regexp.MustCompile(`\bexport (?:default|const|function)\b`),
+	},
+	"typescript": {
+		regexp.MustCompile(`\binterface \w+ \{`),
+		regexp.MustCompile(`\btype \w+ = `),
+		regexp.MustCompile(`[\w)]: (?:string|number|boolean|any|unknown|void)\b`),
+		regexp.MustCompile(`\bimport (?:type )?\{[^}]*\} from ['"]`),
+	},
+	"rust": {
+		regexp.MustCompile(`\bfn \w+(?:<[^>]*>)?\(`),
+		regexp.MustCompile(`\blet mut \w+`),
+		regexp.MustCompile(`\bimpl(?:<[^>]*>)? \w+`),
+		regexp.MustCompile(`\b(?:println|vec|format)!\(`),
+		regexp.MustCompile(`\buse \w+::`),
+	},
+	"java": {
+		regexp.MustCompile(`\bpublic (?:static )?(?:final )?(?:class|void|interface)\b`),
+		regexp.MustCompile(`\bSystem\.out\.print`),
+		regexp.MustCompile(`@Override\b`),
+		regexp.MustCompile(`\bprivate (?:final )?\w+(?:<[^>]*>)? \w+;`),
+		regexp.MustCompile(`(?m)^import java\.`),
+	},
+	"shell": {
+		regexp.MustCompile(`^#!/(?:usr/)?bin/(?:env )?(?:ba|z)?sh`),
+		regexp.MustCompile(`(?m)^\s*(?:\$ )?(?:sudo|apt(?:-get)?|brew|cd|ls|echo|export|grep|curl|chmod|mkdir|git) \S`),
+		regexp.MustCompile(`\$\{\w+\}|\$\(\w`),
+		regexp.MustCompile(`\| (?:grep|awk|sed|xargs|sort|head|tail)\b`),
+		regexp.MustCompile(`(?m)^\s*(?:fi|done|esac)\s*$`),
+	},
+	"sql": {
+		regexp.MustCompile(`(?im)^\s*(?:select|insert into|update|delete from|create table|alter table)\b`),
+		regexp.MustCompile(`(?i)\bfrom \w+(?:\.\w+)?\b`),
+		regexp.MustCompile(`(?i)\bwhere \w+ (?:=|<>|in|like|is)\b`),
+		regexp.MustCompile(`(?i)\b(?:inner|left|right) join\b`),
+		regexp.MustCompile(`(?i)\bgroup by\b|\border by\b`),
+	},
+}
+
+// minLanguageScore is the number of distinct signals a language needs before
+// detectLanguage reports it.
+const minLanguageScore = 2
+
+// isLanguageCondition reports whether cond is a lang: condition.
+func isLanguageCondition(cond string) bool {
+	return strings.HasPrefix(cond, "lang:")
+}
+
+// parseLanguage returns the canonical language a lang: condition names.
+func parseLanguage(cond string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(cond, "lang:")))
+	language, ok := languageAliases[name]
+	if !ok {
+		return "", fmt.Errorf("unknown language %q", name)
+	}
+	return language, nil
+}
+
+// detectLanguage guesses the programming language of text, returning "" when
+// no language scores at least minLanguageScore or the best score is tied.
+// TypeScript is JavaScript plus type syntax, so it also scores the
+// JavaScript signals.
+func detectLanguage(text string) string {
+	if len(text) > maxLanguageSample {
+		text = text[:maxLanguageSample]
+	}
+
+	scores := make(map[string]int, len(languageSignals))
+	for language, signals := range languageSignals {
+		for _, signal := range signals {
+			if signal.MatchString(text) {
+				scores[language]++
+			}
+		}
+	}
+	if scores["typescript"] > 0 {
+		scores["typescript"] += scores["javascript"]
+	}
+
+	best, bestScore, tied := "", 0, false
+	for language, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = language, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if bestScore < minLanguageScore || tied {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,78 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/clipboard-ai/agent/internal/clipboard"
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "go", text: "package main\n\nfunc main() {\n\tvalue, err := run()\n\tif err != nil {\n\t\tpanic(err)\n\t}\n}", want: "go"},
+		{name: "python", text: "import os\n\ndef main():\n    print(os.getcwd())\n\nif __name__ == \"__main__\":\n    main()", want: "python"},
+		{name: "javascript", text: "const fs = require('fs');\nconst read = (p) => fs.readFileSync(p);\nconsole.log(read('a'));", want: "javascript"},
+		{name: "typescript", text: "interface User {\n  name: string;\n}\nconst greet = (u: User): string => `hi ${u.name}`;", want: "typescript"},
+		{name: "rust", text: "use std::io;\n\nfn main() {\n    let mut line = String::new();\n    println!(\"{}\", line);\n}", want: "rust"},
+		{name: "java", text: "public class Main {\n  public static void main(String[] args) {\n    System.out.println(\"hi\");\n  }\n}", want: "java"},
+		{name: "shell", text: "#!/bin/bash\nexport PATH=\"${HOME}/bin:$PATH\"\nls -la | grep foo", want: "shell"},
+		{name: "sql", text: "SELECT id, name\nFROM users\nWHERE id = 3\nORDER BY name", want: "sql"},
+		{name: "prose", text: "Let me know when you are free; I will return from the trip on Friday.", want: ""},
+		{name: "empty", text: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := detectLanguage(tt.text); got != tt.want {
+			t.Errorf("%s: detectLanguage = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseLanguage(t *testing.T) {
+	tests := []struct {
+		cond    string
+		want    string
+		wantErr bool
+	}{
+		{cond: "lang:go", want: "go"},
+		{cond: "lang:golang", want: "go"},
+		{cond: "lang:TS", want: "typescript"},
+		{cond: "lang:bash", want: "shell"},
+		{cond: "lang:cobol", wantErr: true},
+		{cond: "lang:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLanguage(tt.cond)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseLanguage(%q) = %q, %v; want %q, wantErr %v", tt.cond, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestEvaluate_LangCondition(t *testing.T) {
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"go_review": {Enabled: true, Trigger: "mime:code AND lang:go"},
+		"invalid":   {Enabled: true, Trigger: "lang:cobol"},
+	})
+
+	matches := engine.Evaluate(makeContent("func add(a, b int) int {\n\tsum := a + b\n\treturn sum\n}", clipboard.ContentTypeCode))
+	if len(matches) != 1 || matches[0].ActionName != "go_review" {
+		t.Fatalf("expected go_review to match Go code, got %+v", matches)
+	}
+	if len(engine.Evaluate(makeContent("def add(a, b):\n    return a + b\n\nprint(add(1, 2))", clipboard.ContentTypeCode))) != 0 {
+		t.Fatal("expected no match for Python code")
+	}
+}
+
+func TestDetectLanguage_SamplesLargeInput(t *testing.T) {
+	text := "package main\n\nfunc main() {\n\tx := 1\n}\n" + strings.Repeat("// filler\n", maxLanguageSample)
+	if got := detectLanguage(text); got != "go" {
+		t.Fatalf("expected go from the leading sample, got %q", got)
+	}
+}
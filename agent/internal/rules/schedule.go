@@ -0,0 +1,137 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// schedule is a parsed time: or weekday: condition. Both kinds accept an
+// optional trailing "tz:<IANA zone>" field; without it the engine clock's own
+// location (normally the local zone) is used:
+//
+//	time:09:00-17:30
+//	time:22:00-06:00 tz:Europe/Berlin
+//	weekday:mon-fri
+//	weekday:sat,sun tz:America/New_York
+type schedule struct {
+	// time: window as minutes since midnight, [start, end). end < start wraps
+	// past midnight.
+	hasWindow bool
+	start     int
+	end       int
+	// weekday: set, indexed by time.Weekday.
+	hasDays bool
+	days    [7]bool
+	loc     *time.Location
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// isScheduleCondition reports whether cond is a time: or weekday: condition.
+func isScheduleCondition(cond string) bool {
+	return strings.HasPrefix(cond, "time:") || strings.HasPrefix(cond, "weekday:")
+}
+
+// parseSchedule parses a time: or weekday: condition (see schedule).
+func parseSchedule(cond string) (schedule, error) {
+	var s schedule
+	fields := strings.Fields(cond)
+	if len(fields) == 0 {
+		return s, fmt.Errorf("empty schedule condition")
+	}
+
+	for _, field := range fields[1:] {
+		zone, ok := strings.CutPrefix(field, "tz:")
+		if !ok || s.loc != nil {
+			return s, fmt.Errorf("unexpected %q (only a single tz:<zone> may follow)", field)
+		}
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return s, fmt.Errorf("invalid tz %q: %w", zone, err)
+		}
+		s.loc = loc
+	}
+
+	if window, ok := strings.CutPrefix(fields[0], "time:"); ok {
+		startText, endText, found := strings.Cut(window, "-")
+		if !found {
+			return s, fmt.Errorf("invalid time window %q: expected HH:MM-HH:MM", window)
+		}
+		start, err := parseClock(startText)
+		if err != nil {
+			return s, err
+		}
+		end, err := parseClock(endText)
+		if err != nil {
+			return s, err
+		}
+		if start == end {
+			return s, fmt.Errorf("invalid time window %q: start and end are equal", window)
+		}
+		s.hasWindow, s.start, s.end = true, start, end
+		return s, nil
+	}
+
+	if spec, ok := strings.CutPrefix(fields[0], "weekday:"); ok {
+		for _, part := range strings.Split(strings.ToLower(spec), ",") {
+			fromText, toText, isRange := strings.Cut(part, "-")
+			from, ok := weekdayNames[fromText]
+			if !ok {
+				return s, fmt.Errorf("invalid weekday %q", fromText)
+			}
+			to := from
+			if isRange {
+				if to, ok = weekdayNames[toText]; !ok {
+					return s, fmt.Errorf("invalid weekday %q", toText)
+				}
+			}
+			// Ranges wrap around the week, so fri-mon is fri,sat,sun,mon.
+			for d := from; ; d = (d + 1) % 7 {
+				s.days[d] = true
+				if d == to {
+					break
+				}
+			}
+		}
+		s.hasDays = true
+		return s, nil
+	}
+
+	return s, fmt.Errorf("unknown schedule condition %q", fields[0])
+}
+
+// parseClock parses a 24-hour HH:MM string into minutes since midnight.
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: expected 24-hour HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// matches reports whether now falls inside the schedule.
+func (s schedule) matches(now time.Time) bool {
+	if s.loc != nil {
+		now = now.In(s.loc)
+	}
+	if s.hasDays && !s.days[now.Weekday()] {
+		return false
+	}
+	if s.hasWindow {
+		minute := now.Hour()*60 + now.Minute()
+		if s.start < s.end {
+			return minute >= s.start && minute < s.end
+		}
+		return minute >= s.start || minute < s.end
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/clipboard"
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+func fixedClock(t *testing.T, value string) func() time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("invalid clock value %q: %v", value, err)
+	}
+	return func() time.Time { return parsed }
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		cond    string
+		wantErr bool
+	}{
+		{cond: "time:09:00-17:30"},
+		{cond: "time:22:00-06:00"},
+		{cond: "time:09:00-17:30 tz:Europe/Berlin"},
+		{cond: "weekday:mon-fri"},
+		{cond: "weekday:sat,sun"},
+		{cond: "weekday:Fri-Mon tz:UTC"},
+		{cond: "time:9-17", wantErr: true},
+		{cond: "time:25:00-26:00", wantErr: true},
+		{cond: "time:09:00-09:00", wantErr: true},
+		{cond: "time:09:00", wantErr: true},
+		{cond: "weekday:funday", wantErr: true},
+		{cond: "weekday:mon-", wantErr: true},
+		{cond: "weekday:mon tz:Mars/Olympus", wantErr: true},
+		{cond: "weekday:mon tz:UTC tz:UTC", wantErr: true},
+		{cond: "weekday:mon extra", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		_, err := parseSchedule(tt.cond)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSchedule(%q) error = %v, wantErr %v", tt.cond, err, tt.wantErr)
+		}
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	tests := []struct {
+		cond string
+		now  string
+		want bool
+	}{
+		{cond: "time:09:00-17:30", now: "2026-03-04T09:00:00Z", want: true},
+		{cond: "time:09:00-17:30", now: "2026-03-04T17:29:59Z", want: true},
+		{cond: "time:09:00-17:30", now: "2026-03-04T17:30:00Z", want: false},
+		{cond: "time:09:00-17:30", now: "2026-03-04T08:59:00Z", want: false},
+		{cond: "time:22:00-06:00", now: "2026-03-04T23:15:00Z", want: true},
+		{cond: "time:22:00-06:00", now: "2026-03-04T05:59:00Z", want: true},
+		{cond: "time:22:00-06:00", now: "2026-03-04T12:00:00Z", want: false},
+		// 2026-03-04 is a Wednesday.
+		{cond: "weekday:mon-fri", now: "2026-03-04T12:00:00Z", want: true},
+		{cond: "weekday:mon-fri", now: "2026-03-07T12:00:00Z", want: false},
+		{cond: "weekday:sat,sun", now: "2026-03-08T12:00:00Z", want: true},
+		{cond: "weekday:fri-mon", now: "2026-03-09T12:00:00Z", want: true},
+		{cond: "weekday:fri-mon", now: "2026-03-10T12:00:00Z", want: false},
+		// 08:30 UTC is 09:30 in Berlin (CET, UTC+1).
+		{cond: "time:09:00-17:30 tz:Europe/Berlin", now: "2026-03-04T08:30:00Z", want: true},
+		{cond: "time:09:00-17:30 tz:Europe/Berlin", now: "2026-03-04T16:45:00Z", want: false},
+		// Saturday 02:00 UTC is still Friday evening in New York.
+		{cond: "weekday:mon-fri tz:America/New_York", now: "2026-03-07T02:00:00Z", want: true},
+	}
+
+	for _, tt := range tests {
+		s, err := parseSchedule(tt.cond)
+		if err != nil {
+			t.Fatalf("parseSchedule(%q): %v", tt.cond, err)
+		}
+		now, _ := time.Parse(time.RFC3339, tt.now)
+		if got := s.matches(now); got != tt.want {
+			t.Errorf("%q at %s = %v, want %v", tt.cond, tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluate_ScheduleUsesInjectedClock(t *testing.T) {
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"work": {Enabled: true, Trigger: "length > 3 AND time:09:00-17:30 tz:UTC AND weekday:mon-fri tz:UTC"},
+	})
+	content := makeContent("hello world", clipboard.ContentTypeText)
+
+	engine.SetClock(fixedClock(t, "2026-03-04T10:00:00Z"))
+	if len(engine.Evaluate(content)) != 1 {
+		t.Fatal("expected a match during working hours on a weekday")
+	}
+
+	engine.SetClock(fixedClock(t, "2026-03-04T19:00:00Z"))
+	if len(engine.Evaluate(content)) != 0 {
+		t.Fatal("expected no match after working hours")
+	}
+
+	engine.SetClock(fixedClock(t, "2026-03-07T10:00:00Z"))
+	if len(engine.Evaluate(content)) != 0 {
+		t.Fatal("expected no match on a Saturday")
+	}
+}
+
+func TestEvaluate_NegatedScheduleInGroup(t *testing.T) {
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"after_hours": {Enabled: true, Trigger: "NOT (time:09:00-17:00 tz:UTC) AND contains:alert"},
+	})
+	engine.SetClock(fixedClock(t, "2026-03-04T20:00:00Z"))
+
+	if len(engine.Evaluate(makeContent("alert: disk full", clipboard.ContentTypeText))) != 1 {
+		t.Fatal("expected negated schedule to match outside the window")
+	}
+}
+
+func TestNewEngine_InvalidScheduleIsSkippedNotFatal(t *testing.T) {
+	engine, err := NewEngine(map[string]config.ActionConfig{
+		"invalid": {Enabled: true, Trigger: "time:9am-5pm"},
+		"good":    {Enabled: true, Trigger: "weekday:mon-sun"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error for invalid schedule, got %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, m := range engine.Evaluate(makeContent("hello", clipboard.ContentTypeText)) {
+		names[m.ActionName] = true
+	}
+	if names["invalid"] {
+		t.Fatal("action with an invalid schedule must not match")
+	}
+	if !names["good"] {
+		t.Fatal("valid schedule alongside an invalid one must still match")
+	}
+}
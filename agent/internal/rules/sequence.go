@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/clipboard"
+)
+
+// maxHistoryEvents bounds the clipboard event history kept for repeat:/after:
+// conditions so memory stays constant regardless of copy rate.
+const maxHistoryEvents = 64
+
+// sequence is a parsed stateful condition over recent clipboard events:
+//
+//	repeat:2 within:1500ms   the same content copied N times inside the window
+//	after:url within:10s     the previous copy was of this type, inside the window
+type sequence struct {
+	repeat int
+	after  clipboard.ContentType
+	within time.Duration
+}
+
+// historyEvent is a clipboard event remembered for sequence conditions.
+type historyEvent struct {
+	signature   string
+	contentType clipboard.ContentType
+	at          time.Time
+}
+
+// eventHistory is the bounded record of recent clipboard events. It outlives a
+// single Engine: InheritState hands it to the engine built by a config reload
+// so an in-progress gesture isn't reset.
+type eventHistory struct {
+	mu     sync.Mutex
+	events []historyEvent
+}
+
+// record appends an event, dropping the oldest past maxHistoryEvents. Caller
+// holds mu.
+func (h *eventHistory) record(event historyEvent) {
+	if len(h.events) >= maxHistoryEvents {
+		copy(h.events, h.events[1:])
+		h.events = h.events[:len(h.events)-1]
+	}
+	h.events = append(h.events, event)
+}
+
+// isSequenceCondition reports whether cond is a repeat: or after: condition.
+func isSequenceCondition(cond string) bool {
+	return strings.HasPrefix(cond, "repeat:") || strings.HasPrefix(cond, "after:")
+}
+
+// parseSequence parses a repeat: or after: condition (see sequence).
+func parseSequence(cond string) (sequence, error) {
+	var s sequence
+	fields := strings.Fields(cond)
+	if len(fields) != 2 {
+		return s, fmt.Errorf("expected %q followed by within:<duration>", fields[0])
+	}
+
+	windowText, ok := strings.CutPrefix(fields[1], "within:")
+	if !ok {
+		return s, fmt.Errorf("unexpected %q: expected within:<duration>", fields[1])
+	}
+	window, err := time.ParseDuration(windowText)
+	if err != nil || window <= 0 {
+		return s, fmt.Errorf("invalid within duration %q: expected a positive duration like 1500ms or 10s", windowText)
+	}
+	s.within = window
+
+	if countText, ok := strings.CutPrefix(fields[0], "repeat:"); ok {
+		count, err := strconv.Atoi(countText)
+		if err != nil || count < 2 {
+			return s, fmt.Errorf("invalid repeat count %q: must be an integer of at least 2", countText)
+		}
+		s.repeat = count
+		return s, nil
+	}
+
+	if typeText, ok := strings.CutPrefix(fields[0], "after:"); ok {
+		if typeText == "" {
+			return s, fmt.Errorf("after: requires a content type such as url or code")
+		}
+		s.after = clipboard.ContentType(typeText)
+		return s, nil
+	}
+
+	return s, fmt.Errorf("unknown sequence condition %q", fields[0])
+}
+
+// matches reports whether the current event completes the sequence, given the
+// events recorded before it (oldest first).
+func (s sequence) matches(history []historyEvent, current historyEvent) bool {
+	if s.repeat > 0 {
+		seen := 1
+		for i := len(history) - 1; i >= 0 && seen < s.repeat; i-- {
+			if current.at.Sub(history[i].at) > s.within {
+				break
+			}
+			if history[i].signature == current.signature {
+				seen++
+			}
+		}
+		return seen >= s.repeat
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		previous := history[i]
+		// A re-copy of the same content doesn't count as "the previous copy".
+		if previous.signature == current.signature {
+			continue
+		}
+		return previous.contentType == s.after && current.at.Sub(previous.at) <= s.within
+	}
+	return false
+}
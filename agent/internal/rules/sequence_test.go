@@ -0,0 +1,144 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/clipboard"
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+// steppingClock returns a clock whose time is advanced explicitly by the test.
+func steppingClock() (func() time.Time, func(time.Duration)) {
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestParseSequence(t *testing.T) {
+	tests := []struct {
+		cond    string
+		wantErr bool
+	}{
+		{cond: "repeat:2 within:1500ms"},
+		{cond: "repeat:3 within:5s"},
+		{cond: "after:url within:10s"},
+		{cond: "repeat:1 within:1s", wantErr: true},
+		{cond: "repeat:two within:1s", wantErr: true},
+		{cond: "repeat:2", wantErr: true},
+		{cond: "repeat:2 within:soon", wantErr: true},
+		{cond: "repeat:2 within:-1s", wantErr: true},
+		{cond: "after: within:1s", wantErr: true},
+		{cond: "after:url for:1s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		_, err := parseSequence(tt.cond)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSequence(%q) error = %v, wantErr %v", tt.cond, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEvaluate_RepeatWithinWindow(t *testing.T) {
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"double": {Enabled: true, Trigger: "repeat:2 within:1500ms"},
+	})
+	now, advance := steppingClock()
+	engine.SetClock(now)
+	content := makeContent("deliberate", clipboard.ContentTypeText)
+
+	if len(engine.Evaluate(content)) != 0 {
+		t.Fatal("a single copy must not complete repeat:2")
+	}
+	advance(time.Second)
+	if len(engine.Evaluate(content)) != 1 {
+		t.Fatal("a second copy inside the window should complete repeat:2")
+	}
+	advance(3 * time.Second)
+	if len(engine.Evaluate(makeContent("other", clipboard.ContentTypeText))) != 0 {
+		t.Fatal("different content must not complete repeat:2")
+	}
+	advance(3 * time.Second)
+	if len(engine.Evaluate(content)) != 0 {
+		t.Fatal("a copy outside the window must not complete repeat:2")
+	}
+}
+
+func TestEvaluate_AfterTypeWithinWindow(t *testing.T) {
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"annotate": {Enabled: true, Trigger: "after:url within:10s AND mime:text"},
+	})
+	now, advance := steppingClock()
+	engine.SetClock(now)
+
+	engine.Evaluate(makeContent("https://example.com", clipboard.ContentTypeURL))
+	advance(4 * time.Second)
+	if len(engine.Evaluate(makeContent("a note about it", clipboard.ContentTypeText))) != 1 {
+		t.Fatal("text copied shortly after a URL should match")
+	}
+	advance(2 * time.Second)
+	if len(engine.Evaluate(makeContent("another note", clipboard.ContentTypeText))) != 0 {
+		t.Fatal("the previous copy was text, not a URL")
+	}
+
+	engine.Evaluate(makeContent("https://example.org", clipboard.ContentTypeURL))
+	advance(11 * time.Second)
+	if len(engine.Evaluate(makeContent("too late", clipboard.ContentTypeText))) != 0 {
+		t.Fatal("a copy outside the window must not match")
+	}
+}
+
+func TestEvaluateRepeats_OnlyConsidersRepeatActions(t *testing.T) {
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"double":    {Enabled: true, Trigger: "repeat:2 within:2s"},
+		"summarize": {Enabled: true, Trigger: "length > 3"},
+	})
+	now, advance := steppingClock()
+	engine.SetClock(now)
+	content := makeContent("some text", clipboard.ContentTypeText)
+
+	if matches := engine.Evaluate(content); len(matches) != 1 || matches[0].ActionName != "summarize" {
+		t.Fatalf("expected only summarize on the first copy, got %+v", matches)
+	}
+	advance(500 * time.Millisecond)
+	matches := engine.EvaluateRepeats(content)
+	if len(matches) != 1 || matches[0].ActionName != "double" {
+		t.Fatalf("expected only the repeat action for a duplicate, got %+v", matches)
+	}
+}
+
+func TestInheritState_KeepsHistoryAcrossReload(t *testing.T) {
+	actions := map[string]config.ActionConfig{
+		"double": {Enabled: true, Trigger: "repeat:2 within:2s"},
+	}
+	now, advance := steppingClock()
+	content := makeContent("deliberate", clipboard.ContentTypeText)
+
+	first := mustNewEngine(t, actions)
+	first.SetClock(now)
+	first.Evaluate(content)
+
+	reloaded := mustNewEngine(t, actions)
+	reloaded.SetClock(now)
+	reloaded.InheritState(first)
+	advance(time.Second)
+	if len(reloaded.Evaluate(content)) != 1 {
+		t.Fatal("a gesture started before a reload should complete after it")
+	}
+
+	fresh := mustNewEngine(t, actions)
+	fresh.SetClock(now)
+	if len(fresh.Evaluate(content)) != 0 {
+		t.Fatal("an engine without inherited state starts with empty history")
+	}
+}
+
+func TestEventHistory_IsBounded(t *testing.T) {
+	h := &eventHistory{}
+	for i := 0; i < maxHistoryEvents+10; i++ {
+		h.record(historyEvent{signature: string(rune('a' + i%26))})
+	}
+	if len(h.events) != maxHistoryEvents {
+		t.Fatalf("expected %d events, got %d", maxHistoryEvents, len(h.events))
+	}
+}
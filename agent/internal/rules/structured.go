@@ -0,0 +1,348 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// structuredFormats are the condition prefixes that parse the clipboard text
+// as structured data before applying a JSONPath-style predicate:
+//
+//	json:$.level == "error"
+//	json:has($.stack)
+//	yaml:$.spec.replicas >= 3
+//	csv:$[0].status != "ok"
+//
+// CSV content is parsed as an array of objects keyed by the header row.
+var structuredFormats = []string{"json", "yaml", "csv"}
+
+// structuredPredicate is a compiled json:/yaml:/csv: condition.
+type structuredPredicate struct {
+	format string
+	path   []pathStep
+	// op is "has" for has(path), otherwise a comparison operator applied
+	// against value.
+	op    string
+	value any
+}
+
+// pathStep is one segment of a $.a.b[0]["c d"] path.
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parsedPayload is the lazily parsed form of the clipboard text in one format.
+type parsedPayload struct {
+	value any
+	err   error
+}
+
+// payloadCache parses the content of a single clipboard event at most once
+// per format, so several rules inspecting the same payload share one parse.
+type payloadCache struct {
+	text   string
+	parsed map[string]parsedPayload
+	// language is detectLanguage(text), computed on the first lang: condition.
+	language         string
+	languageDetected bool
+}
+
+func newPayloadCache(text string) *payloadCache {
+	return &payloadCache{text: text, parsed: make(map[string]parsedPayload)}
+}
+
+// get returns the content parsed as format, parsing it on first use.
+func (c *payloadCache) get(format string) (any, error) {
+	if cached, ok := c.parsed[format]; ok {
+		return cached.value, cached.err
+	}
+	value, err := parsePayload(format, c.text)
+	c.parsed[format] = parsedPayload{value: value, err: err}
+	return value, err
+}
+
+// detectedLanguage returns the content's programming language, detecting it
+// on first use.
+func (c *payloadCache) detectedLanguage() string {
+	if !c.languageDetected {
+		c.language, c.languageDetected = detectLanguage(c.text), true
+	}
+	return c.language
+}
+
+// structuredFormatOf returns the format prefix of cond ("json", "yaml",
+// "csv"), or "" if cond is not a structured-data condition.
+func structuredFormatOf(cond string) string {
+	for _, format := range structuredFormats {
+		if strings.HasPrefix(cond, format+":") {
+			return format
+		}
+	}
+	return ""
+}
+
+func parsePayload(format string, text string) (any, error) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return nil, fmt.Errorf("empty payload")
+	}
+
+	switch format {
+	case "json":
+		var value any
+		if err := json.Unmarshal([]byte(trimmed), &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case "yaml":
+		var value any
+		if err := yaml.Unmarshal([]byte(trimmed), &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case "csv":
+		records, err := csv.NewReader(bytes.NewReader([]byte(trimmed))).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) < 2 {
+			return nil, fmt.Errorf("csv payload needs a header row and a data row")
+		}
+		rows := make([]any, 0, len(records)-1)
+		for _, record := range records[1:] {
+			row := make(map[string]any, len(record))
+			for i, field := range record {
+				row[records[0][i]] = field
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// parseStructuredPredicate compiles a json:/yaml:/csv: condition.
+func parseStructuredPredicate(cond string) (structuredPredicate, error) {
+	var p structuredPredicate
+	p.format = structuredFormatOf(cond)
+	if p.format == "" {
+		return p, fmt.Errorf("unknown structured condition %q", cond)
+	}
+	expr := strings.TrimSpace(strings.TrimPrefix(cond, p.format+":"))
+
+	if inner, ok := strings.CutPrefix(expr, "has("); ok {
+		inner, ok = strings.CutSuffix(strings.TrimSpace(inner), ")")
+		if !ok {
+			return p, fmt.Errorf("unterminated has(")
+		}
+		path, rest, err := parsePath(strings.TrimSpace(inner))
+		if err != nil {
+			return p, err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return p, fmt.Errorf("unexpected %q after path in has()", rest)
+		}
+		p.path, p.op = path, "has"
+		return p, nil
+	}
+
+	path, rest, err := parsePath(expr)
+	if err != nil {
+		return p, err
+	}
+	p.path = path
+
+	rest = strings.TrimSpace(rest)
+	for _, op := range []string{"==", "!=", ">=", "<=", ">", "<"} {
+		if operand, ok := strings.CutPrefix(rest, op); ok {
+			p.op = op
+			p.value, err = parseLiteral(strings.TrimSpace(operand))
+			return p, err
+		}
+	}
+	return p, fmt.Errorf("expected has(<path>) or <path> <op> <value>, got %q", expr)
+}
+
+// parsePath parses a $-rooted path from the start of s and returns the
+// unconsumed remainder.
+func parsePath(s string) ([]pathStep, string, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, s, fmt.Errorf("path must start with $, got %q", s)
+	}
+	var steps []pathStep
+	i := 1
+	for i < len(s) {
+		switch s[i] {
+		case '.':
+			start := i + 1
+			end := start
+			for end < len(s) && (isWordChar(s[end]) || s[end] == '-') {
+				end++
+			}
+			if end == start {
+				return nil, s, fmt.Errorf("empty key after '.' in path %q", s)
+			}
+			steps = append(steps, pathStep{key: s[start:end]})
+			i = end
+		case '[':
+			closing := strings.IndexByte(s[i:], ']')
+			if closing < 0 {
+				return nil, s, fmt.Errorf("unterminated '[' in path %q", s)
+			}
+			inner := strings.TrimSpace(s[i+1 : i+closing])
+			if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+				steps = append(steps, pathStep{key: inner[1 : len(inner)-1]})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, s, fmt.Errorf("invalid index [%s] in path %q", inner, s)
+				}
+				steps = append(steps, pathStep{index: index, isIndex: true})
+			}
+			i += closing + 1
+		default:
+			return steps, s[i:], nil
+		}
+	}
+	return steps, "", nil
+}
+
+// parseLiteral parses a comparison operand: a quoted string, a number, true,
+// false or null.
+func parseLiteral(s string) (any, error) {
+	switch s {
+	case "":
+		return nil, fmt.Errorf("missing comparison value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1], nil
+	}
+	if s[0] == '"' {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string literal %s", s)
+		}
+		return unquoted, nil
+	}
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comparison value %q (quote strings)", s)
+	}
+	return number, nil
+}
+
+// matches applies the predicate to the cached payload.
+func (p structuredPredicate) matches(cache *payloadCache) bool {
+	root, err := cache.get(p.format)
+	if err != nil {
+		return false
+	}
+	value, ok := resolvePath(root, p.path)
+	if !ok {
+		return false
+	}
+	if p.op == "has" {
+		return true
+	}
+	return compareValues(normalizeValue(value), p.op, p.value)
+}
+
+func resolvePath(value any, path []pathStep) (any, bool) {
+	for _, step := range path {
+		switch node := value.(type) {
+		case map[string]any:
+			if step.isIndex {
+				return nil, false
+			}
+			next, ok := node[step.key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			if !step.isIndex {
+				return nil, false
+			}
+			index := step.index
+			if index < 0 {
+				index += len(node)
+			}
+			if index < 0 || index >= len(node) {
+				return nil, false
+			}
+			value = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// normalizeValue maps decoded numbers of any width to float64 so JSON, YAML
+// and literal operands compare alike.
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	default:
+		return value
+	}
+}
+
+// compareValues is type-aware: equality never coerces between types, and
+// ordering applies only to two numbers or two strings.
+func compareValues(left any, op string, right any) bool {
+	switch op {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	}
+
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		return ok && orderedCompare(l, op, r)
+	case string:
+		r, ok := right.(string)
+		return ok && orderedCompare(l, op, r)
+	default:
+		return false
+	}
+}
+
+func orderedCompare[T float64 | string](left T, op string, right T) bool {
+	switch op {
+	case ">":
+		return left > right
+	case ">=":
+		return left >= right
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	default:
+		return false
+	}
+}
@@ -0,0 +1,162 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/clipboard-ai/agent/internal/clipboard"
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+func TestParseStructuredPredicate(t *testing.T) {
+	tests := []struct {
+		cond    string
+		wantErr bool
+	}{
+		{cond: `json:$.level == "error"`},
+		{cond: `json:has($.stack)`},
+		{cond: `json:$.items[0]["display name"] != 'x'`},
+		{cond: `json:$.items[-1].count >= 3`},
+		{cond: `yaml:$.spec.replicas > 2`},
+		{cond: `csv:$[0].status == "ok"`},
+		{cond: `json:$.ok == true`},
+		{cond: `json:$.parent == null`},
+		{cond: `json:level == "error"`, wantErr: true},
+		{cond: `json:$.level`, wantErr: true},
+		{cond: `json:$.level == error`, wantErr: true},
+		{cond: `json:$.level ==`, wantErr: true},
+		{cond: `json:has($.stack`, wantErr: true},
+		{cond: `json:has($.stack extra)`, wantErr: true},
+		{cond: `json:$.items[first] == 1`, wantErr: true},
+		{cond: `json:$. == 1`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		_, err := parseStructuredPredicate(tt.cond)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseStructuredPredicate(%q) error = %v, wantErr %v", tt.cond, err, tt.wantErr)
+		}
+	}
+}
+
+func TestStructuredPredicateMatches(t *testing.T) {
+	jsonPayload := `{"level": "error", "code": 500, "ok": false, "parent": null,
+		"stack": ["a", "b"], "items": [{"display name": "first", "count": 2}, {"count": 7}]}`
+	yamlPayload := "spec:\n  replicas: 3\n  image: app:v2\nlabels:\n  - web\n"
+	csvPayload := "name,status\napi,ok\nworker,failed\n"
+
+	tests := []struct {
+		cond    string
+		payload string
+		want    bool
+	}{
+		{cond: `json:$.level == "error"`, payload: jsonPayload, want: true},
+		{cond: `json:$.level != "error"`, payload: jsonPayload, want: false},
+		{cond: `json:$.code >= 500`, payload: jsonPayload, want: true},
+		{cond: `json:$.code < 500`, payload: jsonPayload, want: false},
+		{cond: `json:$.code == "500"`, payload: jsonPayload, want: false},
+		{cond: `json:$.level > 1`, payload: jsonPayload, want: false},
+		{cond: `json:$.ok == false`, payload: jsonPayload, want: true},
+		{cond: `json:$.parent == null`, payload: jsonPayload, want: true},
+		{cond: `json:has($.stack)`, payload: jsonPayload, want: true},
+		{cond: `json:has($.missing)`, payload: jsonPayload, want: false},
+		{cond: `json:$.missing != "x"`, payload: jsonPayload, want: false},
+		{cond: `json:$.items[0]["display name"] == "first"`, payload: jsonPayload, want: true},
+		{cond: `json:$.items[-1].count > 5`, payload: jsonPayload, want: true},
+		{cond: `json:has($.items[5])`, payload: jsonPayload, want: false},
+		{cond: `json:has($.level)`, payload: "not json at all", want: false},
+		{cond: `yaml:$.spec.replicas >= 3`, payload: yamlPayload, want: true},
+		{cond: `yaml:$.spec.image == "app:v2"`, payload: yamlPayload, want: true},
+		{cond: `yaml:$.labels[0] == "web"`, payload: yamlPayload, want: true},
+		{cond: `yaml:has($.spec)`, payload: "just a sentence", want: false},
+		{cond: `csv:$[1].status == "failed"`, payload: csvPayload, want: true},
+		{cond: `csv:$[0].name == "api"`, payload: csvPayload, want: true},
+		{cond: `csv:has($[0])`, payload: "one line of text", want: false},
+		{cond: `csv:$[1].status == "failed"`, payload: "status\nok\nfailed\n", want: true},
+		{cond: `csv:$[0].status == "ok"`, payload: "status\nok\n", want: true},
+		{cond: `csv:has($[0].status)`, payload: "first line\nsecond line", want: false},
+	}
+
+	for _, tt := range tests {
+		predicate, err := parseStructuredPredicate(tt.cond)
+		if err != nil {
+			t.Fatalf("parseStructuredPredicate(%q): %v", tt.cond, err)
+		}
+		if got := predicate.matches(newPayloadCache(tt.payload)); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+}
+
+func TestPayloadCache_ParsesOncePerFormat(t *testing.T) {
+	cache := newPayloadCache(`{"a": 1}`)
+	first, err := cache.get("json")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	second, _ := cache.get("json")
+	// Maps are reference types: the same parse yields the same map.
+	if first.(map[string]any)["a"] != second.(map[string]any)["a"] || len(cache.parsed) != 1 {
+		t.Fatal("expected the cached parse to be reused")
+	}
+	first.(map[string]any)["marker"] = true
+	if _, ok := second.(map[string]any)["marker"]; !ok {
+		t.Fatal("expected both lookups to return the same parsed value")
+	}
+}
+
+func TestIgnoredAndEvaluate_ShareOneParsePerEvent(t *testing.T) {
+	engine, err := NewEngineFromConfig(&config.Config{
+		Actions: map[string]config.ActionConfig{
+			"triage": {Enabled: true, Trigger: `json:$.level == "error"`},
+		},
+		Settings: config.SettingsConfig{Ignore: []string{`json:$.level == "debug"`}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content := makeContent(`{"level": "error"}`, clipboard.ContentTypeText)
+	if _, ignored := engine.Ignored(content); ignored {
+		t.Fatal("expected the content not to be ignored")
+	}
+	payloads := engine.payloads
+	if matches := engine.Evaluate(content); len(matches) != 1 {
+		t.Fatalf("expected triage to match, got %+v", matches)
+	}
+	if engine.payloads != payloads || len(payloads.parsed) != 1 {
+		t.Fatal("expected Evaluate to reuse the parse Ignored made")
+	}
+
+	engine.Evaluate(makeContent(`{"level": "info"}`, clipboard.ContentTypeText))
+	if engine.payloads == payloads {
+		t.Fatal("expected new content to start a new parse cache")
+	}
+}
+
+func TestEvaluate_StructuredConditionsInTriggers(t *testing.T) {
+	engine := mustNewEngine(t, map[string]config.ActionConfig{
+		"explain_error": {Enabled: true, Trigger: `json:$.level == "error" AND (json:has($.stack) OR json:$.msg == "a AND b")`},
+		"not_json":      {Enabled: true, Trigger: `NOT json:has($.level) AND length > 0`},
+	})
+
+	names := func(text string) map[string]bool {
+		found := map[string]bool{}
+		for _, m := range engine.Evaluate(makeContent(text, clipboard.ContentTypeText)) {
+			found[m.ActionName] = true
+		}
+		return found
+	}
+
+	if got := names(`{"level": "error", "stack": "at main"}`); !got["explain_error"] || got["not_json"] {
+		t.Fatalf("expected only explain_error for an error with a stack, got %v", got)
+	}
+	if got := names(`{"level": "error", "msg": "a AND b"}`); !got["explain_error"] {
+		t.Fatalf("expected a quoted AND inside a literal to be part of the condition, got %v", got)
+	}
+	if got := names(`{"level": "info", "stack": "x"}`); got["explain_error"] {
+		t.Fatalf("expected no match for an info log, got %v", got)
+	}
+	if got := names("plain text"); got["explain_error"] || !got["not_json"] {
+		t.Fatalf("expected only not_json for plain text, got %v", got)
+	}
+}
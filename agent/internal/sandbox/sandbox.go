@@ -0,0 +1,79 @@
+// Package sandbox confines action subprocesses on Linux: Landlock restricts
+// the filesystem they can reach, and a new network namespace cuts them off
+// from the network. Protections the kernel doesn't support are skipped and
+// reported in the Status rather than failing the action.
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// HelperCommand is the agent subcommand that applies a filesystem policy to
+// itself and then execs the action. Landlock only confines the calling thread
+// and what it execs, so it can't be applied from the agent's side of fork.
+const HelperCommand = "__sandbox-exec"
+
+// Status values for Status.Filesystem and Status.Network.
+const (
+	StatusOff         = "off"
+	StatusEnforced    = "enforced"
+	StatusUnsupported = "unsupported"
+)
+
+// Policy is the confinement requested for one action subprocess.
+type Policy struct {
+	// Filesystem restricts the process to ReadOnly and ReadWrite (and
+	// everything beneath them) with Landlock.
+	Filesystem bool
+	ReadOnly   []string
+	ReadWrite  []string
+	// NoNetwork runs the process in an empty network namespace: no
+	// interfaces beyond a down loopback, so no localhost either.
+	NoNetwork bool
+}
+
+// Status reports which protections were applied to an action subprocess.
+type Status struct {
+	Filesystem string `json:"filesystem"` // off, enforced or unsupported
+	Network    string `json:"network"`    // off, enforced or unsupported
+	// Reason explains unsupported protections.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Degraded reports whether a requested protection couldn't be applied.
+func (s Status) Degraded() bool {
+	return s.Filesystem == StatusUnsupported || s.Network == StatusUnsupported
+}
+
+// helperPath locates the binary that implements HelperCommand.
+var helperPath = os.Executable
+
+// helperRules is the filesystem policy handed to the helper as its first
+// argument.
+type helperRules struct {
+	ABI       int      `json:"abi"`
+	ReadOnly  []string `json:"read_only"`
+	ReadWrite []string `json:"read_write"`
+}
+
+// RunHelper implements HelperCommand: args are the JSON rules followed by
+// the command to exec. It only returns on failure, with an exit status.
+func RunHelper(args []string, stderr io.Writer) int {
+	if len(args) < 2 {
+		fmt.Fprintf(stderr, "usage: %s <rules> <command> [args...]\n", HelperCommand)
+		return 2
+	}
+	var rules helperRules
+	if err := json.Unmarshal([]byte(args[0]), &rules); err != nil {
+		fmt.Fprintf(stderr, "sandbox: invalid rules: %v\n", err)
+		return 2
+	}
+	if err := restrictAndExec(rules, args[1], args[1:]); err != nil {
+		fmt.Fprintf(stderr, "sandbox: %v\n", err)
+		return 126
+	}
+	return 0
+}
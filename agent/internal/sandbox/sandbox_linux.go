@@ -0,0 +1,227 @@
+//go:build linux && !(mips || mipsle || mips64 || mips64le)
+
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Landlock syscall numbers; every architecture Go supports except mips uses
+// the asm-generic numbering.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+)
+
+const (
+	landlockCreateRulesetVersion = 1 << 0
+	landlockRulePathBeneath      = 1
+	prSetNoNewPrivs              = 38
+	oPath                        = 0x200000 // O_PATH, missing from package syscall
+)
+
+// Landlock filesystem access rights (linux/landlock.h).
+const (
+	accessExecute    = 1 << 0
+	accessWriteFile  = 1 << 1
+	accessReadFile   = 1 << 2
+	accessReadDir    = 1 << 3
+	accessRemoveDir  = 1 << 4
+	accessRemoveFile = 1 << 5
+	accessMakeChar   = 1 << 6
+	accessMakeDir    = 1 << 7
+	accessMakeReg    = 1 << 8
+	accessMakeSock   = 1 << 9
+	accessMakeFifo   = 1 << 10
+	accessMakeBlock  = 1 << 11
+	accessMakeSym    = 1 << 12
+	accessRefer      = 1 << 13 // ABI 2
+	accessTruncate   = 1 << 14 // ABI 3
+
+	accessABI1 = accessExecute | accessWriteFile | accessReadFile | accessReadDir |
+		accessRemoveDir | accessRemoveFile | accessMakeChar | accessMakeDir |
+		accessMakeReg | accessMakeSock | accessMakeFifo | accessMakeBlock | accessMakeSym
+	accessRead = accessExecute | accessReadFile | accessReadDir
+	// accessFile are the rights that apply to a non-directory.
+	accessFile = accessExecute | accessWriteFile | accessReadFile | accessTruncate
+)
+
+type rulesetAttr struct {
+	handledAccessFS uint64
+}
+
+// pathBeneathAttr mirrors the packed struct landlock_path_beneath_attr; the
+// kernel reads only its first 12 bytes.
+type pathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+}
+
+// landlockABI returns the kernel's Landlock ABI version.
+var landlockABI = func() (int, error) {
+	abi, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return 0, fmt.Errorf("landlock unavailable: %w", errno)
+	}
+	return int(abi), nil
+}
+
+var (
+	netnsOnce sync.Once
+	netnsErr  error
+)
+
+// probeNetworkNamespace reports whether an unprivileged process can be
+// started in a new user and network namespace. The result is cached.
+var probeNetworkNamespace = func() error {
+	netnsOnce.Do(func() {
+		cmd := exec.Command("/bin/true")
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+		isolateNetwork(cmd.SysProcAttr)
+		if err := cmd.Run(); err != nil {
+			netnsErr = fmt.Errorf("network namespace unavailable: %w", err)
+		}
+	})
+	return netnsErr
+}
+
+var unsupportedOnce sync.Once
+
+// Apply configures cmd, which must not have been started, to run under
+// policy and reports what will be enforced. The filesystem policy reroutes
+// cmd through HelperCommand.
+func Apply(cmd *exec.Cmd, policy Policy) Status {
+	status := Status{Filesystem: StatusOff, Network: StatusOff}
+	var reasons []error
+
+	if policy.NoNetwork {
+		if err := probeNetworkNamespace(); err != nil {
+			status.Network = StatusUnsupported
+			reasons = append(reasons, err)
+		} else {
+			if cmd.SysProcAttr == nil {
+				cmd.SysProcAttr = &syscall.SysProcAttr{}
+			}
+			isolateNetwork(cmd.SysProcAttr)
+			status.Network = StatusEnforced
+		}
+	}
+
+	if policy.Filesystem {
+		if err := wrapWithHelper(cmd, policy); err != nil {
+			status.Filesystem = StatusUnsupported
+			reasons = append(reasons, err)
+		} else {
+			status.Filesystem = StatusEnforced
+		}
+	}
+
+	if len(reasons) > 0 {
+		status.Reason = errors.Join(reasons...).Error()
+		unsupportedOnce.Do(func() {
+			slog.Warn("action sandbox degraded", "reason", status.Reason)
+		})
+	}
+	return status
+}
+
+// isolateNetwork puts the process in new user and network namespaces. The
+// user namespace maps only the caller's own IDs, so it gains no privileges.
+func isolateNetwork(attr *syscall.SysProcAttr) {
+	attr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+}
+
+func wrapWithHelper(cmd *exec.Cmd, policy Policy) error {
+	abi, err := landlockABI()
+	if err != nil {
+		return err
+	}
+	self, err := helperPath()
+	if err != nil {
+		return fmt.Errorf("locate sandbox helper: %w", err)
+	}
+	rules, err := json.Marshal(helperRules{ABI: abi, ReadOnly: policy.ReadOnly, ReadWrite: policy.ReadWrite})
+	if err != nil {
+		return err
+	}
+	cmd.Args = append([]string{self, HelperCommand, string(rules), cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = self
+	return nil
+}
+
+// restrictAndExec confines the current thread to rules and execs argv[0] =
+// path from it, so the new program inherits the Landlock domain.
+func restrictAndExec(rules helperRules, path string, argv []string) error {
+	runtime.LockOSThread()
+
+	handled := uint64(accessABI1)
+	if rules.ABI >= 2 {
+		handled |= accessRefer
+	}
+	if rules.ABI >= 3 {
+		handled |= accessTruncate
+	}
+	attr := rulesetAttr{handledAccessFS: handled}
+	fd, _, errno := syscall.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("create landlock ruleset: %w", errno)
+	}
+	defer syscall.Close(int(fd))
+
+	for _, dir := range rules.ReadOnly {
+		if err := allowPath(int(fd), dir, accessRead&handled); err != nil {
+			return err
+		}
+	}
+	for _, dir := range rules.ReadWrite {
+		if err := allowPath(int(fd), dir, handled); err != nil {
+			return err
+		}
+	}
+
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return fmt.Errorf("set no_new_privs: %w", errno)
+	}
+	if _, _, errno := syscall.Syscall(sysLandlockRestrictSelf, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("enforce landlock ruleset: %w", errno)
+	}
+	return fmt.Errorf("exec %s: %w", path, syscall.Exec(path, argv, os.Environ()))
+}
+
+// allowPath grants access beneath path. Missing paths are skipped so one
+// policy fits different distributions.
+func allowPath(rulesetFd int, path string, access uint64) error {
+	fd, err := syscall.Open(path, oPath|syscall.O_CLOEXEC, 0)
+	if errors.Is(err, syscall.ENOENT) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer syscall.Close(fd)
+
+	var stat syscall.Stat_t
+	if err := syscall.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+	if stat.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		access &= accessFile
+	}
+	rule := pathBeneathAttr{allowedAccess: access, parentFd: int32(fd)}
+	if _, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(rulesetFd), landlockRulePathBeneath, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("allow %s: %w", path, errno)
+	}
+	return nil
+}
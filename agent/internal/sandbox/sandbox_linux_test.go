@@ -0,0 +1,84 @@
+//go:build linux && !(mips || mipsle || mips64 || mips64le)
+
+package sandbox
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSystemPaths = []string{"/usr", "/lib", "/lib64", "/bin", "/etc"}
+
+func TestApply_FilesystemConfinesWrites(t *testing.T) {
+	if _, err := landlockABI(); err != nil {
+		t.Skipf("kernel lacks Landlock: %v", err)
+	}
+	allowed, denied := t.TempDir(), t.TempDir()
+
+	cmd := exec.Command("/bin/sh", "-c", `echo ok > "$1/a" && cat "$1/a" && ! { echo no > "$2/b"; } 2>/dev/null`, "sh", allowed, denied)
+	status := Apply(cmd, Policy{Filesystem: true, ReadOnly: testSystemPaths, ReadWrite: []string{allowed, "/dev/null"}})
+	if status.Filesystem != StatusEnforced || status.Network != StatusOff {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sandboxed command failed: %v: %s", err, out)
+	}
+	if strings.TrimSpace(string(out)) != "ok" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(denied, "b")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("write outside the policy wasn't blocked: %v", err)
+	}
+}
+
+func TestApply_NoNetworkLeavesOnlyLoopback(t *testing.T) {
+	if err := probeNetworkNamespace(); err != nil {
+		t.Skipf("kernel lacks unprivileged network namespaces: %v", err)
+	}
+
+	cmd := exec.Command("/bin/cat", "/proc/net/dev")
+	status := Apply(cmd, Policy{NoNetwork: true})
+	if status.Network != StatusEnforced || status.Filesystem != StatusOff {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("isolated command failed: %v: %s", err, out)
+	}
+	var interfaces []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if name, _, ok := strings.Cut(line, ":"); ok {
+			interfaces = append(interfaces, strings.TrimSpace(name))
+		}
+	}
+	if len(interfaces) != 1 || interfaces[0] != "lo" {
+		t.Fatalf("expected only loopback, got %v", interfaces)
+	}
+}
+
+func TestApply_DegradesWhenUnsupported(t *testing.T) {
+	previousABI, previousProbe := landlockABI, probeNetworkNamespace
+	landlockABI = func() (int, error) { return 0, errors.New("landlock unavailable: function not implemented") }
+	probeNetworkNamespace = func() error { return errors.New("network namespace unavailable: operation not permitted") }
+	t.Cleanup(func() { landlockABI, probeNetworkNamespace = previousABI, previousProbe })
+
+	cmd := exec.Command("/bin/true")
+	status := Apply(cmd, Policy{Filesystem: true, ReadWrite: []string{t.TempDir()}, NoNetwork: true})
+	if status.Filesystem != StatusUnsupported || status.Network != StatusUnsupported || !status.Degraded() {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if !strings.Contains(status.Reason, "landlock unavailable") || !strings.Contains(status.Reason, "network namespace unavailable") {
+		t.Fatalf("reason doesn't explain both protections: %q", status.Reason)
+	}
+	if cmd.Path != "/bin/true" || cmd.SysProcAttr != nil {
+		t.Fatalf("degraded command was modified: path=%s attr=%+v", cmd.Path, cmd.SysProcAttr)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("degraded command should still run: %v", err)
+	}
+}
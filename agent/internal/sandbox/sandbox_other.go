@@ -0,0 +1,29 @@
+//go:build !linux || mips || mipsle || mips64 || mips64le
+
+package sandbox
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errUnsupported = errors.New("action sandboxing requires Linux")
+
+// Apply reports requested protections as unsupported; cmd runs unconfined.
+func Apply(cmd *exec.Cmd, policy Policy) Status {
+	status := Status{Filesystem: StatusOff, Network: StatusOff}
+	if policy.Filesystem {
+		status.Filesystem = StatusUnsupported
+	}
+	if policy.NoNetwork {
+		status.Network = StatusUnsupported
+	}
+	if status.Degraded() {
+		status.Reason = errUnsupported.Error()
+	}
+	return status
+}
+
+func restrictAndExec(helperRules, string, []string) error {
+	return errUnsupported
+}
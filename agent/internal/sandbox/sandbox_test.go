@@ -0,0 +1,56 @@
+package sandbox
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestMain lets the test binary act as the sandbox helper, as the agent
+// binary does.
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == HelperCommand {
+		os.Exit(RunHelper(os.Args[2:], os.Stderr))
+	}
+	os.Exit(m.Run())
+}
+
+func TestRunHelper_RejectsBadArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "missing command", args: []string{`{}`}, want: "usage"},
+		{name: "invalid rules", args: []string{`{`, "/bin/true"}, want: "invalid rules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			if code := RunHelper(tt.args, &stderr); code != 2 {
+				t.Fatalf("expected exit status 2, got %d", code)
+			}
+			if !strings.Contains(stderr.String(), tt.want) {
+				t.Fatalf("expected %q in stderr, got %q", tt.want, stderr.String())
+			}
+		})
+	}
+}
+
+func TestStatus_Degraded(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   bool
+	}{
+		{status: Status{Filesystem: StatusOff, Network: StatusOff}, want: false},
+		{status: Status{Filesystem: StatusEnforced, Network: StatusEnforced}, want: false},
+		{status: Status{Filesystem: StatusUnsupported, Network: StatusOff}, want: true},
+		{status: Status{Filesystem: StatusEnforced, Network: StatusUnsupported}, want: true},
+	}
+	for _, tt := range tests {
+		if got := tt.status.Degraded(); got != tt.want {
+			t.Fatalf("%+v: expected Degraded() = %v, got %v", tt.status, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Package scheduler admits action runs under the agent's shared concurrency
+// limit and any narrower limits a run is subject to, such as its action's or
+// its provider endpoint's. Runs that can't start wait in a bounded priority
+// queue; when the queue is full an overflow policy decides which run gives
+// way.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+// Priority orders queued runs: higher priorities start first, and equal
+// priorities start in arrival order.
+type Priority int
+
+const (
+	PriorityTriggered Priority = iota // matched by a clipboard trigger
+	PriorityManual                    // requested through the API
+
+	numPriorities = int(PriorityManual) + 1
+)
+
+var (
+	// ErrQueueFull is returned when the queue is full and the overflow
+	// policy keeps the queued runs over the new one.
+	ErrQueueFull = errors.New("action queue is full")
+	// ErrDropped is returned to a queued run evicted to make room for a
+	// newer or higher-priority one.
+	ErrDropped = errors.New("dropped from the action queue")
+	// ErrCoalesced is returned to a queued run replaced by a newer run of
+	// the same action.
+	ErrCoalesced = errors.New("replaced by a newer run of the same action")
+)
+
+// Limit caps the runs sharing a resource, such as one action or one provider
+// endpoint, that may run at once.
+type Limit struct {
+	Key string
+	Max int
+}
+
+// Options are the scheduler's limits.
+type Options struct {
+	// MaxConcurrent caps running actions; 0 means unlimited, so nothing
+	// ever queues.
+	MaxConcurrent int
+	// QueueSize caps runs waiting for a slot; 0 means runs never wait and
+	// the overflow policy applies as soon as every slot is busy.
+	QueueSize int
+	// Overflow is a config.QueueOverflow* policy.
+	Overflow string
+}
+
+// OptionsFrom returns the scheduler options the settings configure.
+func OptionsFrom(settings config.SettingsConfig) Options {
+	return Options{
+		MaxConcurrent: settings.MaxConcurrentActions,
+		QueueSize:     settings.ActionQueueSize,
+		Overflow:      settings.ActionQueueOverflow,
+	}
+}
+
+// Stats is a snapshot of the scheduler's state and counters.
+type Stats struct {
+	MaxConcurrent int    `json:"max_concurrent"`
+	QueueSize     int    `json:"queue_size"`
+	Overflow      string `json:"overflow"`
+	Running       int    `json:"running"`
+	Queued        int    `json:"queued"`
+	// Submitted counts every Acquire; Started those granted a slot.
+	Submitted int64 `json:"submitted"`
+	Started   int64 `json:"started"`
+	// Rejected counts runs refused on arrival, Dropped queued runs evicted,
+	// and Coalesced queued runs replaced by a newer run of their action.
+	Rejected  int64 `json:"rejected"`
+	Dropped   int64 `json:"dropped"`
+	Coalesced int64 `json:"coalesced"`
+	// Limited counts running actions per Limit key.
+	Limited map[string]int `json:"limited,omitempty"`
+	// WaitAvgMs and WaitMaxMs cover the time started runs spent queued.
+	WaitAvgMs int64 `json:"wait_avg_ms"`
+	WaitMaxMs int64 `json:"wait_max_ms"`
+}
+
+// Scheduler grants run slots. The zero value is not usable; call New.
+type Scheduler struct {
+	mu      sync.Mutex
+	opts    Options
+	running int
+	// inUse counts running actions per Limit key.
+	inUse   map[string]int
+	queues  [numPriorities][]*waiter
+	stats   Stats
+	waitSum time.Duration
+	waitMax time.Duration
+}
+
+// waiter is a queued run. ready receives nil when the run is granted a slot,
+// or the error that removed it from the queue.
+type waiter struct {
+	priority Priority
+	key      string
+	limits   []Limit
+	queuedAt time.Time
+	ready    chan error
+}
+
+// New returns a scheduler with the given limits.
+func New(opts Options) *Scheduler {
+	return &Scheduler{opts: opts, inUse: make(map[string]int)}
+}
+
+// SetOptions changes the scheduler's limits, starting queued runs if the
+// concurrency limit rose. Runs already queued past a smaller QueueSize keep
+// their place.
+func (s *Scheduler) SetOptions(opts Options) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opts = opts
+	s.dispatch()
+}
+
+// Acquire waits for a slot to run an action with the given priority, within
+// the scheduler's limit and each of limits. key identifies the action for the
+// coalesce policy; an empty key never coalesces. It returns a release func to
+// call when the run finishes, or ErrQueueFull, ErrDropped, ErrCoalesced or
+// ctx's error.
+func (s *Scheduler) Acquire(ctx context.Context, priority Priority, key string, limits ...Limit) (func(), error) {
+	if priority < 0 || int(priority) >= numPriorities {
+		priority = PriorityTriggered
+	}
+	s.mu.Lock()
+	s.stats.Submitted++
+	// Queued runs are all blocked by a limit, or dispatch would have
+	// started them, so a run that fits now needn't wait behind them.
+	if s.free() && s.fits(limits) {
+		s.start(limits, 0)
+		s.mu.Unlock()
+		return s.releaseFunc(limits), nil
+	}
+	w := &waiter{priority: priority, key: key, limits: limits, queuedAt: time.Now(), ready: make(chan error, 1)}
+	if err := s.enqueue(w); err != nil {
+		s.stats.Rejected++
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.mu.Unlock()
+
+	select {
+	case err := <-w.ready:
+		if err != nil {
+			return nil, err
+		}
+		return s.releaseFunc(limits), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		removed := s.remove(w)
+		s.mu.Unlock()
+		// Granted or evicted as ctx ended: hand back a granted slot.
+		if !removed {
+			if err := <-w.ready; err == nil {
+				s.release(limits)
+			}
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the scheduler's state and counters.
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.MaxConcurrent = s.opts.MaxConcurrent
+	stats.QueueSize = s.opts.QueueSize
+	stats.Overflow = s.opts.Overflow
+	stats.Running = s.running
+	stats.Queued = s.queued()
+	if stats.Started > 0 {
+		stats.WaitAvgMs = (s.waitSum / time.Duration(stats.Started)).Milliseconds()
+	}
+	stats.WaitMaxMs = s.waitMax.Milliseconds()
+	if len(s.inUse) > 0 {
+		stats.Limited = make(map[string]int, len(s.inUse))
+		for key, n := range s.inUse {
+			stats.Limited[key] = n
+		}
+	}
+	return stats
+}
+
+// free reports whether a run may start now. Callers hold s.mu.
+func (s *Scheduler) free() bool {
+	return s.opts.MaxConcurrent <= 0 || s.running < s.opts.MaxConcurrent
+}
+
+// fits reports whether a run under limits may start as far as they're
+// concerned. Callers hold s.mu.
+func (s *Scheduler) fits(limits []Limit) bool {
+	for _, limit := range limits {
+		if limit.Max > 0 && s.inUse[limit.Key] >= limit.Max {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Scheduler) queued() int {
+	n := 0
+	for _, queue := range s.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// start counts a run under limits granted a slot after waiting for wait.
+func (s *Scheduler) start(limits []Limit, wait time.Duration) {
+	s.running++
+	for _, limit := range limits {
+		if limit.Max > 0 {
+			s.inUse[limit.Key]++
+		}
+	}
+	s.stats.Started++
+	s.waitSum += wait
+	s.waitMax = max(s.waitMax, wait)
+}
+
+func (s *Scheduler) releaseFunc(limits []Limit) func() {
+	var once sync.Once
+	return func() { once.Do(func() { s.release(limits) }) }
+}
+
+func (s *Scheduler) release(limits []Limit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	for _, limit := range limits {
+		if limit.Max <= 0 {
+			continue
+		}
+		if s.inUse[limit.Key]--; s.inUse[limit.Key] <= 0 {
+			delete(s.inUse, limit.Key)
+		}
+	}
+	s.dispatch()
+}
+
+// dispatch grants free slots to queued runs, highest priority first. A run
+// held back by one of its limits doesn't hold back the runs behind it.
+func (s *Scheduler) dispatch() {
+	for s.free() {
+		w := s.popNext()
+		if w == nil {
+			return
+		}
+		s.start(w.limits, time.Since(w.queuedAt))
+		w.ready <- nil
+	}
+}
+
+// popNext dequeues the first run, by priority then arrival, whose limits
+// let it start.
+func (s *Scheduler) popNext() *waiter {
+	for p := numPriorities - 1; p >= 0; p-- {
+		for i, w := range s.queues[p] {
+			if s.fits(w.limits) {
+				s.queues[p] = append(s.queues[p][:i:i], s.queues[p][i+1:]...)
+				return w
+			}
+		}
+	}
+	return nil
+}
+
+// enqueue queues w, applying the overflow policy when the queue is full. A
+// queued run of lower priority always gives way to w, whatever the policy.
+func (s *Scheduler) enqueue(w *waiter) error {
+	if s.opts.Overflow == config.QueueOverflowCoalesce && w.key != "" {
+		queue := s.queues[w.priority]
+		for i, queued := range queue {
+			if queued.key == w.key {
+				queued.ready <- ErrCoalesced
+				s.stats.Coalesced++
+				queue[i] = w
+				return nil
+			}
+		}
+	}
+	if s.queued() >= s.opts.QueueSize {
+		victim := s.oldestLowest()
+		switch {
+		case victim == nil:
+			return ErrQueueFull
+		case victim.priority < w.priority,
+			victim.priority == w.priority && s.opts.Overflow == config.QueueOverflowDropOldest:
+			s.remove(victim)
+			victim.ready <- ErrDropped
+			s.stats.Dropped++
+		default:
+			return ErrQueueFull
+		}
+	}
+	s.queues[w.priority] = append(s.queues[w.priority], w)
+	return nil
+}
+
+// oldestLowest returns the longest-waiting run of the lowest queued
+// priority, or nil when nothing is queued.
+func (s *Scheduler) oldestLowest() *waiter {
+	for _, queue := range s.queues {
+		if len(queue) > 0 {
+			return queue[0]
+		}
+	}
+	return nil
+}
+
+// remove takes w out of the queue, reporting whether it was still queued.
+func (s *Scheduler) remove(w *waiter) bool {
+	queue := s.queues[w.priority]
+	for i, queued := range queue {
+		if queued == w {
+			s.queues[w.priority] = append(queue[:i:i], queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/clipboard-ai/agent/internal/config"
+)
+
+// result is the outcome of an Acquire run in the background.
+type result struct {
+	release func()
+	err     error
+}
+
+// acquireAsync starts Acquire in the background and waits until the run is
+// submitted, so tests control arrival order.
+func acquireAsync(t *testing.T, s *Scheduler, ctx context.Context, priority Priority, key string, limits ...Limit) <-chan result {
+	t.Helper()
+	submitted := s.Stats().Submitted
+	done := make(chan result, 1)
+	go func() {
+		release, err := s.Acquire(ctx, priority, key, limits...)
+		done <- result{release, err}
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	// Acquire counts and queues a run under one lock.
+	for s.Stats().Submitted == submitted {
+		if time.Now().After(deadline) {
+			t.Fatal("run was never queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+func expectResult(t *testing.T, done <-chan result, wantErr error) result {
+	t.Helper()
+	select {
+	case r := <-done:
+		if !errors.Is(r.err, wantErr) {
+			t.Fatalf("expected error %v, got %v", wantErr, r.err)
+		}
+		return r
+	case <-time.After(2 * time.Second):
+		t.Fatalf("run never finished waiting (want %v)", wantErr)
+		return result{}
+	}
+}
+
+func expectWaiting(t *testing.T, done <-chan result) {
+	t.Helper()
+	select {
+	case r := <-done:
+		t.Fatalf("expected the run to still be queued, got %+v", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func mustAcquire(t *testing.T, s *Scheduler, priority Priority, key string, limits ...Limit) func() {
+	t.Helper()
+	release, err := s.Acquire(context.Background(), priority, key, limits...)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	return release
+}
+
+func TestAcquire_Unlimited(t *testing.T) {
+	s := New(Options{})
+	for i := 0; i < 10; i++ {
+		mustAcquire(t, s, PriorityTriggered, "summarize")
+	}
+	if stats := s.Stats(); stats.Running != 10 || stats.Queued != 0 {
+		t.Fatalf("expected every run to start, got %+v", stats)
+	}
+}
+
+func TestAcquire_QueuesUntilReleased(t *testing.T) {
+	s := New(Options{MaxConcurrent: 1, QueueSize: 4, Overflow: config.QueueOverflowReject})
+	release := mustAcquire(t, s, PriorityTriggered, "a")
+
+	waiting := acquireAsync(t, s, context.Background(), PriorityTriggered, "b")
+	expectWaiting(t, waiting)
+	release()
+	release() // releasing twice must not free a second slot
+	next := expectResult(t, waiting, nil)
+
+	if stats := s.Stats(); stats.Running != 1 || stats.Queued != 0 || stats.Started != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	next.release()
+	if stats := s.Stats(); stats.Running != 0 {
+		t.Fatalf("expected no running actions, got %+v", stats)
+	}
+}
+
+func TestAcquire_ManualBeforeTriggered(t *testing.T) {
+	s := New(Options{MaxConcurrent: 1, QueueSize: 4, Overflow: config.QueueOverflowReject})
+	release := mustAcquire(t, s, PriorityTriggered, "a")
+
+	triggered := acquireAsync(t, s, context.Background(), PriorityTriggered, "b")
+	manual := acquireAsync(t, s, context.Background(), PriorityManual, "")
+	release()
+
+	r := expectResult(t, manual, nil)
+	expectWaiting(t, triggered)
+	r.release()
+	expectResult(t, triggered, nil).release()
+}
+
+func TestAcquire_Overflow(t *testing.T) {
+	tests := []struct {
+		name      string
+		overflow  string
+		key       string
+		wantFirst error // the queued run
+		wantNew   error // the arriving run
+	}{
+		{name: "reject", overflow: config.QueueOverflowReject, key: "b", wantFirst: nil, wantNew: ErrQueueFull},
+		{name: "drop oldest", overflow: config.QueueOverflowDropOldest, key: "b", wantFirst: ErrDropped, wantNew: nil},
+		{name: "coalesce same action", overflow: config.QueueOverflowCoalesce, key: "a", wantFirst: ErrCoalesced, wantNew: nil},
+		{name: "coalesce other action", overflow: config.QueueOverflowCoalesce, key: "b", wantFirst: nil, wantNew: ErrQueueFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(Options{MaxConcurrent: 1, QueueSize: 1, Overflow: tt.overflow})
+			release := mustAcquire(t, s, PriorityTriggered, "running")
+			first := acquireAsync(t, s, context.Background(), PriorityTriggered, "a")
+
+			var arriving <-chan result
+			if tt.wantNew != nil {
+				_, err := s.Acquire(context.Background(), PriorityTriggered, tt.key)
+				if !errors.Is(err, tt.wantNew) {
+					t.Fatalf("expected %v for the new run, got %v", tt.wantNew, err)
+				}
+			} else {
+				done := make(chan result, 1)
+				go func() {
+					r, err := s.Acquire(context.Background(), PriorityTriggered, tt.key)
+					done <- result{r, err}
+				}()
+				arriving = done
+				if tt.wantFirst != nil {
+					expectResult(t, first, tt.wantFirst)
+				}
+			}
+
+			release()
+			if tt.wantFirst == nil {
+				expectResult(t, first, nil).release()
+			}
+			if arriving != nil {
+				expectResult(t, arriving, nil).release()
+			}
+		})
+	}
+}
+
+func TestAcquire_Limits(t *testing.T) {
+	s := New(Options{MaxConcurrent: 3, QueueSize: 4, Overflow: config.QueueOverflowReject})
+	caption := Limit{Key: "action:caption", Max: 1}
+	ollama := Limit{Key: "endpoint:http://localhost:11434/v1", Max: 1}
+
+	releaseCaption := mustAcquire(t, s, PriorityTriggered, "caption", caption)
+	secondCaption := acquireAsync(t, s, context.Background(), PriorityTriggered, "caption", caption)
+	expectWaiting(t, secondCaption)
+
+	// A waiting caption doesn't hold back other actions while slots are free.
+	releaseSummary := mustAcquire(t, s, PriorityTriggered, "summarize", ollama)
+	explain := acquireAsync(t, s, context.Background(), PriorityTriggered, "explain", ollama)
+	expectWaiting(t, explain)
+	if stats := s.Stats(); stats.Running != 2 || stats.Queued != 2 || stats.Limited["action:caption"] != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	releaseSummary()
+	expectResult(t, explain, nil).release()
+	expectWaiting(t, secondCaption)
+	releaseCaption()
+	expectResult(t, secondCaption, nil).release()
+
+	if stats := s.Stats(); stats.Running != 0 || len(stats.Limited) != 0 {
+		t.Fatalf("expected every slot released, got %+v", stats)
+	}
+}
+
+func TestAcquire_ManualEvictsTriggeredWhenFull(t *testing.T) {
+	s := New(Options{MaxConcurrent: 1, QueueSize: 1, Overflow: config.QueueOverflowReject})
+	release := mustAcquire(t, s, PriorityTriggered, "running")
+	triggered := acquireAsync(t, s, context.Background(), PriorityTriggered, "a")
+
+	manual := acquireAsync(t, s, context.Background(), PriorityManual, "")
+	expectResult(t, triggered, ErrDropped)
+	if _, err := s.Acquire(context.Background(), PriorityTriggered, "b"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected a triggered run not to evict a manual one, got %v", err)
+	}
+
+	release()
+	expectResult(t, manual, nil).release()
+	if stats := s.Stats(); stats.Dropped != 1 || stats.Rejected != 1 || stats.Submitted != 4 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestAcquire_ZeroQueueSize(t *testing.T) {
+	s := New(Options{MaxConcurrent: 1, Overflow: config.QueueOverflowDropOldest})
+	release := mustAcquire(t, s, PriorityTriggered, "a")
+	if _, err := s.Acquire(context.Background(), PriorityManual, "b"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected no queueing with queue_size 0, got %v", err)
+	}
+	release()
+}
+
+func TestAcquire_ContextCancelled(t *testing.T) {
+	s := New(Options{MaxConcurrent: 1, QueueSize: 4, Overflow: config.QueueOverflowReject})
+	release := mustAcquire(t, s, PriorityTriggered, "a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := acquireAsync(t, s, ctx, PriorityTriggered, "b")
+	cancel()
+	expectResult(t, waiting, context.Canceled)
+	if stats := s.Stats(); stats.Queued != 0 {
+		t.Fatalf("expected the cancelled run to leave the queue, got %+v", stats)
+	}
+
+	release()
+	if stats := s.Stats(); stats.Running != 0 {
+		t.Fatalf("expected the cancelled run not to hold a slot, got %+v", stats)
+	}
+}
+
+func TestSetOptions_StartsQueuedRuns(t *testing.T) {
+	s := New(Options{MaxConcurrent: 1, QueueSize: 4, Overflow: config.QueueOverflowReject})
+	release := mustAcquire(t, s, PriorityTriggered, "a")
+	waiting := acquireAsync(t, s, context.Background(), PriorityTriggered, "b")
+
+	s.SetOptions(Options{MaxConcurrent: 2, QueueSize: 4, Overflow: config.QueueOverflowReject})
+	expectResult(t, waiting, nil).release()
+	release()
+
+	stats := s.Stats()
+	if stats.MaxConcurrent != 2 || stats.Started != 2 || stats.WaitMaxMs < 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestOptionsFrom(t *testing.T) {
+	opts := OptionsFrom(config.Default().Settings)
+	if opts.MaxConcurrent != 4 || opts.QueueSize != 16 || opts.Overflow != config.QueueOverflowDropOldest {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+}
@@ -0,0 +1,113 @@
+package script
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	"github.com/clipboard-ai/agent/internal/clipboard"
+)
+
+// maxSteps bounds the Starlark execution steps of one script run (the module
+// body at compile time, or one match() call), so an accidental infinite loop
+// can't wedge the clipboard handler. It is the limit scripts are meant to hit,
+// the same on every machine.
+const maxSteps = 1_000_000
+
+// runTimeout is a backstop on the wall-clock time of one script run, for the
+// rare step that is slow on its own (a huge string operation); set well above
+// what maxSteps takes even on a loaded machine. 0 disables it, as tests do.
+var runTimeout = 5 * time.Second
+
+// Program is a compiled trigger script. The script must define a function
+// match(content) returning a bool; content is a read-only struct with the
+// fields text, rtf, type, length (in runes), image_size (in bytes) and
+// timestamp (Unix seconds).
+type Program struct {
+	name  string
+	match starlark.Callable
+}
+
+// Compile parses and runs the script's top level once, returning the program
+// whose match() is called per clipboard event.
+func Compile(name string, src string) (*Program, error) {
+	thread := newThread(name)
+	stop := cancelAfter(thread, runTimeout)
+	globals, err := starlark.ExecFile(thread, name+".star", src, nil)
+	stop()
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := globals["match"]
+	if !ok {
+		return nil, fmt.Errorf("script does not define match(content)")
+	}
+	fn, ok := value.(*starlark.Function)
+	if !ok {
+		return nil, fmt.Errorf("match is a %s, want a function", value.Type())
+	}
+	if fn.NumParams() != 1 {
+		return nil, fmt.Errorf("match must take exactly one parameter (content), has %d", fn.NumParams())
+	}
+
+	return &Program{name: name, match: fn}, nil
+}
+
+// Match calls the script's match() with content.
+func (p *Program) Match(content clipboard.Content) (bool, error) {
+	thread := newThread(p.name)
+	stop := cancelAfter(thread, runTimeout)
+	defer stop()
+
+	result, err := starlark.Call(thread, p.match, starlark.Tuple{contentValue(content)}, nil)
+	if err != nil {
+		return false, err
+	}
+	matched, ok := result.(starlark.Bool)
+	if !ok {
+		return false, fmt.Errorf("match() returned %s, want bool", result.Type())
+	}
+	return bool(matched), nil
+}
+
+func newThread(name string) *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: name,
+		// Scripts have no load() and no print output.
+		Print: func(*starlark.Thread, string) {},
+	}
+	thread.SetMaxExecutionSteps(maxSteps)
+	return thread
+}
+
+// cancelAfter cancels thread once timeout elapses, unless timeout is 0; the
+// returned func stops the timer.
+func cancelAfter(thread *starlark.Thread, timeout time.Duration) func() {
+	if timeout <= 0 {
+		return func() {}
+	}
+	timer := time.AfterFunc(timeout, func() {
+		thread.Cancel(fmt.Sprintf("exceeded %s time limit", timeout))
+	})
+	return func() { timer.Stop() }
+}
+
+// contentValue exposes clipboard content to a script as an immutable struct.
+func contentValue(content clipboard.Content) starlark.Value {
+	var timestamp int64
+	if !content.Timestamp.IsZero() {
+		timestamp = content.Timestamp.Unix()
+	}
+	return starlarkstruct.FromStringDict(starlark.String("content"), starlark.StringDict{
+		"text":       starlark.String(content.Text),
+		"rtf":        starlark.String(content.RTF),
+		"type":       starlark.String(content.Type),
+		"length":     starlark.MakeInt(utf8.RuneCountInString(content.Text)),
+		"image_size": starlark.MakeInt(len(content.Image)),
+		"timestamp":  starlark.MakeInt64(timestamp),
+	})
+}
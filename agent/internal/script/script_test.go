@@ -0,0 +1,164 @@
+package script
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.starlark.net/starlark"
+
+	"github.com/clipboard-ai/agent/internal/clipboard"
+)
+
+const jiraKeyScript = `
+PROJECTS = ["CORE", "WEB"]
+
+def match(content):
+    key, sep, number = content.text.strip().partition("-")
+    return sep == "-" and key in PROJECTS and number.isdigit()
+`
+
+func TestCompileAndMatch(t *testing.T) {
+	program, err := Compile("jira", jiraKeyScript)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "CORE-123", want: true},
+		{text: " WEB-9 ", want: true},
+		{text: "OTHER-123", want: false},
+		{text: "CORE-abc", want: false},
+		{text: "hello", want: false},
+	}
+	for _, tt := range tests {
+		got, err := program.Match(clipboard.Content{Text: tt.text, Type: clipboard.ContentTypeText})
+		if err != nil {
+			t.Fatalf("Match(%q): unexpected error: %v", tt.text, err)
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMatch_ExposesContentFields(t *testing.T) {
+	program, err := Compile("fields", `
+def match(content):
+    return content.type == "image" and content.image_size == 3 and content.length == 2 and content.rtf == ""
+`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %v", err)
+	}
+	got, err := program.Match(clipboard.Content{Text: "hé", Image: []byte{1, 2, 3}, Type: clipboard.ContentTypeImage})
+	if err != nil || !got {
+		t.Fatalf("expected content fields to be exposed, got %v, %v", got, err)
+	}
+}
+
+func TestMatch_ContentIsReadOnly(t *testing.T) {
+	program, err := Compile("mutate", `
+def match(content):
+    content.text = "changed"
+    return True
+`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %v", err)
+	}
+	if _, err := program.Match(clipboard.Content{Text: "original"}); err == nil {
+		t.Fatal("expected assigning to a content field to fail")
+	}
+}
+
+// useRunTimeout replaces the wall-clock backstop for the test; 0 turns it
+// off, so step limit tests don't depend on the machine's speed.
+func useRunTimeout(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	previous := runTimeout
+	runTimeout = timeout
+	t.Cleanup(func() { runTimeout = previous })
+}
+
+func TestCompile_Errors(t *testing.T) {
+	useRunTimeout(t, 0)
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "syntax error", src: "def match(content)\n    return True", want: "got newline"},
+		{name: "missing match", src: "x = 1", want: "does not define match(content)"},
+		{name: "match not a function", src: "match = True", want: "want a function"},
+		{name: "wrong arity", src: "def match():\n    return True", want: "exactly one parameter"},
+		{name: "top-level step limit", src: "def spin():\n    for i in range(100000000):\n        pass\nspin()\ndef match(c):\n    return True", want: "too many steps"},
+	}
+
+	for _, tt := range tests {
+		_, err := Compile(tt.name, tt.src)
+		if err == nil {
+			t.Fatalf("%s: expected compile error", tt.name)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestMatch_RuntimeErrors(t *testing.T) {
+	useRunTimeout(t, 0)
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "non-bool result", src: "def match(content):\n    return 1", want: "want bool"},
+		{name: "step limit", src: "def match(content):\n    for i in range(100000000):\n        pass\n    return True", want: "too many steps"},
+		{name: "failure", src: "def match(content):\n    return content.missing", want: "has no .missing attribute"},
+	}
+
+	for _, tt := range tests {
+		program, err := Compile(tt.name, tt.src)
+		if err != nil {
+			t.Fatalf("%s: unexpected compile error: %v", tt.name, err)
+		}
+		_, err = program.Match(clipboard.Content{Text: "x"})
+		if err == nil {
+			t.Fatalf("%s: expected runtime error", tt.name)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestCancelAfter(t *testing.T) {
+	thread := newThread("spin")
+	stop := cancelAfter(thread, time.Nanosecond)
+	defer stop()
+	// Wait for the timer rather than racing a loop against it.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := starlark.ExecFile(thread, "spin.star", "x = 1", nil)
+		if err != nil {
+			if !strings.Contains(err.Error(), "time limit") {
+				t.Fatalf("expected a time limit error, got %v", err)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the time limit to cancel the thread")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCancelAfter_Disabled(t *testing.T) {
+	thread := newThread("spin")
+	defer cancelAfter(thread, 0)()
+	if _, err := starlark.ExecFile(thread, "spin.star", "x = 1", nil); err != nil {
+		t.Fatalf("expected no time limit, got %v", err)
+	}
+}
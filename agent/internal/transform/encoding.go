@@ -0,0 +1,178 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+func jsonPretty(input string) (string, error) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(strings.TrimSpace(input)), "", "  "); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	return out.String(), nil
+}
+
+func jsonMinify(input string) (string, error) {
+	var out bytes.Buffer
+	if err := json.Compact(&out, []byte(strings.TrimSpace(input))); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	return out.String(), nil
+}
+
+func yamlPretty(input string) (string, error) {
+	return reencodeYAML(input, nil)
+}
+
+// yamlMinify rewrites every document in flow style, one line each. Comments
+// are dropped.
+func yamlMinify(input string) (string, error) {
+	return reencodeYAML(input, func(node *yaml.Node) {
+		if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+			node.Style = yaml.FlowStyle
+		}
+		node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	})
+}
+
+// reencodeYAML decodes every document in input, applies edit to each node,
+// and encodes them back with two-space indentation.
+func reencodeYAML(input string, edit func(*yaml.Node)) (string, error) {
+	if strings.TrimSpace(input) == "" {
+		return "", errEmptyInput
+	}
+	decoder := yaml.NewDecoder(strings.NewReader(input))
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("invalid YAML: %w", err)
+		}
+		if edit != nil {
+			walkYAML(&doc, edit)
+		}
+		if err := encoder.Encode(&doc); err != nil {
+			return "", err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
+
+func walkYAML(node *yaml.Node, edit func(*yaml.Node)) {
+	edit(node)
+	for _, child := range node.Content {
+		walkYAML(child, edit)
+	}
+}
+
+func base64Encode(input string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(input)), nil
+}
+
+// base64Decode accepts the standard and URL-safe alphabets, with or without
+// padding, and ignores line breaks. The decoded bytes must be UTF-8 text.
+func base64Decode(input string) (string, error) {
+	compact := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, input)
+	if compact == "" {
+		return "", errEmptyInput
+	}
+	compact = strings.TrimRight(compact, "=")
+	encoding := base64.RawStdEncoding
+	if strings.ContainsAny(compact, "-_") {
+		encoding = base64.RawURLEncoding
+	}
+	decoded, err := encoding.DecodeString(compact)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64: %w", err)
+	}
+	if !utf8.Valid(decoded) {
+		return "", errors.New("decoded data is binary, not text")
+	}
+	return string(decoded), nil
+}
+
+func urlEncode(input string) (string, error) {
+	return url.QueryEscape(input), nil
+}
+
+func urlDecode(input string) (string, error) {
+	decoded, err := url.QueryUnescape(strings.TrimSpace(input))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL encoding: %w", err)
+	}
+	return decoded, nil
+}
+
+func htmlEscape(input string) (string, error) {
+	return html.EscapeString(input), nil
+}
+
+func htmlUnescape(input string) (string, error) {
+	return html.UnescapeString(input), nil
+}
+
+// jwtDecode shows a JWT's header and payload as indented JSON. The signature
+// is neither verified nor shown.
+func jwtDecode(input string) (string, error) {
+	token := strings.TrimSpace(input)
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	if token == "" {
+		return "", errEmptyInput
+	}
+	parts := strings.Split(token, ".")
+	switch len(parts) {
+	case 3:
+	case 5:
+		return "", errors.New("token is encrypted (JWE); only signed JWTs can be decoded")
+	default:
+		return "", fmt.Errorf("invalid JWT: expected 3 dot-separated parts, got %d", len(parts))
+	}
+
+	var decoded struct {
+		Header  json.RawMessage `json:"header"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	for i, target := range []*json.RawMessage{&decoded.Header, &decoded.Payload} {
+		part := [...]string{"header", "payload"}[i]
+		data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[i], "="))
+		if err != nil {
+			return "", fmt.Errorf("invalid JWT %s: %w", part, err)
+		}
+		var object map[string]any
+		if err := json.Unmarshal(data, &object); err != nil {
+			return "", fmt.Errorf("invalid JWT %s: not a JSON object", part)
+		}
+		*target = data
+	}
+	out, err := json.MarshalIndent(decoded, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,206 @@
+package transform
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Unix timestamps at or above these magnitudes are read as milliseconds,
+// microseconds and nanoseconds; 1e11 seconds is in the year 5138.
+const (
+	millisThreshold = 1e11
+	microsThreshold = 1e14
+	nanosThreshold  = 1e17
+)
+
+// isoLayouts are the date formats iso-to-timestamp accepts; those without a
+// zone are read as UTC.
+var isoLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// timestampToISO converts a Unix timestamp in seconds, milliseconds,
+// microseconds or nanoseconds (chosen by magnitude) to an RFC 3339 UTC date.
+func timestampToISO(input string) (string, error) {
+	text := strings.TrimSpace(input)
+	if text == "" {
+		return "", errEmptyInput
+	}
+	value, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid Unix timestamp %q: must be an integer", text)
+	}
+	var t time.Time
+	switch magnitude := math.Abs(float64(value)); {
+	case magnitude >= nanosThreshold:
+		t = time.Unix(0, value)
+	case magnitude >= microsThreshold:
+		t = time.UnixMicro(value)
+	case magnitude >= millisThreshold:
+		t = time.UnixMilli(value)
+	default:
+		t = time.Unix(value, 0)
+	}
+	return t.UTC().Format(time.RFC3339Nano), nil
+}
+
+// isoToTimestamp converts an RFC 3339 date (or a plain date and time, read as
+// UTC) to a Unix timestamp in seconds.
+func isoToTimestamp(input string) (string, error) {
+	text := strings.TrimSpace(input)
+	if text == "" {
+		return "", errEmptyInput
+	}
+	for _, layout := range isoLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return strconv.FormatInt(t.Unix(), 10), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q: expected RFC 3339, e.g. 2006-01-02T15:04:05Z", text)
+}
+
+func uppercase(input string) (string, error) {
+	return strings.ToUpper(input), nil
+}
+
+func lowercase(input string) (string, error) {
+	return strings.ToLower(input), nil
+}
+
+// titleCase capitalizes the first letter of every word and leaves the rest
+// alone, so acronyms survive.
+func titleCase(input string) (string, error) {
+	var out strings.Builder
+	atWordStart := true
+	for _, r := range input {
+		if atWordStart && unicode.IsLetter(r) {
+			r = unicode.ToUpper(r)
+		}
+		atWordStart = unicode.IsSpace(r) || r == '-' || r == '_'
+		out.WriteRune(r)
+	}
+	return out.String(), nil
+}
+
+func snakeCase(input string) (string, error) {
+	return joinWordsPerLine(input, func(words []string) string {
+		return strings.Join(words, "_")
+	}), nil
+}
+
+func kebabCase(input string) (string, error) {
+	return joinWordsPerLine(input, func(words []string) string {
+		return strings.Join(words, "-")
+	}), nil
+}
+
+func camelCase(input string) (string, error) {
+	return joinWordsPerLine(input, func(words []string) string {
+		for i := 1; i < len(words); i++ {
+			words[i] = capitalize(words[i])
+		}
+		return strings.Join(words, "")
+	}), nil
+}
+
+func pascalCase(input string) (string, error) {
+	return joinWordsPerLine(input, func(words []string) string {
+		for i := range words {
+			words[i] = capitalize(words[i])
+		}
+		return strings.Join(words, "")
+	}), nil
+}
+
+// joinWordsPerLine splits each line of input into lowercase words and joins
+// them with join, so a list of identifiers converts line by line.
+func joinWordsPerLine(input string, join func(words []string) string) string {
+	lines := strings.Split(input, "\n")
+	for i, line := range lines {
+		lines[i] = join(splitWords(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitWords breaks s into lowercase words at non-alphanumeric characters and
+// at case changes: "parseHTTPRequest" and "parse_http request" both give
+// parse, http, request.
+func splitWords(s string) []string {
+	runes := []rune(s)
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// "fooBar" splits before B; "HTTPRequest" splits before the R.
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+func capitalize(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	if r == utf8.RuneError {
+		return word
+	}
+	return string(unicode.ToUpper(r)) + word[size:]
+}
+
+var (
+	horizontalSpaceRe = regexp.MustCompile(`[\t\v\f\r\p{Zs}]+`)
+	blankLinesRe      = regexp.MustCompile(`\n{3,}`)
+)
+
+// normalizeWhitespace collapses runs of spaces and tabs to one space, trims
+// every line, keeps at most one blank line in a row, and trims the text.
+func normalizeWhitespace(input string) (string, error) {
+	text := strings.ReplaceAll(input, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(horizontalSpaceRe.ReplaceAllString(line, " "))
+	}
+	text = blankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text), nil
+}
+
+func md5Digest(input string) (string, error)    { return digest(md5.New(), input), nil }
+func sha1Digest(input string) (string, error)   { return digest(sha1.New(), input), nil }
+func sha256Digest(input string) (string, error) { return digest(sha256.New(), input), nil }
+func sha512Digest(input string) (string, error) { return digest(sha512.New(), input), nil }
+
+// digest returns the lowercase hex digest of input, byte for byte as copied.
+func digest(h hash.Hash, input string) string {
+	h.Write([]byte(input))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,76 @@
+package transform
+
+import "testing"
+
+func TestTimestampTransforms(t *testing.T) {
+	runTransformCases(t, "timestamp-to-iso", []transformCase{
+		{name: "seconds", input: "1700000000\n", want: "2023-11-14T22:13:20Z"},
+		{name: "milliseconds", input: "1700000000123", want: "2023-11-14T22:13:20.123Z"},
+		{name: "microseconds", input: "1700000000123456", want: "2023-11-14T22:13:20.123456Z"},
+		{name: "nanoseconds", input: "1700000000123456789", want: "2023-11-14T22:13:20.123456789Z"},
+		{name: "before the epoch", input: "-86400", want: "1969-12-31T00:00:00Z"},
+		{name: "not a number", input: "yesterday", wantErr: "invalid Unix timestamp"},
+		{name: "empty", input: "", wantErr: "input is empty"},
+	})
+	runTransformCases(t, "iso-to-timestamp", []transformCase{
+		{name: "utc", input: "2023-11-14T22:13:20Z", want: "1700000000"},
+		{name: "offset", input: " 2023-11-15T00:13:20+02:00 ", want: "1700000000"},
+		{name: "fractional seconds", input: "2023-11-14T22:13:20.999Z", want: "1700000000"},
+		{name: "no zone is utc", input: "2023-11-14 22:13:20", want: "1700000000"},
+		{name: "date only", input: "2023-11-14", want: "1699920000"},
+		{name: "invalid", input: "14/11/2023", wantErr: "invalid date"},
+	})
+}
+
+func TestCaseTransforms(t *testing.T) {
+	runTransformCases(t, "uppercase", []transformCase{
+		{name: "text", input: "Hello, wörld", want: "HELLO, WÖRLD"},
+	})
+	runTransformCases(t, "lowercase", []transformCase{
+		{name: "text", input: "Hello, WÖRLD", want: "hello, wörld"},
+	})
+	runTransformCases(t, "title-case", []transformCase{
+		{name: "sentence", input: "the quick brown fox", want: "The Quick Brown Fox"},
+		{name: "keeps acronyms", input: "using NASA data", want: "Using NASA Data"},
+		{name: "separators", input: "well-known_name", want: "Well-Known_Name"},
+	})
+	runTransformCases(t, "snake-case", []transformCase{
+		{name: "camel", input: "parseHTTPRequest", want: "parse_http_request"},
+		{name: "words", input: "Hello  World-again", want: "hello_world_again"},
+		{name: "digits", input: "utf8Decoder2", want: "utf8_decoder2"},
+		{name: "per line", input: "firstName\nlastName", want: "first_name\nlast_name"},
+	})
+	runTransformCases(t, "kebab-case", []transformCase{
+		{name: "pascal", input: "UserProfileID", want: "user-profile-id"},
+		{name: "snake", input: "max_retry_count", want: "max-retry-count"},
+	})
+	runTransformCases(t, "camel-case", []transformCase{
+		{name: "snake", input: "max_retry_count", want: "maxRetryCount"},
+		{name: "words", input: "Hello World", want: "helloWorld"},
+	})
+	runTransformCases(t, "pascal-case", []transformCase{
+		{name: "kebab", input: "user-profile-id", want: "UserProfileId"},
+		{name: "camel", input: "parseHTTPRequest", want: "ParseHttpRequest"},
+	})
+}
+
+func TestNormalizeWhitespace(t *testing.T) {
+	runTransformCases(t, "normalize-whitespace", []transformCase{
+		{name: "runs of spaces and tabs", input: "a  \t b\u00a0\u00a0c", want: "a b c"},
+		{name: "trims lines", input: "  one  \n\ttwo\t", want: "one\ntwo"},
+		{name: "collapses blank lines", input: "\n\npara one\n\n\n\r\npara two\n\n", want: "para one\n\npara two"},
+		{name: "crlf", input: "a\r\nb", want: "a\nb"},
+		{name: "only whitespace", input: " \n\t ", want: ""},
+	})
+}
+
+func TestDigestTransforms(t *testing.T) {
+	runTransformCases(t, "md5", []transformCase{{name: "text", input: "hello", want: "5d41402abc4b2a76b9719d911017c592"}})
+	runTransformCases(t, "sha1", []transformCase{{name: "text", input: "hello", want: "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"}})
+	runTransformCases(t, "sha256", []transformCase{
+		{name: "text", input: "hello", want: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{name: "whitespace counts", input: "hello\n", want: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+	})
+	runTransformCases(t, "sha512", []transformCase{{name: "text", input: "hello",
+		want: "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"}})
+}
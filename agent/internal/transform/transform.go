@@ -0,0 +1,61 @@
+// Package transform implements the agent's built-in deterministic transforms:
+// local text conversions such as JSON pretty-printing or base64 decoding that
+// run in-process and need no LLM. Each is addressable as an action by name.
+package transform
+
+import (
+	"errors"
+	"sort"
+)
+
+// Func transforms clipboard text.
+type Func func(input string) (string, error)
+
+// errEmptyInput is returned by transforms that parse their input when it is
+// blank.
+var errEmptyInput = errors.New("input is empty")
+
+// registry maps action names to transforms.
+var registry = map[string]Func{
+	"json-pretty":          jsonPretty,
+	"json-minify":          jsonMinify,
+	"yaml-pretty":          yamlPretty,
+	"yaml-minify":          yamlMinify,
+	"base64-encode":        base64Encode,
+	"base64-decode":        base64Decode,
+	"url-encode":           urlEncode,
+	"url-decode":           urlDecode,
+	"html-escape":          htmlEscape,
+	"html-unescape":        htmlUnescape,
+	"jwt-decode":           jwtDecode,
+	"timestamp-to-iso":     timestampToISO,
+	"iso-to-timestamp":     isoToTimestamp,
+	"uppercase":            uppercase,
+	"lowercase":            lowercase,
+	"title-case":           titleCase,
+	"snake-case":           snakeCase,
+	"kebab-case":           kebabCase,
+	"camel-case":           camelCase,
+	"pascal-case":          pascalCase,
+	"normalize-whitespace": normalizeWhitespace,
+	"md5":                  md5Digest,
+	"sha1":                 sha1Digest,
+	"sha256":               sha256Digest,
+	"sha512":               sha512Digest,
+}
+
+// Lookup returns the transform named name.
+func Lookup(name string) (Func, bool) {
+	fn, ok := registry[name]
+	return fn, ok
+}
+
+// Names returns the names of all transforms, sorted.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
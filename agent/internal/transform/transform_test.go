@@ -0,0 +1,71 @@
+package transform
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "json-pretty", want: true},
+		{name: "sha256", want: true},
+		{name: "jwt-decode", want: true},
+		{name: "summarize", want: false},
+		{name: "", want: false},
+	}
+	for _, tt := range tests {
+		if _, ok := Lookup(tt.name); ok != tt.want {
+			t.Errorf("Lookup(%q) ok = %v, want %v", tt.name, ok, tt.want)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	names := Names()
+	if len(names) != len(registry) || !sort.StringsAreSorted(names) {
+		t.Fatalf("expected every transform name, sorted, got %v", names)
+	}
+	for _, name := range names {
+		if fn, ok := Lookup(name); !ok || fn == nil {
+			t.Fatalf("Names() lists %q but Lookup can't find it", name)
+		}
+	}
+}
+
+// transformCase is one input and the output or error a transform must give.
+type transformCase struct {
+	name    string
+	input   string
+	want    string
+	wantErr string
+}
+
+// runTransformCases runs the named transform over cases.
+func runTransformCases(t *testing.T, transform string, cases []transformCase) {
+	t.Helper()
+	fn, ok := Lookup(transform)
+	if !ok {
+		t.Fatalf("unknown transform %q", transform)
+	}
+	for _, tc := range cases {
+		t.Run(transform+"/"+tc.name, func(t *testing.T) {
+			got, err := fn(tc.input)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %q, %v", tc.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -52,11 +52,12 @@ export async function historyCommand(
     console.log("──────────────────");
 
     for (const record of records) {
-      const status = record.status === "success" ? "ok" : "error";
+      const status = record.status === "success" ? "ok" : record.status;
       const latency = `${record.latency_ms}ms`;
       const replay = record.replay_of ? ` replay:${record.replay_of}` : "";
+      const cached = record.cached ? " cached" : "";
       console.log(
-        `${record.id} | ${record.timestamp} | ${status} | ${record.source} | ${record.action} | ${latency}${replay}`
+        `${record.id} | ${record.timestamp} | ${status} | ${record.source} | ${record.action} | ${latency}${cached}${replay}`
       );
     }
   } catch (err) {
@@ -27,6 +27,7 @@ export class AIClient {
   private maxTokens: number;
   private onToken?: (token: string) => void;
   private usage = { promptTokens: 0, completionTokens: 0 };
+  private warnings: string[] = [];
 
   constructor(config: AIConfig) {
     this.model = config.model;
@@ -55,6 +56,19 @@ export class AIClient {
     return { ...this.usage };
   }
 
+  // getWarnings returns the non-fatal problems (e.g. truncated output) seen
+  // across this client's calls.
+  getWarnings(): string[] {
+    return [...this.warnings];
+  }
+
+  private noteTruncation(finishReason: string | null | undefined): void {
+    const warning = warnIfTruncated(finishReason, this.maxTokens);
+    if (warning) {
+      this.warnings.push(warning);
+    }
+  }
+
   private getBaseURL(config: AIConfig): string {
     if (config.endpoint) {
       return config.endpoint;
@@ -75,7 +89,7 @@ export class AIClient {
       max_tokens: this.maxTokens,
     });
 
-    warnIfTruncated(response.choices?.[0]?.finish_reason, this.maxTokens);
+    this.noteTruncation(response.choices?.[0]?.finish_reason);
 
     const usage = response.usage
       ? {
@@ -123,7 +137,7 @@ export class AIClient {
       onToken?.(token);
     }
 
-    warnIfTruncated(finishReason, this.maxTokens);
+    this.noteTruncation(finishReason);
 
     if (content === "") {
       throw new Error("provider returned no completion choices");
@@ -160,7 +174,7 @@ export class AIClient {
       max_tokens: this.maxTokens,
     });
 
-    warnIfTruncated(response.choices?.[0]?.finish_reason, this.maxTokens);
+    this.noteTruncation(response.choices?.[0]?.finish_reason);
 
     const usage = response.usage
       ? {
@@ -242,7 +256,7 @@ export class AIClient {
         max_tokens: this.maxTokens,
         response_format: { type: "json_object" },
       });
-      warnIfTruncated(response.choices?.[0]?.finish_reason, this.maxTokens);
+      this.noteTruncation(response.choices?.[0]?.finish_reason);
       return {
         content: completionContent(response),
         model: response.model,
@@ -290,12 +304,13 @@ function completionMessages(
 
 // warnIfTruncated alerts (on stderr) when the model stopped because it hit the
 // token cap, so a silently-cut summary/OCR/extraction is at least visible.
-export function warnIfTruncated(finishReason: string | null | undefined, maxTokens: number): void {
-  if (finishReason === "length") {
-    console.error(
-      `Warning: output was truncated at max_tokens=${maxTokens}. Raise max_tokens (settings or per-action) for a complete result.`
-    );
+export function warnIfTruncated(finishReason: string | null | undefined, maxTokens: number): string | undefined {
+  if (finishReason !== "length") {
+    return undefined;
   }
+  const warning = `output was truncated at max_tokens=${maxTokens}. Raise max_tokens (settings or per-action) for a complete result.`;
+  console.error(`Warning: ${warning}`);
+  return warning;
 }
 
 // validateJSON ensures a model response is parseable JSON, returning a clear
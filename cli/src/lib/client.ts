@@ -45,7 +45,12 @@ export interface ConfigResponse {
       timeout_ms?: number;
       retry_count?: number;
       retry_backoff_ms?: number;
+      retry_backoff_multiplier?: number;
+      retry_backoff_max_ms?: number;
+      retry_jitter?: number;
       cooldown_ms?: number;
+      max_concurrent?: number;
+      supersede?: boolean;
       max_tokens?: number;
     }
   >;
@@ -4,7 +4,15 @@ import { homedir } from "os";
 import { dirname, join } from "path";
 
 export type RunSource = "manual" | "daemon" | "rerun";
-export type RunStatus = "success" | "error";
+export type RunStatus = "success" | "error" | "superseded";
+
+export interface ActionRunStep {
+  action: string;
+  status: "success" | "error" | "skipped";
+  attempts: number;
+  latency_ms: number;
+  error?: string;
+}
 
 export interface ActionRunRecord {
   id: string;
@@ -24,6 +32,8 @@ export interface ActionRunRecord {
   replay_of?: string;
   prompt_tokens?: number;
   completion_tokens?: number;
+  steps?: ActionRunStep[];
+  cached?: boolean;
 }
 
 export type ActionRunRecordInput = Omit<ActionRunRecord, "id" | "timestamp"> & {
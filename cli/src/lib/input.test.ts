@@ -0,0 +1,59 @@
+import { describe, expect, it } from "bun:test";
+import { existsSync, mkdtempSync, writeFileSync } from "fs";
+import { tmpdir } from "os";
+import { join } from "path";
+import { parseInputEnvelope } from "./input.js";
+
+describe("parseInputEnvelope", () => {
+  it("reads text, rtf, and type", () => {
+    const payload = parseInputEnvelope(
+      JSON.stringify({ version: 1, text: "hello", rtf: "{\\rtf1 hello}", type: "rtf" })
+    );
+    expect(payload.text).toBe("hello");
+    expect(payload.rtf).toBe("{\\rtf1 hello}");
+    expect(payload.type).toBe("rtf");
+    expect(payload.imageBase64).toBeUndefined();
+  });
+
+  it("reads the source, trigger, and timestamp", () => {
+    const payload = parseInputEnvelope(
+      JSON.stringify({
+        version: 1,
+        text: "hello",
+        source: "clipboard",
+        trigger: "length > 3",
+        timestamp: "2026-06-11T10:00:00Z",
+      })
+    );
+    expect(payload.source).toBe("clipboard");
+    expect(payload.trigger).toBe("length > 3");
+    expect(payload.timestamp).toBe("2026-06-11T10:00:00Z");
+  });
+
+  it("round-trips multi-megabyte text", () => {
+    const text = "clipboard ünïcode line\n".repeat(400_000);
+    const payload = parseInputEnvelope(JSON.stringify({ version: 1, text }));
+    expect(payload.text).toBe(text);
+  });
+
+  it("reads and removes the image file", () => {
+    const dir = mkdtempSync(join(tmpdir(), "cbai-input-"));
+    const imagePath = join(dir, "clip.png");
+    writeFileSync(imagePath, Buffer.from([0x89, 0x50, 0x4e, 0x47]));
+
+    const payload = parseInputEnvelope(
+      JSON.stringify({ version: 1, text: "", type: "image", image_path: imagePath, image_mime: "image/png" })
+    );
+    expect(payload.imageBase64).toBe("iVBORw==");
+    expect(payload.imageMime).toBe("image/png");
+    expect(existsSync(imagePath)).toBe(false);
+  });
+
+  it("rejects malformed and newer envelopes", () => {
+    expect(() => parseInputEnvelope("not json")).toThrow("not JSON");
+    expect(() => parseInputEnvelope("null")).toThrow("expected an object");
+    expect(() => parseInputEnvelope(JSON.stringify({ version: 2, text: "x" }))).toThrow(
+      "Unsupported input envelope version 2"
+    );
+  });
+});
@@ -7,9 +7,77 @@ export interface InputPayload {
   imageBase64?: string;
   imageMime?: string;
   type?: string;
+  // Where the input came from ("clipboard", "request" or "pipeline"), the
+  // trigger that matched, and when it was copied (RFC 3339); set by the daemon.
+  source?: string;
+  trigger?: string;
+  timestamp?: string;
+}
+
+// Version of the stdin input envelope the daemon writes when
+// CBAI_INPUT_STDIN=1 (see agent/internal/executor/executor.go).
+const INPUT_ENVELOPE_VERSION = 1;
+
+interface InputEnvelope {
+  version?: number;
+  text?: string;
+  rtf?: string;
+  type?: string;
+  image_path?: string;
+  image_mime?: string;
+  source?: string;
+  trigger?: string;
+  timestamp?: string;
+}
+
+function readImageFile(path: string): string {
+  const imageBase64 = readFileSync(path).toString("base64");
+  try {
+    unlinkSync(path);
+  } catch {
+    // ignore cleanup errors
+  }
+  return imageBase64;
+}
+
+export function parseInputEnvelope(raw: string): InputPayload {
+  let envelope: InputEnvelope;
+  try {
+    envelope = JSON.parse(raw) as InputEnvelope;
+  } catch {
+    throw new Error("Invalid input envelope on stdin: not JSON");
+  }
+  if (!envelope || typeof envelope !== "object") {
+    throw new Error("Invalid input envelope on stdin: expected an object");
+  }
+  if (
+    typeof envelope.version !== "number" ||
+    envelope.version > INPUT_ENVELOPE_VERSION
+  ) {
+    throw new Error(
+      `Unsupported input envelope version ${envelope.version}; upgrade cbai`
+    );
+  }
+
+  return {
+    text: envelope.text ?? "",
+    rtf: envelope.rtf || undefined,
+    imageBase64: envelope.image_path
+      ? readImageFile(envelope.image_path)
+      : undefined,
+    imageMime: envelope.image_mime || undefined,
+    type: envelope.type || undefined,
+    source: envelope.source || undefined,
+    trigger: envelope.trigger || undefined,
+    timestamp: envelope.timestamp || undefined,
+  };
 }
 
 export async function getInput(): Promise<InputPayload> {
+  if (process.env.CBAI_INPUT_STDIN === "1") {
+    return parseInputEnvelope(readFileSync(0, "utf8"));
+  }
+
   const envType = process.env.CBAI_INPUT_TYPE;
   const envText = process.env.CBAI_INPUT_TEXT;
   const envRtf = process.env.CBAI_INPUT_RTF;
@@ -25,13 +93,7 @@ export async function getInput(): Promise<InputPayload> {
   ) {
     let imageBase64 = envImageBase64;
     if (!imageBase64 && envImagePath) {
-      const buffer = readFileSync(envImagePath);
-      imageBase64 = buffer.toString("base64");
-      try {
-        unlinkSync(envImagePath);
-      } catch {
-        // ignore cleanup errors
-      }
+      imageBase64 = readImageFile(envImagePath);
     }
 
     return {
@@ -0,0 +1,24 @@
+import { describe, expect, it } from "bun:test";
+import OpenAI from "openai";
+import { ActionError, errorCodeOf } from "./result.js";
+
+describe("errorCodeOf", () => {
+  it("returns the code of an ActionError", () => {
+    expect(errorCodeOf(new ActionError("safe_mode_blocked", "blocked"))).toBe("safe_mode_blocked");
+  });
+
+  it("classifies provider connection failures", () => {
+    expect(errorCodeOf(new OpenAI.APIConnectionError({ message: "refused" }))).toBe("provider_unreachable");
+    expect(errorCodeOf(new OpenAI.APIConnectionTimeoutError())).toBe("timeout");
+  });
+
+  it("classifies retryable provider responses", () => {
+    expect(errorCodeOf(new OpenAI.RateLimitError(429, undefined, "slow down", undefined))).toBe("rate_limited");
+    expect(errorCodeOf(new OpenAI.InternalServerError(503, undefined, "unavailable", undefined))).toBe("server_error");
+    expect(errorCodeOf(new OpenAI.BadRequestError(400, undefined, "bad request", undefined))).toBeUndefined();
+  });
+
+  it("leaves other errors unclassified", () => {
+    expect(errorCodeOf(new Error("Clipboard is empty"))).toBeUndefined();
+  });
+});
@@ -0,0 +1,67 @@
+import OpenAI from "openai";
+
+// Version of the result envelope written when the daemon sets
+// CBAI_RESULT_FORMAT=json (parsed by agent/internal/executor/result.go).
+export const RESULT_ENVELOPE_VERSION = 1;
+
+export type ResultErrorCode =
+  | "safe_mode_blocked"
+  | "provider_unreachable"
+  | "guard_blocked"
+  | "timeout"
+  | "rate_limited"
+  | "server_error";
+
+// ActionError carries a machine-readable code alongside the message, so the
+// daemon can react to a failure without matching on its text.
+export class ActionError extends Error {
+  constructor(
+    readonly code: ResultErrorCode,
+    message: string
+  ) {
+    super(message);
+    this.name = "ActionError";
+  }
+}
+
+export interface ResultEnvelope {
+  version: number;
+  ok: boolean;
+  output: string;
+  provider: string;
+  model: string;
+  usage?: { prompt_tokens: number; completion_tokens: number };
+  latency: { provider_ms: number; total_ms: number };
+  error?: { code?: ResultErrorCode; message: string };
+  warnings: string[];
+}
+
+export function wantsResultEnvelope(): boolean {
+  return process.env.CBAI_RESULT_FORMAT === "json";
+}
+
+export function errorCodeOf(err: unknown): ResultErrorCode | undefined {
+  if (err instanceof ActionError) {
+    return err.code;
+  }
+  // APIConnectionTimeoutError extends APIConnectionError, so check it first.
+  if (err instanceof OpenAI.APIConnectionTimeoutError) {
+    return "timeout";
+  }
+  if (err instanceof OpenAI.APIConnectionError) {
+    return "provider_unreachable";
+  }
+  if (err instanceof OpenAI.RateLimitError) {
+    return "rate_limited";
+  }
+  if (err instanceof OpenAI.InternalServerError) {
+    return "server_error";
+  }
+  return undefined;
+}
+
+// writeResultEnvelope prints the envelope as the last line of stdout, where
+// the daemon looks for it.
+export function writeResultEnvelope(envelope: ResultEnvelope): void {
+  process.stdout.write(`${JSON.stringify(envelope)}\n`);
+}
@@ -76,6 +76,7 @@ describe("runActionCommand history", () => {
     delete process.env.CBAI_SENSITIVE_GUARD_HIT;
     delete process.env.CBAI_MODEL_OVERRIDE;
     delete process.env.CBAI_ENDPOINT_OVERRIDE;
+    delete process.env.CBAI_RESULT_FORMAT;
     Object.defineProperty(process.stdout, "isTTY", {
       value: false,
       configurable: true,
@@ -326,4 +327,44 @@ describe("runActionCommand history", () => {
     expect(call.status).toBe("success");
     expect(call.output).toBe("a serene mountain lake");
   });
+
+  it("writes a JSON result envelope for the daemon", async () => {
+    process.env.CBAI_RESULT_FORMAT = "json";
+    const writeSpy = spyOn(process.stdout, "write").mockImplementation(() => true);
+    (console.log as unknown as { mockClear: () => void }).mockClear();
+
+    await runActionCommand("summary", { registry, deps: deps() });
+
+    expect(console.log).not.toHaveBeenCalledWith("Summary:");
+    const lastWrite = String(writeSpy.mock.calls[writeSpy.mock.calls.length - 1][0]);
+    writeSpy.mockRestore();
+    const envelope = JSON.parse(lastWrite);
+    expect(envelope.version).toBe(1);
+    expect(envelope.ok).toBe(true);
+    expect(envelope.output).toBe("out: clipboard text");
+    expect(envelope.model).toBe("mistral");
+    expect(envelope.error).toBeUndefined();
+  });
+
+  it("reports a coded error in the result envelope", async () => {
+    process.env.CBAI_RESULT_FORMAT = "json";
+    mockGetConfig.mockResolvedValueOnce(
+      makeConfig({ settings: { sensitive_guard: "block" } })
+    );
+    mockGetInput.mockResolvedValueOnce({ text: "api_key = EXAMPLEKEY1234567890" });
+    const writeSpy = spyOn(process.stdout, "write").mockImplementation(() => true);
+    const exitSpy = spyOn(process, "exit").mockImplementation((() => {
+      throw new Error("exit");
+    }) as never);
+
+    await expect(runActionCommand("summary", { registry, deps: deps() })).rejects.toThrow("exit");
+
+    const lastWrite = String(writeSpy.mock.calls[writeSpy.mock.calls.length - 1][0]);
+    writeSpy.mockRestore();
+    exitSpy.mockRestore();
+    const envelope = JSON.parse(lastWrite);
+    expect(envelope.ok).toBe(false);
+    expect(envelope.error.code).toBe("guard_blocked");
+    expect(envelope.error.message).toContain("contains a secret");
+  });
 });
@@ -5,6 +5,14 @@ import { copyToClipboard } from "./clipboard.js";
 import { getConfig, type ConfigResponse } from "./client.js";
 import { appendHistoryRecord, type HistoryRetentionSettings, type RunSource } from "./history.js";
 import { getInput, type InputPayload } from "./input.js";
+import {
+  ActionError,
+  errorCodeOf,
+  RESULT_ENVELOPE_VERSION,
+  wantsResultEnvelope,
+  writeResultEnvelope,
+  type ResultErrorCode,
+} from "./result.js";
 import { enforceSafeMode } from "./safe-mode.js";
 import { scanSensitiveText } from "./sensitive-guard.js";
 
@@ -100,6 +108,8 @@ export async function runActionCommand(actionName: string, options: RunActionOpt
   let latencyMs = 0;
   let output: string | undefined;
   let runError: string | undefined;
+  let runErrorCode: ResultErrorCode | undefined;
+  let warnings: string[] = [];
   let shouldRecord = false;
   let promptTokens: number | undefined;
   let completionTokens: number | undefined;
@@ -107,6 +117,9 @@ export async function runActionCommand(actionName: string, options: RunActionOpt
   let historySettings: HistoryRetentionSettings | undefined;
   let guardHit = process.env.CBAI_SENSITIVE_GUARD_HIT === "true";
   const deps = { ...defaultRunActionDeps, ...options.deps };
+  // The daemon asks for a JSON result envelope in place of the human-readable
+  // output (see result.ts).
+  const envelopeMode = wantsResultEnvelope();
 
   try {
     const registry = options.registry ?? (await deps.getActionRegistry());
@@ -145,7 +158,7 @@ export async function runActionCommand(actionName: string, options: RunActionOpt
     }
 
     source = options.source ?? (process.env.CBAI_DAEMON_MODE === "true" ? "daemon" : "manual");
-    trigger = options.trigger ?? process.env.CBAI_TRIGGER ?? (source === "manual" ? "cli" : "daemon");
+    trigger = options.trigger ?? input.trigger ?? process.env.CBAI_TRIGGER ?? (source === "manual" ? "cli" : "daemon");
     const actionConfig = resolveConfiguredAction(config, actionName, action);
     const effectiveConfig = withProviderOverrides(config, {
       model: process.env.CBAI_MODEL_OVERRIDE || actionConfig?.model,
@@ -166,17 +179,21 @@ export async function runActionCommand(actionName: string, options: RunActionOpt
       if (findings.length > 0) {
         guardHit = true;
         if (guardMode === "block" && !options.force) {
-          throw new Error("clipboard looks like it contains a secret — action skipped. Use --force to run the action anyway.");
+          throw new ActionError(
+            "guard_blocked",
+            "clipboard looks like it contains a secret — action skipped. Use --force to run the action anyway."
+          );
         }
         if (guardMode === "warn" && !options.force) {
           console.error("Warning: clipboard looks like it contains a secret.");
+          warnings.push("clipboard looks like it contains a secret");
         }
       }
     }
 
     await deps.enforceSafeMode(effectiveConfig, { yes: options.yes });
 
-    if (action.progressMessage) {
+    if (action.progressMessage && !envelopeMode) {
       console.log(`${action.progressMessage}\n`);
     }
 
@@ -216,9 +233,12 @@ export async function runActionCommand(actionName: string, options: RunActionOpt
         promptTokens = used.promptTokens;
         completionTokens = used.completionTokens;
       }
+      warnings = [...warnings, ...(ai.getWarnings?.() ?? [])];
     }
 
-    if (shouldStreamOutput && streamedChunks.length > 0) {
+    if (envelopeMode) {
+      // The output travels in the envelope written below.
+    } else if (shouldStreamOutput && streamedChunks.length > 0) {
       // Tokens were streamed to stdout live; the accumulated buffer is the result.
       output = streamedChunks.join("");
       process.stdout.write("\n");
@@ -239,8 +259,25 @@ export async function runActionCommand(actionName: string, options: RunActionOpt
     }
   } catch (err) {
     runError = (err as Error).message;
+    runErrorCode = errorCodeOf(err);
     console.error(`Error: ${runError}`);
   } finally {
+    if (envelopeMode) {
+      writeResultEnvelope({
+        version: RESULT_ENVELOPE_VERSION,
+        ok: !runError,
+        output: output ?? "",
+        provider: providerType,
+        model: providerModel,
+        usage:
+          promptTokens !== undefined || completionTokens !== undefined
+            ? { prompt_tokens: promptTokens ?? 0, completion_tokens: completionTokens ?? 0 }
+            : undefined,
+        latency: { provider_ms: latencyMs, total_ms: Math.round(performance.now()) },
+        error: runError ? { code: runErrorCode, message: runError } : undefined,
+        warnings,
+      });
+    }
     if (!shouldRecord) {
       return;
     }
@@ -98,6 +98,7 @@ describe("enforceSafeMode", () => {
     process.env.CBAI_DAEMON_MODE = "true";
     const config = makeConfig({ type: "openai", safe_mode: true });
     await expect(enforceSafeMode(config)).rejects.toThrow("safe mode");
+    await expect(enforceSafeMode(config)).rejects.toMatchObject({ code: "safe_mode_blocked" });
   });
 
   it("blocks anthropic in daemon mode", async () => {
@@ -1,5 +1,6 @@
 import { createInterface } from "readline";
 import type { ConfigResponse } from "./client.js";
+import { ActionError } from "./result.js";
 
 export function isCloudProvider(providerType: string, endpoint?: string): boolean {
   if (endpoint) {
@@ -60,7 +61,8 @@ export async function enforceSafeMode(
 
   // Daemon-triggered — cannot prompt, block entirely
   if (process.env.CBAI_DAEMON_MODE === "true") {
-    throw new Error(
+    throw new ActionError(
+      "safe_mode_blocked",
       `safe mode: blocked cloud call to ${provider} (daemon auto-triggered)`
     );
   }